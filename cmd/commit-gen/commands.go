@@ -2,10 +2,17 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,22 +38,61 @@ var installCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Install git hook for automatic commit message generation",
 	Long: `Installs a prepare-commit-msg git hook in the current repository.
-This allows automatic commit message generation when running 'git commit -m ""'.`,
+This allows automatic commit message generation when running 'git commit -m ""'.
+With --chain, an existing hook is kept as prepare-commit-msg.local and run first.
+With --lint, a commit-msg hook that runs 'commit-gen lint' is installed too.
+Hooks written by an older release are upgraded in place.
+With --global, the hook is added to the git template directory so that
+repositories created or cloned afterward get it.`,
 	RunE: runInstall,
 }
 
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Inspect the installed git hooks",
+}
+
+var hookStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the commit-gen hooks are installed and up to date",
+	RunE:  runHookStatus,
+}
+
 var uninstallCmd = &cobra.Command{
 	Use:   "uninstall",
 	Short: "Remove the git hook",
-	Long:  `Removes the prepare-commit-msg git hook from the current repository.`,
-	RunE:  runUninstall,
+	Long: `Removes the prepare-commit-msg git hook, and the commit-msg lint hook if
+installed, from the current repository.`,
+	RunE: runUninstall,
 }
 
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Manage configuration",
-	Long:  `View and modify commit-gen configuration.`,
-	RunE:  runConfig,
+	Long: `View and modify commit-gen configuration.
+
+Use --explain to list every effective setting with its source
+(default, file, or env).`,
+	RunE: runConfig,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a configuration value",
+	Long: `Set a single setting and save it to the config file, e.g.
+
+  commit-gen config set generation.style imperative
+
+Integers and booleans are validated; list settings take comma-separated values.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a configuration value",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
 }
 
 var previewCmd = &cobra.Command{
@@ -74,6 +120,48 @@ var cacheClearCmd = &cobra.Command{
 	RunE:  runCacheClear,
 }
 
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove expired sessions from the cache",
+	RunE:  runCachePrune,
+}
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List the models the OpenCode backend can use",
+	Long: `Prints one "provider/model" per line, the form generation.model expects.
+Server mode asks the server for its configured providers; run mode runs
+'opencode models'.`,
+	RunE: runModels,
+}
+
+var lintCmd = &cobra.Command{
+	Use:   "lint <file|->",
+	Short: "Check a commit message against the configured style",
+	Long: `Reads a commit message from a file, or from stdin with "-", and checks
+the subject format and type, generation.max_subject_length, and the blank
+line before the body. Git comment lines are ignored. Exits non-zero and lists
+every violation if the message does not pass.
+
+As a commit-msg hook:
+  commit-gen lint "$1"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLint,
+}
+
+var serverCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Manage the auto-started OpenCode server",
+	Long: `In server mode, commit-gen starts 'opencode serve' when no server is
+running and records its PID in the cache directory.`,
+}
+
+var serverStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the OpenCode server started by commit-gen",
+	RunE:  runServerStop,
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version information",
@@ -92,330 +180,1439 @@ var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize the configuration file",
 	Long: `Creates a configuration file at ~/.config/commit-gen/config.yaml
-with default settings. Run this command once to set up commit-gen.`,
+with default settings. Run this command once to set up commit-gen.
+
+Use --print to write the default configuration to stdout instead.`,
 	Run: runInit,
 }
 
-// runGenerate generates a commit message from staged changes.
-func runGenerate(cmd *cobra.Command, args []string) error {
+var releaseNotesCmd = &cobra.Command{
+	Use:   "release-notes",
+	Short: "Generate release notes from commits since a tag",
+	Long: `Collects commit subjects since the given tag, groups them by
+conventional commit type, and prints a markdown release-notes block.`,
+	RunE: runReleaseNotes,
+}
+
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Generate a commit message and commit the staged changes",
+	Long: `Generates a commit message from your staged changes and runs git commit
+with it. Use --author to commit on behalf of someone else.`,
+	RunE: runCommit,
+}
+
+var tokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Estimate the token count of the staged diff and prompt",
+	Long: `Assembles the prompt for your staged changes without sending it and
+prints estimated token counts, and whether the diff would be summarized.`,
+	RunE: runTokens,
+}
+
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a canned prompt to verify the model responds",
+	Long: `Sends a tiny fixed prompt through the full generation path and reports
+whether a usable response came back. Unlike 'health', this also catches
+authentication and model-name errors.`,
+	RunE: runTest,
+}
+
+// newGenerator applies the shared generation flags to the configuration,
+// checks that the backend is available, and creates a generator.
+func newGenerator(cmd *cobra.Command) (*generator.Generator, *config.Config, error) {
 	cfg := config.Get()
 
 	if modeFlag, _ := cmd.Flags().GetString("mode"); modeFlag != "" {
 		cfg.OpenCode.Mode = modeFlag
 	}
 
-	ignoreCheck, _ := cmd.Flags().GetBool("ignore-server-check")
-	if err := checkBackendAvailability(cfg, ignoreCheck); err != nil {
-		return err
+	if styleFlag, _ := cmd.Flags().GetString("style"); styleFlag != "" {
+		cfg.Generation.Style = styleFlag
 	}
 
-	cacheDir := filepath.Join(os.Getenv("HOME"), ".cache", "commit-gen")
-	sessionCache := cache.GetCache(24*time.Hour, cacheDir)
-	gen := generator.NewGenerator(cfg, sessionCache)
-
-	message, err := gen.Generate()
-	if err != nil {
-		color.Red("Error: %v", err)
-		return err
+	if recordFlag, _ := cmd.Flags().GetString("record"); recordFlag != "" {
+		cfg.OpenCode.RecordRequests = recordFlag
 	}
 
-	dryRun, _ := cmd.Flags().GetBool("dry-run")
-	isHook, _ := cmd.Flags().GetBool("hook")
-	noConfirm, _ := cmd.Flags().GetBool("no-confirm")
-
-	if isHook {
-		fmt.Println(message)
-		return nil
+	if relative, _ := cmd.Flags().GetBool("relative"); relative {
+		cfg.Git.RelativePaths = true
 	}
 
-	if dryRun {
-		fmt.Println(message)
-		return nil
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		cfg.Git.StagedOnly = false
 	}
 
-	shouldConfirm := cfg.Generation.Confirm && !noConfirm
-
-	if shouldConfirm {
-		message, err = confirmMessage(message, cfg)
-		if err != nil {
-			return err
-		}
-		if message == "" {
-			color.Yellow("Commit cancelled")
-			return nil
-		}
+	if cmd.Flags().Changed("candidates") {
+		cfg.Generation.Candidates, _ = cmd.Flags().GetInt("candidates")
 	}
 
-	if err := git.WriteCommitMessage(message); err != nil {
-		return fmt.Errorf("failed to write commit message: %w", err)
+	if cmd.Flags().Changed("context-lines") {
+		cfg.Git.ContextLines, _ = cmd.Flags().GetInt("context-lines")
 	}
-	color.Green("✓ Commit message generated:")
-	fmt.Printf("  %s\n", message)
-
-	return nil
-}
-
-// confirmMessage prompts the user to confirm, edit, or cancel the message.
-// Returns the final message or empty string if cancelled.
-func confirmMessage(message string, cfg *config.Config) (string, error) {
-	color.Cyan("Generated commit message:")
-	fmt.Printf("  %s\n\n", message)
-
-	for {
-		color.White("[y] Accept  [e] Edit  [r] Regenerate  [c] Cancel")
-		fmt.Print("Choice: ")
-
-		reader := bufio.NewReader(os.Stdin)
-		input, err := reader.ReadString('\n')
-		if err != nil {
-			return "", fmt.Errorf("failed to read input: %w", err)
-		}
-
-		choice := strings.ToLower(strings.TrimSpace(input))
-
-		switch choice {
-		case "y", "yes", "":
-			return message, nil
 
-		case "e", "edit":
-			edited, err := editMessage(message, cfg)
-			if err != nil {
-				color.Red("Error editing message: %v", err)
-				continue
-			}
-			return edited, nil
+	if cmd.Flags().Changed("body") {
+		cfg.Generation.Body, _ = cmd.Flags().GetBool("body")
+	}
+	if noBody, _ := cmd.Flags().GetBool("no-body"); noBody {
+		cfg.Generation.Body = false
+	}
 
-		case "r", "regenerate":
-			return "", fmt.Errorf("regenerate requested")
+	if signoff, _ := cmd.Flags().GetBool("signoff"); signoff {
+		cfg.Generation.Signoff = true
+	}
 
-		case "c", "cancel", "n", "no":
-			return "", nil
+	if showUsage, _ := cmd.Flags().GetBool("show-usage"); showUsage {
+		cfg.Generation.ShowUsage = true
+	}
 
-		default:
-			color.Yellow("Invalid choice. Please enter y, e, r, or c.")
-		}
+	if coAuthors, _ := cmd.Flags().GetStringArray("co-author"); len(coAuthors) > 0 {
+		cfg.Generation.CoAuthors = append(append([]string{}, cfg.Generation.CoAuthors...), coAuthors...)
 	}
-}
 
-// editMessage opens the user's editor to edit the commit message.
-func editMessage(message string, cfg *config.Config) (string, error) {
-	tmpFile, err := os.CreateTemp("", "commit-msg-*.txt")
-	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
+	if err := checkConfig(cfg); err != nil {
+		return nil, nil, err
 	}
-	tmpPath := tmpFile.Name()
-	defer func() {
-		_ = os.Remove(tmpPath)
-	}()
 
-	if _, err := tmpFile.WriteString(message); err != nil {
-		_ = tmpFile.Close()
-		return "", fmt.Errorf("failed to write to temp file: %w", err)
+	ignoreCheck, _ := cmd.Flags().GetBool("ignore-server-check")
+	if err := checkBackendAvailability(cfg, ignoreCheck); err != nil {
+		return nil, nil, err
 	}
-	_ = tmpFile.Close()
 
-	editor := cfg.Git.Editor
-	if editor == "" || editor == "cat" {
-		editor = os.Getenv("EDITOR")
+	sessionCache, err := cache.FromConfig(cfg)
+	if err != nil {
+		return nil, nil, err
 	}
-	if editor == "" {
-		editor = os.Getenv("VISUAL")
+	gen := generator.NewGenerator(cfg, sessionCache)
+	if full, _ := cmd.Flags().GetBool("full"); full {
+		gen.SetFull(true)
 	}
-	if editor == "" {
-		editor = "vim"
+	if noBody, _ := cmd.Flags().GetBool("no-body"); noBody {
+		gen.SetNoBody(true)
+	}
+	if force, _ := cmd.Flags().GetBool("force"); force {
+		gen.SetForce(true)
 	}
 
-	cmd := exec.Command(editor, tmpPath)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	return gen, cfg, nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("editor failed: %w", err)
+// checkConfig reports every configuration problem and fails if there are any.
+func checkConfig(cfg *config.Config) error {
+	problems := config.Validate(cfg)
+	if len(problems) == 0 {
+		return nil
 	}
 
-	edited, err := os.ReadFile(tmpPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read edited message: %w", err)
+	_, _ = color.New(color.FgRed).Fprintln(os.Stderr, "Configuration issues:")
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "  - %v\n", problem)
 	}
-
-	return strings.TrimSpace(string(edited)), nil
+	return fmt.Errorf("invalid configuration; fix the settings above (see 'commit-gen config --explain')")
 }
 
-// runInstall installs the git hook.
-func runInstall(cmd *cobra.Command, args []string) error {
-	if err := hook.Install(); err != nil {
-		color.Red("Error: %v", err)
-		return err
-	}
-	color.Green("✓ Git hook installed successfully")
-	fmt.Println("Now you can use: git commit")
-	fmt.Println("The generated message will open in your editor for confirmation.")
-	return nil
+// errInterrupted is returned when generation is cancelled with Ctrl-C.
+var errInterrupted = errors.New("generation interrupted")
+
+// errRegenerate is returned by the confirm prompts when the user asks for a new message.
+var errRegenerate = errors.New("regenerate requested")
+
+// maxRegenerations bounds how many times a message can be regenerated in one run.
+const maxRegenerations = 5
+
+// regenerator generates a fresh message, bypassing the cached one and
+// honoring Ctrl-C like the first generation.
+type regenerator struct {
+	gen *generator.Generator
 }
 
-// runUninstall removes the git hook.
-func runUninstall(cmd *cobra.Command, args []string) error {
-	if err := hook.Uninstall(); err != nil {
-		color.Red("Error: %v", err)
-		return err
+func (r regenerator) Generate() (string, error) {
+	result, err := r.GenerateResult()
+	if err != nil {
+		return "", err
 	}
-	color.Green("✓ Git hook removed successfully")
-	return nil
+	return result.Message, nil
 }
 
-// runConfig displays the current configuration.
-func runConfig(cmd *cobra.Command, args []string) error {
-	cfg := config.Get()
-
-	color.Cyan("OpenCode Configuration:")
-	fmt.Printf("  Mode: %s\n", cfg.OpenCode.Mode)
-	fmt.Printf("  Host: %s (server mode only)\n", cfg.OpenCode.Host)
-	fmt.Printf("  Port: %d (server mode only)\n", cfg.OpenCode.Port)
-	fmt.Printf("  Timeout: %ds\n", cfg.OpenCode.Timeout)
+func (r regenerator) GenerateResult() (*generator.GenerateResult, error) {
+	r.gen.SetForce(true)
+	return generateResult(r.gen)
+}
 
-	color.Cyan("\nGeneration Configuration:")
-	fmt.Printf("  Style: %s\n", cfg.Generation.Style)
-	fmt.Printf("  Confirm: %v\n", cfg.Generation.Confirm)
-	fmt.Printf("  Provider: %s\n", cfg.Generation.Model.Provider)
-	fmt.Printf("  Model: %s\n", cfg.Generation.Model.ModelID)
+// confirmWithRegenerate runs confirm and, each time the user asks to
+// regenerate, generates a new result and asks again, up to maxRegenerations.
+func confirmWithRegenerate(gen generator.ResultGenerator, result *generator.GenerateResult, cfg *config.Config, confirm func(*generator.GenerateResult, *config.Config) (string, error)) (string, error) {
+	for regenerations := 0; ; regenerations++ {
+		message, err := confirm(result, cfg)
+		if !errors.Is(err, errRegenerate) {
+			return message, err
+		}
+		if regenerations >= maxRegenerations {
+			return "", fmt.Errorf("stopped after %d regenerations; edit the message or run the command again", maxRegenerations)
+		}
 
-	color.Cyan("\nCache Configuration:")
-	fmt.Printf("  Enabled: %v (server mode only)\n", cfg.Cache.Enabled)
-	fmt.Printf("  TTL: %s\n", cfg.Cache.TTL)
+		color.Cyan("Regenerating...")
+		result, err = gen.GenerateResult()
+		if err != nil {
+			return "", err
+		}
+		reportSummarized(result, cfg)
+		reportUsage(result, cfg)
+	}
+}
 
-	color.Cyan("\nGit Configuration:")
-	fmt.Printf("  Editor: %s\n", cfg.Git.Editor)
-	fmt.Printf("  Staged Only: %v\n", cfg.Git.StagedOnly)
-	fmt.Printf("  Max Diff Size: %d bytes (%dKB)\n", cfg.Git.MaxDiffSize, cfg.Git.MaxDiffSize/1024)
+// cancellableGenerator is a generator whose in-flight requests follow a context.
+type cancellableGenerator interface {
+	generator.ResultGenerator
+	SetContext(ctx context.Context)
+}
 
-	return nil
+// generateResult runs gen.GenerateResult so that Ctrl-C aborts in-flight
+// server requests. Default interrupt handling is restored once it returns,
+// so Ctrl-C still exits at later prompts.
+func generateResult(gen cancellableGenerator) (*generator.GenerateResult, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	gen.SetContext(ctx)
+
+	result, err := gen.GenerateResult()
+	if err != nil && ctx.Err() != nil {
+		return nil, errInterrupted
+	}
+	return result, err
 }
 
-// runPreview shows staged changes and the generated commit message.
-func runPreview(cmd *cobra.Command, args []string) error {
-	diff, err := git.GetStagedDiff()
-	if err != nil {
-		color.Red("Error: %v", err)
-		return err
+// runGenerate generates a commit message from staged changes.
+func runGenerate(cmd *cobra.Command, args []string) error {
+	amend, _ := cmd.Flags().GetBool("amend")
+	reuseBody, _ := cmd.Flags().GetBool("reuse-body")
+	if reuseBody && !amend {
+		return fmt.Errorf("--reuse-body requires --amend")
 	}
 
-	if diff == "" {
-		color.Yellow("No staged changes found")
+	isHook, _ := cmd.Flags().GetBool("hook")
+	if isHook && amend && config.Get().Hook.OnAmend != "regenerate" {
+		// Print nothing so the hook keeps the message being amended.
 		return nil
 	}
 
-	color.Cyan("=== Staged Changes ===")
-	fmt.Println(diff)
-	color.Cyan("\n=== Generated Commit Message ===")
-
-	cfg := config.Get()
-
-	if modeFlag, _ := cmd.Flags().GetString("mode"); modeFlag != "" {
-		cfg.OpenCode.Mode = modeFlag
+	commit, _ := cmd.Flags().GetBool("commit")
+	jsonOutput, err := wantsJSON(cmd)
+	if err != nil {
+		return err
 	}
 
-	ignoreCheck, _ := cmd.Flags().GetBool("ignore-server-check")
-	if err := checkBackendAvailability(cfg, ignoreCheck); err != nil {
+	diff, provided, err := readProvidedDiff(cmd)
+	if err != nil {
 		return err
 	}
 
-	cacheDir := filepath.Join(os.Getenv("HOME"), ".cache", "commit-gen")
-	sessionCache := cache.GetCache(24*time.Hour, cacheDir)
-	gen := generator.NewGenerator(cfg, sessionCache)
-
-	message, err := gen.Generate()
+	gen, cfg, err := newGenerator(cmd)
 	if err != nil {
-		color.Red("Error generating message: %v", err)
 		return err
 	}
+	gen.SetAmend(amend, reuseBody)
+	if provided {
+		gen.SetDiff(diff)
+	}
 
-	color.Green(message)
-	return nil
-}
-
-// runCacheStatus displays cache statistics.
-func runCacheStatus(cmd *cobra.Command, args []string) error {
-	cacheDir := filepath.Join(os.Getenv("HOME"), ".cache", "commit-gen")
-	sessionCache := cache.GetCache(24*time.Hour, cacheDir)
-
-	total, valid, err := sessionCache.Status()
+	paths, err := selectPaths(cmd)
 	if err != nil {
 		color.Red("Error: %v", err)
 		return err
 	}
+	unstageRest, _ := cmd.Flags().GetBool("unstage-rest")
+	if commit && len(paths) > 0 && !unstageRest {
+		return fmt.Errorf("--commit with --pick or --paths requires --unstage-rest, so only the selected files are committed")
+	}
+	gen.SetPaths(paths)
 
-	color.Cyan("Cache Status:")
-	fmt.Printf("  Total entries: %d\n", total)
-	fmt.Printf("  Valid entries: %d\n", valid)
-	fmt.Printf("  Location: %s\n", cacheDir)
-
-	return nil
-}
-
-// runCacheClear clears all cached sessions.
-func runCacheClear(cmd *cobra.Command, args []string) error {
-	cacheDir := filepath.Join(os.Getenv("HOME"), ".cache", "commit-gen")
-	sessionCache := cache.GetCache(24*time.Hour, cacheDir)
-
-	if err := sessionCache.Clear(); err != nil {
+	result, err := generateResult(gen)
+	if err != nil {
 		color.Red("Error: %v", err)
 		return err
 	}
+	message := result.Message
+	if result.Cached && !quiet {
+		fmt.Fprintln(os.Stderr, "Diff unchanged since the last generation; reusing the previous message (use --force to regenerate)")
+	}
+	reportSummarized(result, cfg)
+	reportUsage(result, cfg)
 
-	color.Green("✓ Cache cleared successfully")
-	return nil
-}
+	if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+		printVerboseSummary(result)
+	}
 
-// runHealth checks if the OpenCode backend is available.
-func runHealth(cmd *cobra.Command, args []string) error {
-	cfg := config.Get()
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	noConfirm, _ := cmd.Flags().GetBool("no-confirm")
 
-	color.Cyan("Commit-gen:")
-	fmt.Printf("  Version: %s\n", version)
+	if isHook {
+		fmt.Print(finalizeMessage(message, cfg))
+		return nil
+	}
+	if dryRun {
+		if jsonOutput {
+			return printJSONResult(result)
+		}
+		fmt.Print(finalizeMessage(message, cfg))
+		return nil
+	}
 
-	configPath, _ := config.GetConfigPath()
-	color.Cyan("Configuration file:")
-	fmt.Printf("  Location: %s\n", configPath)
-	fmt.Printf("  Exists: %v\n", config.ConfigExists())
+	// Quiet and JSON output have no room for the confirmation prompt, and
+	// --diff-stdin has already consumed the input it would read.
+	diffStdin, _ := cmd.Flags().GetBool("diff-stdin")
+	shouldConfirm := cfg.Generation.Confirm && !noConfirm && !quiet && !jsonOutput && !diffStdin
 
-	color.Cyan("Configuration:")
-	fmt.Printf("  Mode: %s\n", cfg.OpenCode.Mode)
-	fmt.Printf("  Host: %s\n", cfg.OpenCode.Host)
-	fmt.Printf("  Port: %d\n", cfg.OpenCode.Port)
+	if shouldConfirm {
+		message, err = confirmWithRegenerate(regenerator{gen}, result, cfg, confirmResult)
+		if err != nil {
+			return err
+		}
+		if message == "" {
+			color.Yellow("Commit cancelled")
+			return nil
+		}
+	}
+
+	if commit {
+		if unstageRest && len(paths) > 0 {
+			if err := unstageOthers(paths); err != nil {
+				return err
+			}
+		}
+		if err := git.Commit(finalizeMessage(applyCommitTemplate(message, cfg), cfg), git.CommitOptions{Amend: amend}); err != nil {
+			color.Red("Error: %v", err)
+			return err
+		}
+		if jsonOutput {
+			return printJSONResult(result)
+		}
+		printResult("✓ Committed:", message)
+		return nil
+	}
+
+	if err := git.WriteCommitMessage(finalizeMessage(applyCommitTemplate(message, cfg), cfg)); err != nil {
+		return fmt.Errorf("failed to write commit message: %w", err)
+	}
+	if unstageRest && len(paths) > 0 {
+		if err := unstageOthers(paths); err != nil {
+			return err
+		}
+	}
+	if jsonOutput {
+		return printJSONResult(result)
+	}
+	printResult("✓ Commit message generated:", message)
+
+	return nil
+}
+
+// wantsJSON reads --output, which is "text" (the default) or "json". JSON
+// output silences decorations so stdout holds only the JSON object.
+func wantsJSON(cmd *cobra.Command) (bool, error) {
+	output, _ := cmd.Flags().GetString("output")
+	switch output {
+	case "", "text":
+		return false, nil
+	case "json":
+		silenceDecorations()
+		return true, nil
+	}
+	return false, fmt.Errorf("--output %q is not valid; use \"text\" or \"json\"", output)
+}
+
+// generateOutput is the object printed by --output json.
+type generateOutput struct {
+	Message          string           `json:"message"`
+	Candidates       []string         `json:"candidates,omitempty"`
+	Style            string           `json:"style"`
+	Model            string           `json:"model"`
+	Mode             string           `json:"mode"`
+	WasSummarized    bool             `json:"was_summarized"`
+	OriginalDiffSize int              `json:"original_diff_size"`
+	FilesChanged     []string         `json:"files_changed"`
+	SplitFiles       int              `json:"split_files,omitempty"`
+	Cached           bool             `json:"cached"`
+	TokenEstimate    int              `json:"token_estimate"`
+	Usage            *generator.Usage `json:"usage,omitempty"`
+	ElapsedMS        int64            `json:"elapsed_ms"`
+}
+
+// printJSONResult writes result to stdout as an indented generateOutput.
+func printJSONResult(result *generator.GenerateResult) error {
+	files := result.Files
+	if files == nil {
+		files = []string{}
+	}
+	data, err := json.MarshalIndent(generateOutput{
+		Message:          result.Message,
+		Candidates:       result.Candidates,
+		Style:            result.Style,
+		Model:            result.Model,
+		Mode:             result.Mode,
+		WasSummarized:    result.IsSummarized,
+		OriginalDiffSize: result.OriginalDiffSize,
+		FilesChanged:     files,
+		SplitFiles:       result.SplitFiles,
+		Cached:           result.Cached,
+		TokenEstimate:    result.TokenEstimate,
+		Usage:            result.Usage,
+		ElapsedMS:        result.Elapsed.Milliseconds(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printResult prints the final message under a heading, or on its own in quiet mode.
+func printResult(heading, message string) {
+	if quiet {
+		fmt.Println(message)
+		return
+	}
+	color.Green(heading)
+	fmt.Printf("  %s\n", message)
+}
+
+// applyCommitTemplate appends the trailers of git's commit.template when
+// git.merge_template is set. The hook does not need it: git already seeds
+// the message file with the template and the hook keeps it.
+func applyCommitTemplate(message string, cfg *config.Config) string {
+	if !cfg.Git.MergeTemplate {
+		return message
+	}
+	template, err := git.GetCommitTemplate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return message
+	}
+	return generator.MergeTemplateTrailers(message, template, git.GetCommentChar())
+}
+
+// finalizeMessage applies git.normalize_blank_lines and guarantees a trailing newline.
+func finalizeMessage(message string, cfg *config.Config) string {
+	if cfg.Git.NormalizeBlankLines {
+		return git.NormalizeBlankLines(message)
+	}
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	return message
+}
+
+// reportSummarized warns on stderr, per generation.on_summarized, that the
+// model only saw a summary of the diff.
+func reportSummarized(result *generator.GenerateResult, cfg *config.Config) {
+	if quiet {
+		return
+	}
+	if notice := summarizedNotice(result, cfg); notice != "" {
+		_, _ = color.New(color.FgYellow).Fprintln(os.Stderr, notice)
+	}
+}
+
+// summarizedNotice describes a summarized diff, or returns "" if the diff was
+// sent whole or generation.on_summarized is "silent". A summary despite
+// "split" means there were too many files to describe one by one, so it also
+// suggests committing them in smaller groups.
+func summarizedNotice(result *generator.GenerateResult, cfg *config.Config) string {
+	if !result.IsSummarized || cfg.Generation.OnSummarized == "silent" {
+		return ""
+	}
+	notice := fmt.Sprintf("Diff was %s; sent a summary (git.max_diff_size is %s)", formatSize(result.OriginalDiffSize), formatSize(cfg.Git.MaxDiffSize))
+	if cfg.Generation.OnSummarized == "split" && len(result.Files) > 1 {
+		notice += fmt.Sprintf("\nToo many files (%d) to describe one by one; consider committing them in smaller groups, e.g. with 'commit-gen generate --pick'", len(result.Files))
+	}
+	return notice
+}
+
+// formatSize renders a byte count as "N bytes" or, from 1KB up, rounded KB.
+func formatSize(bytes int) string {
+	if bytes < 1024 {
+		return fmt.Sprintf("%d bytes", bytes)
+	}
+	return fmt.Sprintf("%dKB", (bytes+512)/1024)
+}
+
+// reportUsage prints the reported token usage, and a cost estimate when
+// generation.pricing has a rate for the model, to stderr if generation.show_usage is set.
+func reportUsage(result *generator.GenerateResult, cfg *config.Config) {
+	if !cfg.Generation.ShowUsage {
+		return
+	}
+	fmt.Fprintln(os.Stderr, formatUsage(result, cfg.Generation.Pricing))
+}
+
+// formatUsage renders a result's usage as "Tokens: P in / C out", with an
+// estimated cost if pricing covers the model.
+func formatUsage(result *generator.GenerateResult, pricing []config.ModelPricing) string {
+	if result.Cached {
+		return "Tokens: none used (message reused from cache)"
+	}
+	if result.Usage == nil {
+		return "Tokens: usage unavailable"
+	}
+	line := fmt.Sprintf("Tokens: %d in / %d out", result.Usage.PromptTokens, result.Usage.CompletionTokens)
+	if cost, ok := generator.EstimateCost(result.Usage, result.Model, pricing); ok {
+		line += fmt.Sprintf(" (~$%.4f)", cost)
+	}
+	return line
+}
+
+// printVerboseSummary reports to stderr what output cleanup removed from the model response.
+func printVerboseSummary(result *generator.GenerateResult) {
+	fmt.Fprintf(os.Stderr, "Filtered %d line(s) of opencode output as noise\n", result.FilteredLines)
+	if result.StrippedMarkdown {
+		fmt.Fprintln(os.Stderr, "Stripped markdown code fence from the response")
+	}
+	for i, body := range result.PartialResponses {
+		fmt.Fprintf(os.Stderr, "Retried truncated server response %d (%d bytes):\n%s\n", i+1, len(body), body)
+	}
+}
+
+// diffSourceConflicts are flags that select or act on the staged changes,
+// which a diff from --diff-file or --diff-stdin replaces.
+var diffSourceConflicts = []string{"all", "pick", "paths", "amend", "commit"}
+
+// readProvidedDiff reads the diff given with --diff-file or --diff-stdin and
+// reports whether one was given. It fails if both are set, or if either is
+// combined with a flag that selects the staged changes.
+func readProvidedDiff(cmd *cobra.Command) (string, bool, error) {
+	diffFile, _ := cmd.Flags().GetString("diff-file")
+	diffStdin, _ := cmd.Flags().GetBool("diff-stdin")
+	if diffFile == "" && !diffStdin {
+		return "", false, nil
+	}
+	if diffFile != "" && diffStdin {
+		return "", false, fmt.Errorf("--diff-file and --diff-stdin cannot be used together")
+	}
+
+	source := "--diff-stdin"
+	if diffFile != "" {
+		source = "--diff-file"
+	}
+	for _, name := range diffSourceConflicts {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			return "", false, fmt.Errorf("%s cannot be used with --%s: the diff comes from %s instead of git", source, name, source)
+		}
+	}
+
+	var data []byte
+	var err error
+	if diffStdin {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(diffFile)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read diff: %w", err)
+	}
+	return string(data), true, nil
+}
+
+// selectPaths returns the staged paths chosen with --paths or interactively
+// with --pick. An empty result means every staged file.
+func selectPaths(cmd *cobra.Command) ([]string, error) {
+	paths, _ := cmd.Flags().GetStringSlice("paths")
+	pick, _ := cmd.Flags().GetBool("pick")
+	if !pick || len(paths) > 0 {
+		return paths, nil
+	}
+
+	if !isTerminal(os.Stdin) {
+		return nil, fmt.Errorf("--pick requires a terminal; use --paths to select files non-interactively")
+	}
+
+	files, err := git.GetChangedFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no staged changes found")
+	}
+
+	return pickFiles(files)
+}
+
+// pickFiles lists staged files and reads a selection like "1,3-4" from stdin.
+func pickFiles(files []string) ([]string, error) {
+	color.Cyan("Staged files:")
+	for i, f := range files {
+		fmt.Printf("  [%d] %s\n", i+1, f)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Select files (e.g. 1,3-4, empty for all): ")
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+
+		selected, err := parseSelection(strings.TrimSpace(input), files)
+		if err != nil {
+			color.Yellow("%v", err)
+			continue
+		}
+		return selected, nil
+	}
+}
+
+// parseSelection turns a comma-separated list of 1-based indexes and ranges into file paths.
+func parseSelection(input string, files []string) ([]string, error) {
+	if input == "" {
+		return nil, nil
+	}
+
+	seen := make(map[int]bool)
+	var selected []string
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q", part)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil {
+				return nil, fmt.Errorf("invalid selection %q", part)
+			}
+		}
+		if start < 1 || end > len(files) || start > end {
+			return nil, fmt.Errorf("selection %q out of range 1-%d", part, len(files))
+		}
+		for i := start; i <= end; i++ {
+			if !seen[i] {
+				seen[i] = true
+				selected = append(selected, files[i-1])
+			}
+		}
+	}
+	return selected, nil
+}
+
+// unstageOthers unstages every staged file not in keep.
+func unstageOthers(keep []string) error {
+	staged, err := git.GetChangedFiles()
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[string]bool, len(keep))
+	for _, p := range keep {
+		kept[filepath.ToSlash(filepath.Clean(p))] = true
+	}
+
+	var rest []string
+	for _, f := range staged {
+		if !kept[f] {
+			rest = append(rest, f)
+		}
+	}
+	return git.UnstageFiles(rest)
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmResult lets the user choose between candidates when there are
+// several, and otherwise confirm the single message.
+func confirmResult(result *generator.GenerateResult, cfg *config.Config) (string, error) {
+	if len(result.Candidates) > 1 {
+		return chooseCandidate(result.Candidates, cfg)
+	}
+	return confirmMessage(result.Message, cfg)
+}
+
+// chooseCandidate lists the candidate messages and prompts the user to pick,
+// edit, or cancel. Returns the final message or empty string if cancelled.
+func chooseCandidate(candidates []string, cfg *config.Config) (string, error) {
+	color.Cyan("Generated commit messages:")
+	for i, candidate := range candidates {
+		lines := strings.Split(candidate, "\n")
+		fmt.Printf("  [%d] %s\n", i+1, lines[0])
+		for _, line := range lines[1:] {
+			fmt.Printf("      %s\n", line)
+		}
+	}
+	fmt.Println()
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		color.White("[1-%d] Accept  [e1-e%d] Edit  [r] Regenerate  [c] Cancel", len(candidates), len(candidates))
+		fmt.Print("Choice: ")
+
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+
+		choice := strings.ToLower(strings.TrimSpace(input))
+		switch choice {
+		case "r", "regenerate":
+			return "", errRegenerate
+		case "c", "cancel", "n", "no":
+			return "", nil
+		}
+
+		edit := false
+		if rest, ok := strings.CutPrefix(choice, "e"); ok {
+			edit, choice = true, strings.TrimSpace(rest)
+			if choice == "" {
+				choice = "1"
+			}
+		}
+		index, err := strconv.Atoi(choice)
+		if err != nil || index < 1 || index > len(candidates) {
+			color.Yellow("Invalid choice. Please enter 1-%d, e followed by a number, r, or c.", len(candidates))
+			continue
+		}
+
+		if !edit {
+			return candidates[index-1], nil
+		}
+		edited, err := editMessage(candidates[index-1], cfg)
+		if err != nil {
+			color.Red("Error editing message: %v", err)
+			continue
+		}
+		return edited, nil
+	}
+}
+
+// confirmMessage prompts the user to confirm, edit, or cancel the message.
+// Returns the final message or empty string if cancelled.
+func confirmMessage(message string, cfg *config.Config) (string, error) {
+	color.Cyan("Generated commit message:")
+	fmt.Printf("  %s\n\n", message)
+
+	for {
+		color.White("[y] Accept  [e] Edit  [r] Regenerate  [c] Cancel")
+		fmt.Print("Choice: ")
+
+		reader := bufio.NewReader(os.Stdin)
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+
+		choice := strings.ToLower(strings.TrimSpace(input))
+
+		switch choice {
+		case "y", "yes", "":
+			return message, nil
+
+		case "e", "edit":
+			edited, err := editMessage(message, cfg)
+			if err != nil {
+				color.Red("Error editing message: %v", err)
+				continue
+			}
+			return edited, nil
+
+		case "r", "regenerate":
+			return "", errRegenerate
+
+		case "c", "cancel", "n", "no":
+			return "", nil
+
+		default:
+			color.Yellow("Invalid choice. Please enter y, e, r, or c.")
+		}
+	}
+}
+
+// editMessage opens the user's editor to edit the commit message.
+func editMessage(message string, cfg *config.Config) (string, error) {
+	tmpFile, err := os.CreateTemp("", "commit-msg-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+
+	if _, err := tmpFile.WriteString(message); err != nil {
+		_ = tmpFile.Close()
+		return "", fmt.Errorf("failed to write to temp file: %w", err)
+	}
+	_ = tmpFile.Close()
+
+	editor := cfg.Git.Editor
+	if editor == "" || editor == "cat" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = os.Getenv("VISUAL")
+	}
+	if editor == "" {
+		editor = "vim"
+	}
+
+	cmd := exec.Command(editor, tmpPath)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited message: %w", err)
+	}
+
+	return strings.TrimSpace(string(edited)), nil
+}
+
+// runInstall installs the git hook.
+func runInstall(cmd *cobra.Command, args []string) error {
+	chain, _ := cmd.Flags().GetBool("chain")
+	lint, _ := cmd.Flags().GetBool("lint")
+	if global, _ := cmd.Flags().GetBool("global"); global {
+		if chain {
+			return fmt.Errorf("--chain cannot be used with --global")
+		}
+		if lint {
+			return fmt.Errorf("--lint cannot be used with --global")
+		}
+		if err := hook.InstallGlobal(); err != nil {
+			color.Red("Error: %v", err)
+			return err
+		}
+		templateDir, _ := hook.GlobalTemplateDir()
+		color.Green("✓ Git hook installed into the template at %s", templateDir)
+		fmt.Println("Repositories created or cloned from now on get the hook.")
+		fmt.Println("Run 'commit-gen install' in existing repositories (or 'git init' there to copy the template).")
+		return nil
+	}
+
+	// Hooks from an older release are rewritten; remember their versions to report it.
+	before, _ := hook.Status()
+	generation, lintHook := findHookStatus(before, "prepare-commit-msg"), findHookStatus(before, "commit-msg")
+	upgradeLint := lintHook.Installed && !lintHook.UpToDate
+
+	install := hook.Install
+	if chain {
+		install = hook.InstallChained
+	}
+	err := install()
+	// With --lint, an existing generation hook is fine; only the lint hook is added.
+	generationInstalled := err == nil
+	if err != nil && !((lint || upgradeLint) && errors.Is(err, hook.ErrAlreadyInstalled)) {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	if lint || upgradeLint {
+		if err := hook.InstallLint(chain); err != nil {
+			color.Red("Error: %v", err)
+			return err
+		}
+		if upgradeLint {
+			color.Green("✓ Upgraded commit-msg hook from v%d to v%d", lintHook.Version, hook.CurrentVersion())
+		} else {
+			color.Green("✓ Commit message lint hook installed successfully")
+			fmt.Println("Commits whose message does not follow generation.style are rejected.")
+		}
+	}
+
+	if generationInstalled {
+		if generation.Installed {
+			color.Green("✓ Upgraded hook from v%d to v%d", generation.Version, hook.CurrentVersion())
+			return nil
+		}
+		color.Green("✓ Git hook installed successfully")
+		fmt.Println("Now you can use: git commit")
+		fmt.Println("The generated message will open in your editor for confirmation.")
+	}
+	return nil
+}
+
+// findHookStatus returns the status of the named hook, or an empty status if it is not listed.
+func findHookStatus(statuses []hook.HookStatus, name string) hook.HookStatus {
+	for _, status := range statuses {
+		if status.Name == name {
+			return status
+		}
+	}
+	return hook.HookStatus{Name: name}
+}
+
+// runHookStatus reports whether each commit-gen hook is installed and current.
+func runHookStatus(cmd *cobra.Command, args []string) error {
+	statuses, err := hook.Status()
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	color.Cyan("Git Hooks (current version v%d):", hook.CurrentVersion())
+	for _, status := range statuses {
+		switch {
+		case status.Installed && status.UpToDate:
+			color.Green("  ✓ %s: installed (v%d, up to date) at %s", status.Name, status.Version, status.Path)
+		case status.Installed:
+			color.Yellow("  ! %s: installed (v%d, outdated; run 'commit-gen install' to upgrade) at %s", status.Name, status.Version, status.Path)
+		case status.Foreign:
+			fmt.Printf("  - %s: another hook is installed at %s (not managed by commit-gen)\n", status.Name, status.Path)
+		default:
+			fmt.Printf("  - %s: not installed\n", status.Name)
+		}
+	}
+	return nil
+}
+
+// runUninstall removes the git hook.
+func runUninstall(cmd *cobra.Command, args []string) error {
+	uninstall := hook.Uninstall
+	if global, _ := cmd.Flags().GetBool("global"); global {
+		uninstall = hook.UninstallGlobal
+	}
+	if err := uninstall(); err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+	color.Green("✓ Git hook removed successfully")
+	return nil
+}
+
+// runConfig displays the current configuration.
+func runConfig(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+
+	if explain, _ := cmd.Flags().GetBool("explain"); explain {
+		return runConfigExplain()
+	}
+
+	color.Cyan("OpenCode Configuration:")
+	fmt.Printf("  Mode: %s\n", cfg.OpenCode.Mode)
+	fmt.Printf("  Binary: %s\n", cfg.OpenCode.Binary)
+	fmt.Printf("  Host: %s (server mode only)\n", cfg.OpenCode.Host)
+	fmt.Printf("  Port: %d (server mode only)\n", cfg.OpenCode.Port)
+	if cfg.OpenCode.Scheme != "http" || cfg.OpenCode.BasePath != "" {
+		fmt.Printf("  URL: %s (server mode only)\n", opencode.BaseURL(cfg.OpenCode.Scheme, cfg.OpenCode.Host, cfg.OpenCode.Port, cfg.OpenCode.BasePath))
+	}
 	fmt.Printf("  Timeout: %ds\n", cfg.OpenCode.Timeout)
-	fmt.Printf("  Cache: %v\n", cfg.Cache.Enabled)
-	fmt.Printf("  Max Diff Size: %d bytes\n", cfg.Git.MaxDiffSize)
+	fmt.Printf("  Max Retries: %d (server mode only)\n", cfg.OpenCode.MaxRetries)
+	fmt.Printf("  API Key: %s (server mode only)\n", apiKeyStatus(cfg.OpenCode.APIKey))
+	if cfg.OpenCode.RecordRequests != "" {
+		fmt.Printf("  Record Requests: %s (server mode only)\n", cfg.OpenCode.RecordRequests)
+	}
+
+	color.Cyan("\nGeneration Configuration:")
+	fmt.Printf("  Style: %s\n", cfg.Generation.Style)
+	fmt.Printf("  Confirm: %v\n", cfg.Generation.Confirm)
+	fmt.Printf("  Body: %v\n", cfg.Generation.Body)
+	fmt.Printf("  Detect Trivial Changes: %v\n", cfg.Generation.DetectTrivial)
+	fmt.Printf("  Skip Backend Check: %v\n", cfg.Generation.SkipBackendCheck)
+	fmt.Printf("  Use PR Template: %v\n", cfg.Generation.UsePRTemplate)
+	fmt.Printf("  Max Subject Length: %d (on too long: %s)\n", cfg.Generation.MaxSubjectLength, cfg.Generation.OnTooLong)
+	fmt.Printf("  Include Branch: %v\n", cfg.Generation.IncludeBranch)
+	if cfg.Generation.TicketPattern != "" {
+		fmt.Printf("  Ticket Pattern: %s (%s)\n", cfg.Generation.TicketPattern, cfg.Generation.TicketPosition)
+	}
+	fmt.Printf("  Force Inferred Scope: %v (override model scope: %v)\n", cfg.Generation.ForceInferredScope, cfg.Generation.OverrideModelScope)
+	fmt.Printf("  Provider: %s\n", cfg.Generation.Model.Provider)
+	fmt.Printf("  Model: %s\n", cfg.Generation.Model.ModelID)
+	if cfg.Generation.MaxCostTokens > 0 {
+		fmt.Printf("  Max Cost Tokens: %d\n", cfg.Generation.MaxCostTokens)
+	}
+	if len(cfg.Generation.AllowedProviders) > 0 {
+		fmt.Printf("  Allowed Providers: %s\n", strings.Join(cfg.Generation.AllowedProviders, ", "))
+	}
+	if len(cfg.Generation.TypeMap) > 0 {
+		fmt.Printf("  Type Map: %v\n", cfg.Generation.TypeMap)
+	}
+	if len(cfg.Generation.CustomStyles) > 0 {
+		names := make([]string, 0, len(cfg.Generation.CustomStyles))
+		for name := range cfg.Generation.CustomStyles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("  Custom Styles: %s\n", strings.Join(names, ", "))
+	}
+	for _, rule := range cfg.Generation.Overrides {
+		fmt.Printf("  Override: %s -> style=%q model=%s/%s\n", rule.PathGlob, rule.Style, rule.Model.Provider, rule.Model.ModelID)
+	}
+
+	color.Cyan("\nCache Configuration:")
+	fmt.Printf("  Enabled: %v (server mode only)\n", cfg.Cache.Enabled)
+	fmt.Printf("  TTL: %s\n", cfg.Cache.TTL)
+
+	color.Cyan("\nGit Configuration:")
+	fmt.Printf("  Editor: %s\n", cfg.Git.Editor)
+	fmt.Printf("  Normalize Blank Lines: %v\n", cfg.Git.NormalizeBlankLines)
+	fmt.Printf("  Merge Template: %v\n", cfg.Git.MergeTemplate)
+	fmt.Printf("  Staged Only: %v\n", cfg.Git.StagedOnly)
+	fmt.Printf("  Max Diff Size: %d bytes (%dKB)\n", cfg.Git.MaxDiffSize, cfg.Git.MaxDiffSize/1024)
+	fmt.Printf("  Relative Paths: %v\n", cfg.Git.RelativePaths)
+	if cfg.Git.DiffAlgorithm != "" {
+		fmt.Printf("  Diff Algorithm: %s\n", cfg.Git.DiffAlgorithm)
+	}
+
+	return nil
+}
+
+// runPreview shows staged changes and the generated commit message.
+func runPreview(cmd *cobra.Command, args []string) error {
+	jsonOutput, err := wantsJSON(cmd)
+	if err != nil {
+		return err
+	}
+
+	diff, provided, err := readProvidedDiff(cmd)
+	if err != nil {
+		return err
+	}
+
+	label := "Provided Diff"
+	if !provided {
+		all, _ := cmd.Flags().GetBool("all")
+		getDiff := git.GetStagedDiff
+		label = "Staged Changes"
+		if all || !config.Get().Git.StagedOnly {
+			getDiff, label = git.GetWorkingDiff, "Changes"
+		}
+
+		diff, err = getDiff()
+		if err != nil {
+			color.Red("Error: %v", err)
+			return err
+		}
+	}
+
+	if diff == "" {
+		color.Yellow("No %s found", strings.ToLower(label))
+		return nil
+	}
+
+	if !quiet && !jsonOutput {
+		color.Cyan("=== %s ===", label)
+		if pretty, _ := cmd.Flags().GetBool("pretty"); pretty {
+			printGroupedDiff(diff)
+		} else {
+			fmt.Println(diff)
+		}
+	}
+	color.Cyan("\n=== Generated Commit Message ===")
+
+	gen, cfg, err := newGenerator(cmd)
+	if err != nil {
+		return err
+	}
+	if provided {
+		gen.SetDiff(diff)
+	}
+
+	var streamed strings.Builder
+	if stream, _ := cmd.Flags().GetBool("stream"); stream && !quiet && !jsonOutput {
+		if gen.GetMode() != "server" {
+			color.Yellow("Note: --stream requires server mode; waiting for the full message")
+		}
+		gen.SetStream(func(chunk string) {
+			streamed.WriteString(chunk)
+			fmt.Print(chunk)
+		})
+	}
+
+	result, err := generateResult(gen)
+	if streamed.Len() > 0 {
+		fmt.Println()
+	}
+	if err != nil {
+		color.Red("Error generating message: %v", err)
+		return err
+	}
+
+	if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+		printVerboseSummary(result)
+	}
+	reportSummarized(result, cfg)
+
+	if jsonOutput {
+		return printJSONResult(result)
+	}
+	if quiet {
+		fmt.Println(result.Message)
+		return nil
+	}
+	if strings.TrimSpace(streamed.String()) != result.Message {
+		color.Green(result.Message)
+	}
+
+	color.Cyan("\n=== Generation Details ===")
+	fmt.Printf("  Mode: %s\n", result.Mode)
+	fmt.Printf("  Model: %s\n", result.Model)
+	fmt.Printf("  Prompt: ~%d tokens\n", result.TokenEstimate)
+	fmt.Printf("  Summarized: %v\n", result.IsSummarized)
+	if result.DownsizedTo > 0 {
+		fmt.Printf("  Downsized: diff reduced to %d bytes after a context-length error\n", result.DownsizedTo)
+	}
+	if len(result.CollapsedFiles) > 0 {
+		fmt.Printf("  Collapsed: %s\n", strings.Join(result.CollapsedFiles, ", "))
+	}
+	if result.SplitFiles > 0 {
+		fmt.Printf("  Split: described %d files separately, then combined\n", result.SplitFiles)
+	}
+	fmt.Printf("  Time: %v\n", result.Elapsed.Round(time.Millisecond))
+	if cfg.Generation.ShowUsage {
+		fmt.Printf("  %s\n", formatUsage(result, cfg.Generation.Pricing))
+	}
+	return nil
+}
+
+// runCacheStatus displays cache statistics.
+func runCacheStatus(cmd *cobra.Command, args []string) error {
+	sessionCache, err := cache.FromConfig(config.Get())
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	total, valid, err := sessionCache.Status()
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
 
-	color.Cyan("OpenCode Backend Check:")
+	color.Cyan("Cache Status:")
+	fmt.Printf("  Total entries: %d\n", total)
+	fmt.Printf("  Valid entries: %d\n", valid)
+	fmt.Printf("  Location: %s\n", sessionCache.Dir())
+
+	return nil
+}
+
+// runCachePrune removes expired sessions from the cache.
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	sessionCache, err := cache.FromConfig(config.Get())
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	removed, err := sessionCache.Prune()
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	color.Green("✓ Removed %d expired session(s)", removed)
+	return nil
+}
+
+// runModels prints the models available to the configured backend.
+func runModels(cmd *cobra.Command, args []string) error {
+	cfg := *config.Get()
+	if modeFlag, _ := cmd.Flags().GetString("mode"); modeFlag != "" {
+		cfg.OpenCode.Mode = modeFlag
+	}
 
 	if cfg.OpenCode.Mode == "server" {
-		client := opencode.NewClient(cfg.OpenCode.Host, cfg.OpenCode.Port, cfg.OpenCode.Timeout)
-		healthy, err := client.CheckHealth()
-		if err != nil {
-			color.Red("✗ OpenCode server is not running")
+		if err := checkBackendAvailability(&cfg, false); err != nil {
+			color.Red("Error: %v", err)
 			return err
 		}
-		if healthy {
-			color.Green("✓ OpenCode server is running")
+	}
+
+	models, err := listModels(&cfg)
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+	for _, model := range models {
+		fmt.Println(model)
+	}
+	return nil
+}
+
+// listModels asks the configured backend for its models. Listing is not
+// supported in openai mode.
+func listModels(cfg *config.Config) ([]opencode.ModelInfo, error) {
+	switch cfg.OpenCode.Mode {
+	case "openai":
+		return nil, fmt.Errorf("listing models is not supported in openai mode; see your provider's model list")
+	case "server":
+		return newServerClient(cfg).ListModels()
+	}
+	return opencode.NewRunnerWithBinary(cfg.OpenCode.Binary, cfg.OpenCode.Timeout).ListModels()
+}
+
+// runServerStop stops the server recorded by an earlier auto-start.
+func runServerStop(cmd *cobra.Command, args []string) error {
+	pid, err := opencode.StopServer(serverStateDir(config.Get()))
+	if errors.Is(err, opencode.ErrNoServerRecorded) {
+		color.Yellow("No OpenCode server started by commit-gen is running")
+		return nil
+	}
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	color.Green("✓ Stopped OpenCode server (pid %d)", pid)
+	return nil
+}
+
+// runCacheClear clears all cached sessions.
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	sessionCache, err := cache.FromConfig(config.Get())
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	cfg := config.Get()
+	localOnly, _ := cmd.Flags().GetBool("local-only")
+	if cfg.OpenCode.Mode == "server" && !localOnly {
+		deleteServerSessions(newServerClient(cfg), sessionCache.Sessions())
+	}
+
+	if err := sessionCache.Clear(); err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	color.Green("✓ Cache cleared successfully")
+	return nil
+}
+
+// deleteServerSessions deletes cached sessions on the OpenCode server so
+// clearing the cache does not leave them orphaned, and returns how many were
+// deleted. Failures are only warnings; the local cache is cleared regardless.
+func deleteServerSessions(client *opencode.Client, sessions []cache.CachedSession) int {
+	deleted := 0
+	for _, session := range sessions {
+		if err := client.DeleteSession(session.SessionID); err != nil {
+			color.Yellow("Warning: failed to delete server session %s: %v", session.SessionID, err)
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		color.Green("✓ Deleted %d server session(s)", deleted)
+	}
+	return deleted
+}
+
+// runHealth checks if the OpenCode backend is available. Failures go to
+// stderr so --quiet still reports them, and any failure makes it return an error.
+func runHealth(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+	errOut := cmd.ErrOrStderr()
+	var failed []string
+	fail := func(check, format string, a ...any) {
+		_, _ = color.New(color.FgRed).Fprintf(errOut, format+"\n", a...)
+		failed = append(failed, check)
+	}
+
+	color.Cyan("Commit-gen:")
+	fmt.Fprintf(infoOut, "  Version: %s\n", version)
+
+	configPath, _ := config.GetConfigPath()
+	color.Cyan("Configuration file:")
+	fmt.Fprintf(infoOut, "  Location: %s\n", configPath)
+	fmt.Fprintf(infoOut, "  Exists: %v\n", config.ConfigExists())
+	if path := config.RepoFileUsed(); path != "" {
+		fmt.Fprintf(infoOut, "  Repository: %s\n", path)
+	}
+
+	color.Cyan("Configuration:")
+	fmt.Fprintf(infoOut, "  Mode: %s\n", cfg.OpenCode.Mode)
+	fmt.Fprintf(infoOut, "  Host: %s\n", cfg.OpenCode.Host)
+	fmt.Fprintf(infoOut, "  Port: %d\n", cfg.OpenCode.Port)
+	fmt.Fprintf(infoOut, "  URL: %s\n", opencode.BaseURL(cfg.OpenCode.Scheme, cfg.OpenCode.Host, cfg.OpenCode.Port, cfg.OpenCode.BasePath))
+	fmt.Fprintf(infoOut, "  Timeout: %ds\n", cfg.OpenCode.Timeout)
+	fmt.Fprintf(infoOut, "  API Key: %s\n", apiKeyStatus(cfg.OpenCode.APIKey))
+	fmt.Fprintf(infoOut, "  Cache: %v\n", cfg.Cache.Enabled)
+	fmt.Fprintf(infoOut, "  Max Diff Size: %d bytes\n", cfg.Git.MaxDiffSize)
+	if cfg.OpenCode.Mode == "openai" {
+		fmt.Fprintf(infoOut, "  OpenAI URL: %s\n", cfg.OpenAI.BaseURL)
+		fmt.Fprintf(infoOut, "  OpenAI API Key: %s\n", apiKeyStatus(cfg.OpenAI.APIKey))
+	}
+
+	if problems := config.Validate(cfg); len(problems) > 0 {
+		fail("config", "✗ Configuration issues:")
+		for _, problem := range problems {
+			fmt.Fprintf(errOut, "  ✗ %v\n", problem)
+		}
+	}
+
+	color.Cyan("OpenCode Backend Check:")
+
+	backendOK := true
+	if cfg.OpenCode.Mode == "openai" {
+		if err := probeOpenAI(cfg); err != nil {
+			fail("backend", "✗ OpenAI-compatible API is not usable: %v", err)
+			backendOK = false
+		} else {
+			color.Green("✓ OpenAI-compatible API is reachable (openai mode); no OpenCode backend needed")
+		}
+	} else if cfg.OpenCode.Mode == "server" {
+		client := newServerClient(cfg)
+		if healthy, err := client.CheckHealth(); err != nil || !healthy {
+			fail("backend", "✗ OpenCode server is not running")
+			backendOK = false
 		} else {
-			color.Red("✗ OpenCode server is not running")
+			color.Green("✓ OpenCode server is running")
 		}
 	} else {
-		runner := opencode.NewRunner(cfg.OpenCode.Timeout)
-		available, err := runner.CheckAvailable()
-		if err != nil || !available {
-			color.Red("✗ opencode binary not found in PATH")
-			return err
+		runner := opencode.NewRunnerWithBinary(cfg.OpenCode.Binary, cfg.OpenCode.Timeout)
+		if available, err := runner.CheckAvailable(); err != nil || !available {
+			fail("backend", "✗ %s binary not found in PATH", opencode.ResolveBinary(cfg.OpenCode.Binary))
+			backendOK = false
+		} else {
+			color.Green("✓ opencode binary is available (run mode)")
 		}
-		color.Green("✓ opencode binary is available (run mode)")
 	}
 
+	if cfg.OpenCode.Mode != "openai" && backendOK {
+		if problem := reportModelAvailability(cfg); problem != "" {
+			fail("model", "%s", problem)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("health check failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// reportModelAvailability checks generation.model against the backend's
+// model list and returns a failure line when the model is definitely missing.
+// Failing to list models is only a warning, since the model may still work.
+func reportModelAvailability(cfg *config.Config) string {
+	model := cfg.Generation.Model.Provider + "/" + cfg.Generation.Model.ModelID
+	models, err := listModels(cfg)
+	switch {
+	case err != nil:
+		color.Yellow("? Could not check model %s: %v", model, err)
+	case len(models) == 0:
+		color.Yellow("? Could not check model %s: the backend listed no models", model)
+	case opencode.HasModel(models, cfg.Generation.Model.Provider, cfg.Generation.Model.ModelID):
+		color.Green("✓ Model %s is available", model)
+	default:
+		return fmt.Sprintf("✗ Model %s is not available; run 'commit-gen models' to see valid models", model)
+	}
+	return ""
+}
+
+// runConfigSet validates and saves a single setting.
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	path, err := config.SetValue(args[0], args[1])
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	value, _ := config.GetValue(args[0])
+	color.Green("✓ Set %s = %v", args[0], value)
+	fmt.Printf("  Saved to: %s\n", path)
+	return nil
+}
+
+// runConfigGet prints the effective value of a single setting.
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	value, err := config.GetValue(args[0])
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	fmt.Println(value)
+	return nil
+}
+
+// apiKeyStatus describes whether an API key is configured without revealing it.
+func apiKeyStatus(key string) string {
+	if key == "" {
+		return "not configured"
+	}
+	return "configured"
+}
+
+// runConfigExplain prints every effective setting with its source.
+func runConfigExplain() error {
+	if path := config.FileUsed(); path != "" {
+		color.Cyan("Config file: %s", path)
+	} else {
+		color.Cyan("Config file: (none, using defaults)")
+	}
+	if path := config.RepoFileUsed(); path != "" {
+		color.Cyan("Repository config: %s", path)
+	}
+
+	for _, s := range config.Explain() {
+		source := s.Source
+		if source == "env" {
+			source += " " + config.EnvVar(s.Key)
+		}
+		fmt.Printf("  %s = %v  [%s]\n", s.Key, s.Value, source)
+	}
 	return nil
 }
 
+// printGroupedDiff prints the diff grouped by file with colored headers and per-file line counts.
+func printGroupedDiff(diff string) {
+	statuses := map[string]string{}
+	if files, err := git.GetChangedFilesWithStatus(); err == nil {
+		for _, f := range files {
+			statuses[f.Path] = f.Status
+		}
+	}
+
+	files := git.GroupDiffByFile(diff)
+	header := color.New(color.FgCyan, color.Bold)
+	added := color.New(color.FgGreen)
+	removed := color.New(color.FgRed)
+	hunk := color.New(color.FgMagenta)
+
+	fmt.Printf("%d file(s) changed\n", len(files))
+	for _, f := range files {
+		status := statuses[f.Path]
+		if status == "" {
+			status = "M"
+		}
+		fmt.Println()
+		header.Printf("── %s %s ", status, f.Path)
+		added.Printf("+%d ", f.Added)
+		removed.Printf("-%d\n", f.Removed)
+
+		for _, line := range strings.Split(strings.TrimRight(f.Body, "\n"), "\n") {
+			switch {
+			case strings.HasPrefix(line, "diff --git "), strings.HasPrefix(line, "index "),
+				strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+				continue
+			case strings.HasPrefix(line, "@@"):
+				hunk.Println(line)
+			case strings.HasPrefix(line, "+"):
+				added.Println(line)
+			case strings.HasPrefix(line, "-"):
+				removed.Println(line)
+			default:
+				fmt.Println(line)
+			}
+		}
+	}
+}
+
 // runInit initializes the configuration file.
 func runInit(cmd *cobra.Command, args []string) {
+	if printOnly, _ := cmd.Flags().GetBool("print"); printOnly {
+		fmt.Print(config.DefaultConfigYAML)
+		return
+	}
+
 	if config.ConfigExists() {
 		configPath, _ := config.GetConfigPath()
 		color.Yellow("Configuration file already exists at: %s", configPath)
@@ -441,3 +1638,193 @@ func runInit(cmd *cobra.Command, args []string) {
 	fmt.Println("  2. Run 'commit-gen install' in your git repository")
 	fmt.Println("  3. Use 'git commit' to generate commit messages")
 }
+
+// runReleaseNotes prints grouped release notes for commits since a tag.
+func runReleaseNotes(cmd *cobra.Command, args []string) error {
+	since, _ := cmd.Flags().GetString("since")
+	if since == "" {
+		return fmt.Errorf("--since is required (e.g. --since v1.2.0)")
+	}
+
+	subjects, err := git.GetCommitSubjectsSince(since)
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	if len(subjects) == 0 {
+		color.Yellow("No commits found since %s", since)
+		return nil
+	}
+
+	fmt.Print(generator.BuildReleaseNotes(subjects))
+	return nil
+}
+
+// runTest sends a canned prompt through the generator and reports the result.
+func runTest(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+
+	if modeFlag, _ := cmd.Flags().GetString("mode"); modeFlag != "" {
+		cfg.OpenCode.Mode = modeFlag
+	}
+
+	if err := checkBackendAvailability(cfg, false); err != nil {
+		return err
+	}
+
+	sessionCache, err := cache.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	gen := generator.NewGenerator(cfg, sessionCache)
+
+	color.Cyan("Testing %s/%s (%s mode)...", cfg.Generation.Model.Provider, cfg.Generation.Model.ModelID, gen.GetMode())
+
+	result, err := gen.Probe()
+	if err != nil {
+		color.Red("✗ Model did not respond: %v", err)
+		return err
+	}
+
+	fmt.Printf("  Response: %q\n", result.Response)
+	fmt.Printf("  Time: %v\n", result.Duration.Round(time.Millisecond))
+
+	if !result.OK {
+		color.Red("✗ Model responded but the response was not usable")
+		return fmt.Errorf("unexpected response from model")
+	}
+
+	color.Green("✓ Model responded successfully")
+	return nil
+}
+
+// runCommit generates a message and commits the staged changes with it.
+func runCommit(cmd *cobra.Command, args []string) error {
+	author, _ := cmd.Flags().GetString("author")
+	if author != "" {
+		if err := git.ValidateAuthor(author); err != nil {
+			color.Red("Error: %v", err)
+			return err
+		}
+	}
+	date, _ := cmd.Flags().GetString("date")
+	committerDate, _ := cmd.Flags().GetBool("committer-date")
+	if date != "" {
+		if err := git.ValidateDate(date); err != nil {
+			color.Red("Error: %v", err)
+			return err
+		}
+	} else if committerDate {
+		return fmt.Errorf("--committer-date requires --date")
+	}
+
+	gen, cfg, err := newGenerator(cmd)
+	if err != nil {
+		return err
+	}
+
+	result, err := generateResult(gen)
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+	reportSummarized(result, cfg)
+	reportUsage(result, cfg)
+	message := result.Message
+
+	noConfirm, _ := cmd.Flags().GetBool("no-confirm")
+	if cfg.Generation.Confirm && !noConfirm && !quiet {
+		message, err = confirmWithRegenerate(regenerator{gen}, result, cfg, confirmResult)
+		if err != nil {
+			return err
+		}
+		if message == "" {
+			color.Yellow("Commit cancelled")
+			return nil
+		}
+	}
+
+	if err := git.Commit(finalizeMessage(applyCommitTemplate(message, cfg), cfg), git.CommitOptions{Author: author, Date: date, CommitterDate: committerDate}); err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	printResult("✓ Committed:", message)
+	return nil
+}
+
+// runTokens prints token estimates for the staged diff and assembled prompt.
+func runTokens(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+	if relative, _ := cmd.Flags().GetBool("relative"); relative {
+		cfg.Git.RelativePaths = true
+	}
+
+	sessionCache, err := cache.FromConfig(cfg)
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+	gen := generator.NewGenerator(cfg, sessionCache)
+	if full, _ := cmd.Flags().GetBool("full"); full {
+		gen.SetFull(true)
+	}
+
+	report, err := gen.TokenReport()
+	if err != nil {
+		color.Red("Error: %v", err)
+		return err
+	}
+
+	color.Cyan("Token Estimate:")
+	fmt.Printf("  Staged diff: ~%d tokens (%d bytes)\n", report.DiffTokens, report.DiffBytes)
+	fmt.Printf("  Prompt: ~%d tokens (%d bytes)\n", report.PromptTokens, report.PromptBytes)
+	if report.Summarized {
+		color.Yellow("  Summarization: yes (diff exceeds git.max_diff_size of %d bytes)", report.MaxDiffSize)
+	} else {
+		fmt.Printf("  Summarization: no (limit %d bytes)\n", report.MaxDiffSize)
+	}
+	if cfg.Generation.MaxCostTokens > 0 && report.PromptTokens > cfg.Generation.MaxCostTokens {
+		color.Yellow("  Over generation.max_cost_tokens (%d)", cfg.Generation.MaxCostTokens)
+	}
+
+	return nil
+}
+
+// runLint checks a commit message file and lists every violation on stderr.
+func runLint(cmd *cobra.Command, args []string) error {
+	cfg := *config.Get()
+	if styleFlag, _ := cmd.Flags().GetString("style"); styleFlag != "" {
+		cfg.Generation.Style = styleFlag
+	}
+
+	var content []byte
+	var err error
+	if args[0] == "-" {
+		content, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		content, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read commit message: %w", err)
+	}
+
+	commentChar := git.GetCommentChar()
+	message := git.StripCommitComments(string(content), commentChar)
+	violations := generator.LintMessage(message, &cfg)
+	if template, _ := git.GetCommitTemplate(); template != "" && message != "" && git.IsCommitMessageEmpty(message, template, commentChar) {
+		violations = []string{"message only contains the commit.template text"}
+	}
+	if len(violations) == 0 {
+		if !quiet {
+			color.Green("✓ Commit message follows the %s style", cfg.Generation.Style)
+		}
+		return nil
+	}
+
+	for _, violation := range violations {
+		fmt.Fprintf(cmd.ErrOrStderr(), "  ✗ %s\n", violation)
+	}
+	return fmt.Errorf("commit message has %d problem(s)", len(violations))
+}
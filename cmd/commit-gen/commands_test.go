@@ -0,0 +1,715 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/avgt93/commit-gen/internal/cache"
+	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/avgt93/commit-gen/internal/generator"
+	"github.com/avgt93/commit-gen/internal/git/gittest"
+	"github.com/avgt93/commit-gen/internal/hook"
+	"github.com/avgt93/commit-gen/internal/llm/openai/openaitest"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// newFlagTestCommand returns a command with the generation flags newGenerator reads.
+func newFlagTestCommand() *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().StringP("style", "s", "", "")
+	cmd.Flags().StringP("mode", "m", "", "")
+	cmd.Flags().Bool("ignore-server-check", true, "")
+	return cmd
+}
+
+func TestStyleFlagOverridesConfig(t *testing.T) {
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	cfg := config.Get()
+	original := cfg.Generation.Style
+	defer func() { cfg.Generation.Style = original }()
+
+	cfg.Generation.Style = "conventional"
+	cmd := newFlagTestCommand()
+	if err := cmd.Flags().Set("style", "imperative"); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+
+	gen, _, err := newGenerator(cmd)
+	if err != nil {
+		t.Fatalf("newGenerator failed: %v", err)
+	}
+	if got := gen.GetConfig().Generation.Style; got != "imperative" {
+		t.Errorf("Expected style imperative, got %q", got)
+	}
+
+	t.Log("✓ --style overrides generation.style")
+}
+
+func TestEmptyStyleFlagKeepsConfig(t *testing.T) {
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	cfg := config.Get()
+	original := cfg.Generation.Style
+	defer func() { cfg.Generation.Style = original }()
+
+	cfg.Generation.Style = "detailed"
+	gen, _, err := newGenerator(newFlagTestCommand())
+	if err != nil {
+		t.Fatalf("newGenerator failed: %v", err)
+	}
+	if got := gen.GetConfig().Generation.Style; got != "detailed" {
+		t.Errorf("Expected configured style detailed, got %q", got)
+	}
+}
+
+func TestCoAuthorFlagRejectsMalformedEntry(t *testing.T) {
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	cfg := config.Get()
+	original := cfg.Generation.CoAuthors
+	defer func() { cfg.Generation.CoAuthors = original }()
+
+	cmd := newFlagTestCommand()
+	cmd.Flags().StringArray("co-author", nil, "")
+	for _, author := range []string{"Jane Doe <jane@example.com>", "Sam Lee"} {
+		if err := cmd.Flags().Set("co-author", author); err != nil {
+			t.Fatalf("Failed to set flag: %v", err)
+		}
+	}
+
+	if _, _, err := newGenerator(cmd); err == nil {
+		t.Error("Expected a malformed --co-author to be rejected")
+	}
+}
+
+func TestEditorSeesTrailers(t *testing.T) {
+	dir := t.TempDir()
+	captured := filepath.Join(dir, "captured")
+	editor := filepath.Join(dir, "editor")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\ncp \"$1\" "+captured+"\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write editor stub: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Git.Editor = editor
+	message := "feat: pair on export\n\nCo-authored-by: Jane Doe <jane@example.com>\nCo-authored-by: Sam Lee <sam@example.com>"
+
+	edited, err := editMessage(message, cfg)
+	if err != nil {
+		t.Fatalf("editMessage failed: %v", err)
+	}
+	opened, err := os.ReadFile(captured)
+	if err != nil {
+		t.Fatalf("Editor did not run: %v", err)
+	}
+	if string(opened) != message || edited != message {
+		t.Errorf("Trailers lost in the edit loop: opened %q, returned %q", opened, edited)
+	}
+}
+
+// fakeGenerator returns the next message on each call.
+type fakeGenerator struct {
+	messages []string
+	calls    int
+	err      error
+}
+
+func (f *fakeGenerator) GenerateResult() (*generator.GenerateResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	message := f.messages[f.calls%len(f.messages)]
+	f.calls++
+	return &generator.GenerateResult{Message: message}, nil
+}
+
+func (f *fakeGenerator) Generate() (string, error) {
+	result, err := f.GenerateResult()
+	if err != nil {
+		return "", err
+	}
+	return result.Message, nil
+}
+
+func (f *fakeGenerator) SetContext(context.Context) {}
+
+func TestFakeGeneratorFlows(t *testing.T) {
+	backendDown := errors.New("backend down")
+
+	tests := []struct {
+		name     string
+		gen      *fakeGenerator
+		choices  []string // "r" regenerates, "c" cancels, anything else accepts
+		expected string
+		err      error
+	}{
+		{"accept first", &fakeGenerator{messages: []string{"feat: a"}}, []string{"y"}, "feat: a", nil},
+		{"regenerate once", &fakeGenerator{messages: []string{"feat: a", "feat: b"}}, []string{"r", "y"}, "feat: b", nil},
+		{"regenerate twice", &fakeGenerator{messages: []string{"feat: a", "feat: b", "feat: c"}}, []string{"r", "r", "y"}, "feat: c", nil},
+		{"cancel after regenerate", &fakeGenerator{messages: []string{"feat: a", "feat: b"}}, []string{"r", "c"}, "", nil},
+		{"regeneration fails", &fakeGenerator{messages: []string{"feat: a"}}, []string{"r"}, "", backendDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gen generator.ResultGenerator = tt.gen
+			first, err := generateResult(tt.gen)
+			if err != nil {
+				t.Fatalf("generateResult failed: %v", err)
+			}
+			if tt.err != nil {
+				tt.gen.err = tt.err
+			}
+
+			step := 0
+			confirm := func(result *generator.GenerateResult, cfg *config.Config) (string, error) {
+				choice := tt.choices[step]
+				step++
+				switch choice {
+				case "r":
+					return "", errRegenerate
+				case "c":
+					return "", nil
+				}
+				return result.Message, nil
+			}
+
+			message, err := confirmWithRegenerate(gen, first, &config.Config{}, confirm)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if message != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, message)
+			}
+		})
+	}
+}
+
+func TestConfirmRegeneratesOnRequest(t *testing.T) {
+	gen := &fakeGenerator{messages: []string{"feat: second try", "feat: third try"}}
+
+	var shown []string
+	confirm := func(result *generator.GenerateResult, cfg *config.Config) (string, error) {
+		shown = append(shown, result.Message)
+		if len(shown) < 3 {
+			return "", errRegenerate
+		}
+		return result.Message, nil
+	}
+
+	message, err := confirmWithRegenerate(gen, &generator.GenerateResult{Message: "feat: first try"}, &config.Config{}, confirm)
+	if err != nil {
+		t.Fatalf("confirmWithRegenerate failed: %v", err)
+	}
+	if message != "feat: third try" || gen.calls != 2 {
+		t.Errorf("Expected the third message after two regenerations, got %q after %d calls", message, gen.calls)
+	}
+	if strings.Join(shown, "|") != "feat: first try|feat: second try|feat: third try" {
+		t.Errorf("Unexpected messages shown: %q", shown)
+	}
+}
+
+func TestConfirmStopsAfterMaxRegenerations(t *testing.T) {
+	gen := &fakeGenerator{messages: []string{"feat: again"}}
+	confirm := func(*generator.GenerateResult, *config.Config) (string, error) {
+		return "", errRegenerate
+	}
+
+	if _, err := confirmWithRegenerate(gen, &generator.GenerateResult{Message: "feat: x"}, &config.Config{}, confirm); err == nil {
+		t.Fatal("Expected an error after too many regenerations")
+	}
+	if gen.calls != maxRegenerations {
+		t.Errorf("Expected %d regenerations, got %d", maxRegenerations, gen.calls)
+	}
+}
+
+func TestFormatUsage(t *testing.T) {
+	pricing := []config.ModelPricing{{Model: "gpt-4o-mini", Input: 0.15, Output: 0.6}}
+
+	tests := []struct {
+		name     string
+		result   *generator.GenerateResult
+		expected string
+	}{
+		{"unavailable", &generator.GenerateResult{Model: "opencode/gpt-5-nano"}, "Tokens: usage unavailable"},
+		{"cached", &generator.GenerateResult{Cached: true}, "Tokens: none used (message reused from cache)"},
+		{"unpriced", &generator.GenerateResult{Model: "opencode/gpt-5-nano", Usage: &generator.Usage{PromptTokens: 1200, CompletionTokens: 15}}, "Tokens: 1200 in / 15 out"},
+		{"priced", &generator.GenerateResult{Model: "openai/gpt-4o-mini", Usage: &generator.Usage{PromptTokens: 20000, CompletionTokens: 500}}, "Tokens: 20000 in / 500 out (~$0.0033)"},
+	}
+	for _, tt := range tests {
+		if got := formatUsage(tt.result, pricing); got != tt.expected {
+			t.Errorf("%s: got %q, expected %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+// setupOpenAIRepo chdirs into a new repository with main.go staged and
+// points the configuration at a stub chat completions server returning reply.
+// Decorations silenced during the test are restored afterward.
+func setupOpenAIRepo(t *testing.T, reply string) {
+	t.Helper()
+	gittest.StagedRepo(t)
+	stub := openaitest.Config(t, openaitest.Reply(reply))
+
+	cfg := config.Get()
+	saved := *cfg
+	t.Cleanup(func() { *cfg = saved })
+	*cfg = stub
+	cfg.Cache.Location = t.TempDir()
+	cfg.Generation.Confirm = true
+
+	savedOutput, savedNoColor, savedInfo := color.Output, color.NoColor, infoOut
+	t.Cleanup(func() {
+		quiet = false
+		color.Output, color.NoColor, infoOut = savedOutput, savedNoColor, savedInfo
+	})
+}
+
+// captureStdout returns what run writes to os.Stdout.
+func captureStdout(t *testing.T, run func() error) (string, error) {
+	t.Helper()
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	os.Stdout = w
+	runErr := run()
+	os.Stdout = stdout
+	_ = w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out), runErr
+}
+
+func TestQuietGeneratePrintsOnlyMessage(t *testing.T) {
+	setupOpenAIRepo(t, "feat: add quiet mode")
+	quiet = true
+	silenceDecorations()
+
+	out, err := captureStdout(t, func() error { return runGenerate(newFlagTestCommand(), nil) })
+	if err != nil {
+		t.Fatalf("runGenerate failed: %v", err)
+	}
+	if out != "feat: add quiet mode\n" {
+		t.Errorf("Expected only the message on stdout, got %q", out)
+	}
+}
+
+func TestQuietCommitPrintsOnlyMessage(t *testing.T) {
+	setupOpenAIRepo(t, "feat: add quiet commit")
+	quiet = true
+	silenceDecorations()
+
+	out, err := captureStdout(t, func() error { return runCommit(newFlagTestCommand(), nil) })
+	if err != nil {
+		t.Fatalf("runCommit failed: %v", err)
+	}
+	if out != "feat: add quiet commit\n" {
+		t.Errorf("Expected only the message on stdout, got %q", out)
+	}
+
+	subject, err := exec.Command("git", "log", "-1", "--format=%s").Output()
+	if err != nil || strings.TrimSpace(string(subject)) != "feat: add quiet commit" {
+		t.Errorf("Expected the commit without a confirm prompt, got %q (%v)", subject, err)
+	}
+}
+
+func TestQuietCheckConfigWritesToStderr(t *testing.T) {
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	cfg := *config.Get()
+	cfg.Generation.Candidates = 0
+
+	savedOutput, savedNoColor, savedInfo := color.Output, color.NoColor, infoOut
+	t.Cleanup(func() { color.Output, color.NoColor, infoOut = savedOutput, savedNoColor, savedInfo })
+	silenceDecorations()
+
+	stderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	os.Stderr = w
+	out, checkErr := captureStdout(t, func() error { return checkConfig(&cfg) })
+	os.Stderr = stderr
+	_ = w.Close()
+	errOut, _ := io.ReadAll(r)
+
+	if checkErr == nil {
+		t.Fatal("Expected an invalid config to fail")
+	}
+	if out != "" {
+		t.Errorf("Expected nothing on stdout, got %q", out)
+	}
+	if !strings.HasPrefix(string(errOut), "Configuration issues:\n  - ") || !strings.Contains(string(errOut), "candidates") {
+		t.Errorf("Expected the header and problems on stderr, got %q", errOut)
+	}
+}
+
+func TestQuietHealthReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	cfg := config.Get()
+	original := *cfg
+	defer func() { *cfg = original }()
+
+	serverURL, _ := url.Parse(server.URL)
+	cfg.OpenCode.Mode = "server"
+	cfg.OpenCode.Host = serverURL.Hostname()
+	cfg.OpenCode.Port, _ = strconv.Atoi(serverURL.Port())
+	cfg.Generation.Candidates = 0
+
+	savedOutput, savedNoColor, savedInfo := color.Output, color.NoColor, infoOut
+	t.Cleanup(func() { color.Output, color.NoColor, infoOut = savedOutput, savedNoColor, savedInfo })
+	silenceDecorations()
+
+	var stderr bytes.Buffer
+	cmd := &cobra.Command{Use: "health"}
+	cmd.SetErr(&stderr)
+	out, err := captureStdout(t, func() error { return runHealth(cmd, nil) })
+
+	if err == nil || !strings.Contains(err.Error(), "config") || !strings.Contains(err.Error(), "backend") {
+		t.Errorf("Expected config and backend failures in the error, got %v", err)
+	}
+	if out != "" {
+		t.Errorf("Expected nothing on stdout, got %q", out)
+	}
+	for _, want := range []string{"Configuration issues", "candidates", "OpenCode server is not running"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("Expected %q on stderr, got %q", want, stderr.String())
+		}
+	}
+}
+
+func TestHealthProbesOpenAIBackend(t *testing.T) {
+	stub := openaitest.Config(t, func(req openaitest.Request) openaitest.Response {
+		return openaitest.Response{Status: http.StatusUnauthorized, Body: `{"error":{"message":"Incorrect API key provided"}}`}
+	})
+	cfg := config.Get()
+	original := *cfg
+	defer func() { *cfg = original }()
+	*cfg = stub
+
+	savedOutput, savedNoColor, savedInfo := color.Output, color.NoColor, infoOut
+	t.Cleanup(func() { color.Output, color.NoColor, infoOut = savedOutput, savedNoColor, savedInfo })
+	silenceDecorations()
+
+	var stderr bytes.Buffer
+	cmd := &cobra.Command{Use: "health"}
+	cmd.SetErr(&stderr)
+	if err := runHealth(cmd, nil); err == nil || !strings.Contains(err.Error(), "backend") {
+		t.Errorf("Expected a rejected API key to fail the backend check, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "Incorrect API key") {
+		t.Errorf("Expected the API error on stderr, got %q", stderr.String())
+	}
+}
+
+func TestGenerateJSONOutput(t *testing.T) {
+	setupOpenAIRepo(t, "feat: add json output")
+	cmd := newFlagTestCommand()
+	cmd.Flags().String("output", "text", "")
+	if err := cmd.Flags().Set("output", "json"); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+
+	out, err := captureStdout(t, func() error { return runGenerate(cmd, nil) })
+	if err != nil {
+		t.Fatalf("runGenerate failed: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("stdout is not a JSON object: %v\n%s", err, out)
+	}
+	if got["message"] != "feat: add json output" {
+		t.Errorf("message mismatch: %v", got["message"])
+	}
+	if got["style"] != config.Get().Generation.Style || got["model"] != "openai/"+config.Get().Generation.Model.ModelID {
+		t.Errorf("style/model mismatch: %v, %v", got["style"], got["model"])
+	}
+	if got["was_summarized"] != false {
+		t.Errorf("was_summarized mismatch: %v", got["was_summarized"])
+	}
+	if size, _ := got["original_diff_size"].(float64); size <= 0 {
+		t.Errorf("original_diff_size should be positive, got %v", got["original_diff_size"])
+	}
+	if files, _ := got["files_changed"].([]any); len(files) != 1 || files[0] != "main.go" {
+		t.Errorf("files_changed mismatch: %v", got["files_changed"])
+	}
+}
+
+func TestOutputFlagRejectsUnknownFormat(t *testing.T) {
+	cmd := newFlagTestCommand()
+	cmd.Flags().String("output", "text", "")
+	_ = cmd.Flags().Set("output", "yaml")
+	if _, err := wantsJSON(cmd); err == nil || !strings.Contains(err.Error(), "yaml") {
+		t.Errorf("Expected an error for --output yaml, got %v", err)
+	}
+}
+
+func TestSummarizedNotice(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Git.MaxDiffSize = 32768
+	summarized := &generator.GenerateResult{IsSummarized: true, OriginalDiffSize: 81920, Files: []string{"a.go", "b.go"}}
+	whole := &generator.GenerateResult{OriginalDiffSize: 2048, Files: []string{"a.go"}}
+
+	tests := []struct {
+		name     string
+		mode     string
+		result   *generator.GenerateResult
+		expected string
+	}{
+		{"warn summarized", "warn", summarized, "Diff was 80KB; sent a summary (git.max_diff_size is 32KB)"},
+		{"warn whole", "warn", whole, ""},
+		{"silent", "silent", summarized, ""},
+		{"split summarized", "split", summarized, "Diff was 80KB; sent a summary (git.max_diff_size is 32KB)\nToo many files (2) to describe one by one; consider committing them in smaller groups, e.g. with 'commit-gen generate --pick'"},
+		{"split whole", "split", whole, ""},
+	}
+	for _, tt := range tests {
+		cfg.Generation.OnSummarized = tt.mode
+		if got := summarizedNotice(tt.result, cfg); got != tt.expected {
+			t.Errorf("%s: got %q, expected %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestSkipBackendCheck(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	cfg := *config.Get()
+	cfg.OpenCode.Mode = "run"
+	cfg.OpenCode.Binary = filepath.Join(t.TempDir(), "missing-opencode")
+
+	if err := checkBackendAvailability(&cfg, false); err == nil {
+		t.Fatal("Expected a missing binary to fail the check")
+	}
+
+	t.Cleanup(func() { skipBackendCheck = false })
+	if err := rootCmd.PersistentFlags().Parse([]string{"--skip-backend-check"}); err != nil {
+		t.Fatalf("Failed to parse flag: %v", err)
+	}
+	if err := checkBackendAvailability(&cfg, false); err != nil {
+		t.Errorf("--skip-backend-check should skip the check, got %v", err)
+	}
+
+	skipBackendCheck = false
+	cfg.Generation.SkipBackendCheck = true
+	if err := checkBackendAvailability(&cfg, false); err != nil {
+		t.Errorf("generation.skip_backend_check should skip the check, got %v", err)
+	}
+}
+
+func TestCacheClearDeletesServerSessions(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	cfg := config.Get()
+	original := *cfg
+	defer func() { *cfg = original }()
+
+	serverURL, _ := url.Parse(server.URL)
+	cfg.OpenCode.Mode = "server"
+	cfg.OpenCode.Host = serverURL.Hostname()
+	cfg.OpenCode.Port, _ = strconv.Atoi(serverURL.Port())
+	cfg.Cache.Location = t.TempDir()
+
+	sessionCache, err := cache.FromConfig(cfg)
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+
+	for _, localOnly := range []bool{true, false} {
+		deleted = nil
+		if err := sessionCache.Set("session-abc"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+
+		cmd := &cobra.Command{Use: "clear"}
+		cmd.Flags().Bool("local-only", localOnly, "")
+		if err := runCacheClear(cmd, nil); err != nil {
+			t.Fatalf("runCacheClear failed: %v", err)
+		}
+
+		expected := []string{"/session/session-abc"}
+		if localOnly {
+			expected = nil
+		}
+		if strings.Join(deleted, ",") != strings.Join(expected, ",") {
+			t.Errorf("local-only=%v: expected deletes %v, got %v", localOnly, expected, deleted)
+		}
+		if len(sessionCache.Sessions()) != 0 {
+			t.Errorf("local-only=%v: expected the local cache to be cleared", localOnly)
+		}
+	}
+}
+
+func TestRunnerCheckHonorsCacheLocation(t *testing.T) {
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	cfg := config.Get()
+	original := *cfg
+	defer func() { *cfg = original }()
+
+	binary := filepath.Join(t.TempDir(), "opencode")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+	cfg.OpenCode.Binary = binary
+	cfg.Cache.Location = t.TempDir()
+
+	if err := checkOpenCodeRunner(cfg); err != nil {
+		t.Fatalf("checkOpenCodeRunner failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Cache.Location, "availability.json")); err != nil {
+		t.Errorf("Expected availability.json under cache.location: %v", err)
+	}
+}
+
+func TestReadProvidedDiff(t *testing.T) {
+	const diff = "diff --git a/a.go b/a.go\n+package a\n"
+	diffPath := filepath.Join(t.TempDir(), "change.diff")
+	if err := os.WriteFile(diffPath, []byte(diff), 0o644); err != nil {
+		t.Fatalf("Failed to write diff: %v", err)
+	}
+
+	newCommand := func(flags map[string]string) *cobra.Command {
+		cmd := newFlagTestCommand()
+		cmd.Flags().String("diff-file", "", "")
+		cmd.Flags().Bool("diff-stdin", false, "")
+		cmd.Flags().BoolP("all", "a", false, "")
+		cmd.SetIn(strings.NewReader(diff))
+		for name, value := range flags {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				t.Fatalf("Failed to set --%s: %v", name, err)
+			}
+		}
+		return cmd
+	}
+
+	for _, flags := range []map[string]string{{"diff-file": diffPath}, {"diff-stdin": "true"}} {
+		got, provided, err := readProvidedDiff(newCommand(flags))
+		if err != nil || !provided || got != diff {
+			t.Errorf("%v: expected the canned diff, got %q, %v, %v", flags, got, provided, err)
+		}
+	}
+
+	if _, provided, err := readProvidedDiff(newCommand(nil)); err != nil || provided {
+		t.Errorf("Expected no provided diff without the flags, got %v, %v", provided, err)
+	}
+
+	for _, flags := range []map[string]string{
+		{"diff-file": diffPath, "diff-stdin": "true"},
+		{"diff-file": diffPath, "all": "true"},
+		{"diff-stdin": "true", "all": "true"},
+	} {
+		if _, _, err := readProvidedDiff(newCommand(flags)); err == nil {
+			t.Errorf("%v: expected an error for conflicting diff sources", flags)
+		}
+	}
+}
+
+func TestLintCommand(t *testing.T) {
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	savedOutput, savedNoColor, savedInfo := color.Output, color.NoColor, infoOut
+	t.Cleanup(func() {
+		color.Output, color.NoColor, infoOut = savedOutput, savedNoColor, savedInfo
+		lintCmd.SetIn(nil)
+		lintCmd.SetErr(nil)
+	})
+	silenceDecorations()
+
+	valid := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	if err := os.WriteFile(valid, []byte("feat(lint): check messages\n# Please enter the commit message\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	if err := runLint(lintCmd, []string{valid}); err != nil {
+		t.Errorf("Expected a valid message to pass, got %v", err)
+	}
+
+	var stderr bytes.Buffer
+	lintCmd.SetErr(&stderr)
+	lintCmd.SetIn(strings.NewReader("Added stuff\nwithout a blank line\n"))
+	if err := runLint(lintCmd, []string{"-"}); err == nil {
+		t.Fatal("Expected an invalid message from stdin to fail")
+	}
+	if !strings.Contains(stderr.String(), "does not match") || !strings.Contains(stderr.String(), "line 2 must be blank") {
+		t.Errorf("Expected each violation listed, got %q", stderr.String())
+	}
+}
+
+func TestInstallReportsHookUpgrade(t *testing.T) {
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "-C", repo, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("Failed to chdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	var out bytes.Buffer
+	savedOutput, savedNoColor := color.Output, color.NoColor
+	t.Cleanup(func() { color.Output, color.NoColor = savedOutput, savedNoColor })
+	color.Output, color.NoColor = &out, true
+
+	old := "#!/bin/sh\n# commit-gen-managed-hook v0\nexit 0\n"
+	if err := os.WriteFile(filepath.Join(repo, ".git", "hooks", "prepare-commit-msg"), []byte(old), 0o755); err != nil {
+		t.Fatalf("Failed to write old hook: %v", err)
+	}
+
+	if err := runInstall(installCmd, nil); err != nil {
+		t.Fatalf("runInstall failed: %v", err)
+	}
+	if want := fmt.Sprintf("Upgraded hook from v0 to v%d", hook.CurrentVersion()); !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	if err := runHookStatus(hookStatusCmd, nil); err != nil {
+		t.Fatalf("runHookStatus failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "prepare-commit-msg: installed") || !strings.Contains(out.String(), "up to date") {
+		t.Errorf("Expected the upgraded hook reported as up to date, got %q", out.String())
+	}
+}
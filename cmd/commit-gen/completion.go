@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Prints a completion script for the given shell to stdout.
+
+Bash:
+  source <(commit-gen completion bash)
+  # or install it: commit-gen completion bash > /etc/bash_completion.d/commit-gen
+
+Zsh:
+  commit-gen completion zsh > "${fpath[1]}/_commit-gen"
+
+Fish:
+  commit-gen completion fish > ~/.config/fish/completions/commit-gen.fish
+
+PowerShell:
+  commit-gen completion powershell | Out-String | Invoke-Expression`,
+	DisableFlagsInUseLine: true,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE:                  runCompletion,
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	root := cmd.Root()
+
+	switch args[0] {
+	case "bash":
+		return root.GenBashCompletionV2(out, true)
+	case "zsh":
+		return root.GenZshCompletion(out)
+	case "fish":
+		return root.GenFishCompletion(out, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(out)
+	}
+	return fmt.Errorf("unsupported shell %q", args[0])
+}
+
+/**
+ * completeStyles suggests the built-in styles followed by the custom styles
+ * defined in generation.custom_styles.
+ */
+func completeStyles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	styles := append([]string{}, config.BuiltinStyles...)
+	if cfg := config.Get(); cfg != nil {
+		var custom []string
+		for name := range cfg.Generation.CustomStyles {
+			custom = append(custom, name)
+		}
+		sort.Strings(custom)
+		styles = append(styles, custom...)
+	}
+	return styles, cobra.ShellCompDirectiveNoFileComp
+}
+
+// fixedCompletion suggests a fixed set of values and no file names.
+func fixedCompletion(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+/**
+ * registerFlagCompletions adds value completions for the --style, --mode,
+ * and --output flags of every command that defines them. It must run after
+ * the flags are declared.
+ *
+ * @param commands - The commands whose flags get completions
+ */
+func registerFlagCompletions(commands ...*cobra.Command) {
+	completions := map[string]func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective){
+		"style":  completeStyles,
+		"mode":   fixedCompletion("run", "server", "openai"),
+		"output": fixedCompletion("text", "json"),
+	}
+
+	for _, cmd := range commands {
+		for name, complete := range completions {
+			if cmd.Flags().Lookup(name) == nil {
+				continue
+			}
+			_ = cmd.RegisterFlagCompletionFunc(name, complete)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/avgt93/commit-gen/internal/config"
+)
+
+// executeRoot runs the root command with args and returns its stdout.
+func executeRoot(t *testing.T, args ...string) string {
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs(args)
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%s failed: %v", strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+func TestCompletionCommand(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		t.Run(shell, func(t *testing.T) {
+			out := executeRoot(t, "completion", shell)
+			if strings.TrimSpace(out) == "" || !strings.Contains(out, "commit-gen") {
+				t.Errorf("Expected a %s completion script, got %q", shell, out)
+			}
+		})
+	}
+}
+
+func TestFlagCompletions(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"generate", "--style", ""}, config.BuiltinStyles},
+		{[]string{"preview", "--mode", ""}, []string{"run", "server", "openai"}},
+		{[]string{"commit", "--mode", ""}, []string{"run", "server", "openai"}},
+		{[]string{"generate", "--output", ""}, []string{"text", "json"}},
+	}
+
+	for _, tt := range tests {
+		out := executeRoot(t, append([]string{"__complete"}, tt.args...)...)
+		for _, value := range tt.expected {
+			if !strings.Contains(out, value+"\n") {
+				t.Errorf("%v: expected %q among completions, got %q", tt.args, value, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/avgt93/commit-gen/internal/git"
+	"github.com/avgt93/commit-gen/internal/hook"
+	"github.com/avgt93/commit-gen/internal/opencode"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the commit-gen setup",
+	Long: `Checks git, the current repository, the configuration, the OpenCode
+backend, and the commit hook. Exits non-zero when a critical check fails.
+Use --json for machine-readable output.`,
+	RunE: runDoctor,
+}
+
+// doctorCheck is the result of one diagnostic check.
+type doctorCheck struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Critical bool   `json:"critical"`
+	Detail   string `json:"detail"`
+}
+
+// doctorReport is the overall doctor result; OK is false if any critical check failed.
+type doctorReport struct {
+	OK     bool          `json:"ok"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+// newDoctorReport computes the overall status for checks.
+func newDoctorReport(checks []doctorCheck) doctorReport {
+	report := doctorReport{OK: true, Checks: checks}
+	for _, c := range checks {
+		if c.Critical && !c.OK {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+// runDoctorChecks runs every diagnostic check; both output formats use these results.
+func runDoctorChecks(cfg *config.Config) []doctorCheck {
+	var checks []doctorCheck
+
+	gitCheck := doctorCheck{Name: "git", Critical: true}
+	if path, err := exec.LookPath("git"); err != nil {
+		gitCheck.Detail = "git not found in PATH"
+	} else {
+		gitCheck.OK = true
+		gitCheck.Detail = path
+	}
+	checks = append(checks, gitCheck)
+
+	repoCheck := doctorCheck{Name: "repository", Critical: true}
+	if root, err := git.GetRepositoryRoot(); err != nil {
+		repoCheck.Detail = "not inside a git repository"
+	} else {
+		repoCheck.OK = true
+		repoCheck.Detail = root
+	}
+	checks = append(checks, repoCheck)
+
+	configCheck := doctorCheck{Name: "config", OK: true}
+	if path := config.FileUsed(); path != "" {
+		configCheck.Detail = path
+	} else {
+		configCheck.Detail = "no config file, using defaults"
+	}
+	checks = append(checks, configCheck)
+
+	backendCheck := doctorCheck{Name: "backend", Critical: true}
+	if cfg.OpenCode.Mode == "openai" {
+		if err := probeOpenAI(cfg); err != nil {
+			backendCheck.Detail = fmt.Sprintf("OpenAI-compatible API at %s not usable: %v", cfg.OpenAI.BaseURL, err)
+		} else {
+			backendCheck.OK = true
+			backendCheck.Detail = fmt.Sprintf("OpenAI-compatible API at %s (openai mode)", cfg.OpenAI.BaseURL)
+		}
+	} else if cfg.OpenCode.Mode == "server" {
+		client := newServerClient(cfg)
+		healthy, err := client.CheckHealth()
+		backendCheck.OK = err == nil && healthy
+		if backendCheck.OK {
+			backendCheck.Detail = fmt.Sprintf("server running at %s:%d", cfg.OpenCode.Host, cfg.OpenCode.Port)
+		} else {
+			backendCheck.Detail = fmt.Sprintf("server not reachable at %s:%d", cfg.OpenCode.Host, cfg.OpenCode.Port)
+		}
+	} else {
+		binary := opencode.ResolveBinary(cfg.OpenCode.Binary)
+		available, err := opencode.NewRunnerWithBinary(cfg.OpenCode.Binary, cfg.OpenCode.Timeout).CheckAvailable()
+		backendCheck.OK = err == nil && available
+		if backendCheck.OK {
+			backendCheck.Detail = binary + " available (run mode)"
+		} else {
+			backendCheck.Detail = binary + " not found in PATH"
+		}
+	}
+	checks = append(checks, backendCheck)
+
+	hookCheck := doctorCheck{Name: "hook"}
+	if repoCheck.OK {
+		installed, err := hook.IsInstalled()
+		hookCheck.OK = err == nil && installed
+		if hookCheck.OK {
+			hookCheck.Detail = "prepare-commit-msg hook installed"
+		} else {
+			hookCheck.Detail = "hook not installed; run 'commit-gen install'"
+		}
+	} else {
+		hookCheck.Detail = "skipped outside a git repository"
+	}
+	checks = append(checks, hookCheck)
+
+	return checks
+}
+
+// runDoctor prints the diagnostic checks and fails if a critical one did.
+func runDoctor(cmd *cobra.Command, args []string) error {
+	report := newDoctorReport(runDoctorChecks(config.Get()))
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, c := range report.Checks {
+			switch {
+			case c.OK:
+				color.Green("✓ %s: %s", c.Name, c.Detail)
+			case c.Critical:
+				color.Red("✗ %s: %s", c.Name, c.Detail)
+			default:
+				color.Yellow("! %s: %s", c.Name, c.Detail)
+			}
+		}
+	}
+
+	if !report.OK {
+		var failed []string
+		for _, c := range report.Checks {
+			if c.Critical && !c.OK {
+				failed = append(failed, c.Name)
+			}
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("critical checks failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/avgt93/commit-gen/internal/llm/openai/openaitest"
+)
+
+func TestDoctorReportJSONSchema(t *testing.T) {
+	report := newDoctorReport([]doctorCheck{
+		{Name: "git", OK: true, Critical: true, Detail: "/usr/bin/git"},
+		{Name: "backend", OK: false, Critical: true, Detail: "opencode not found in PATH"},
+	})
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if decoded["ok"] != false {
+		t.Errorf("Expected overall ok=false with a failing critical check, got %v", decoded["ok"])
+	}
+
+	checks, ok := decoded["checks"].([]any)
+	if !ok || len(checks) != 2 {
+		t.Fatalf("Expected 2 checks, got %v", decoded["checks"])
+	}
+
+	for i, expectedOK := range []bool{true, false} {
+		check, ok := checks[i].(map[string]any)
+		if !ok {
+			t.Fatalf("Check %d is not an object: %v", i, checks[i])
+		}
+		for _, key := range []string{"name", "ok", "critical", "detail"} {
+			if _, exists := check[key]; !exists {
+				t.Errorf("Check %d missing key %q", i, key)
+			}
+		}
+		if check["ok"] != expectedOK {
+			t.Errorf("Check %d: expected ok=%v, got %v", i, expectedOK, check["ok"])
+		}
+	}
+
+	t.Log("✓ Doctor JSON has overall status and per-check fields")
+}
+
+func TestDoctorReportIgnoresNonCriticalFailures(t *testing.T) {
+	report := newDoctorReport([]doctorCheck{
+		{Name: "git", OK: true, Critical: true},
+		{Name: "hook", OK: false},
+	})
+
+	if !report.OK {
+		t.Error("A failing non-critical check should not fail the report")
+	}
+}
+
+func TestDoctorProbesOpenAIBackend(t *testing.T) {
+	backendCheck := func(cfg config.Config) doctorCheck {
+		t.Helper()
+		for _, c := range runDoctorChecks(&cfg) {
+			if c.Name == "backend" {
+				return c
+			}
+		}
+		t.Fatal("No backend check reported")
+		return doctorCheck{}
+	}
+
+	ok := backendCheck(openaitest.Config(t, openaitest.Reply("")))
+	if !ok.OK {
+		t.Errorf("Expected a reachable API to pass, got %q", ok.Detail)
+	}
+
+	rejected := backendCheck(openaitest.Config(t, func(req openaitest.Request) openaitest.Response {
+		return openaitest.Response{Status: http.StatusUnauthorized, Body: `{"error":{"message":"Incorrect API key provided"}}`}
+	}))
+	if rejected.OK || !strings.Contains(rejected.Detail, "Incorrect API key") {
+		t.Errorf("Expected a rejected key to fail the check, got ok=%v %q", rejected.OK, rejected.Detail)
+	}
+
+	cfg := openaitest.Config(t, openaitest.Reply(""))
+	cfg.OpenAI.BaseURL = "http://127.0.0.1:1"
+	if unreachable := backendCheck(cfg); unreachable.OK {
+		t.Errorf("Expected an unreachable base_url to fail the check, got %q", unreachable.Detail)
+	}
+}
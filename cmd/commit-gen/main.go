@@ -1,23 +1,32 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
-	"strconv"
 	"time"
 
+	"github.com/avgt93/commit-gen/internal/cache"
 	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/avgt93/commit-gen/internal/git"
+	"github.com/avgt93/commit-gen/internal/llm/openai"
 	"github.com/avgt93/commit-gen/internal/opencode"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
 var (
-	version = "dev"
-	cfgFile string
+	version          = "dev"
+	cfgFile          string
+	quiet            bool
+	skipBackendCheck bool
 )
 
+// infoOut receives informational output such as status details, which --quiet discards.
+var infoOut io.Writer = os.Stdout
+
 var rootCmd = &cobra.Command{
 	Use:   "commit-gen",
 	Short: "Generate commit messages using OpenCode AI",
@@ -36,6 +45,8 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/commit-gen/config.yaml)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print only the final message; errors still go to stderr")
+	rootCmd.PersistentFlags().BoolVar(&skipBackendCheck, "skip-backend-check", false, "Skip the backend availability check before generating; overrides generation.skip_backend_check")
 
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(installCmd)
@@ -45,28 +56,140 @@ func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(healthCmd)
+	rootCmd.AddCommand(releaseNotesCmd)
+	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(commitCmd)
+	rootCmd.AddCommand(tokensCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(modelsCmd)
+	rootCmd.AddCommand(lintCmd)
+
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configGetCmd)
 
 	cacheCmd.AddCommand(cacheStatusCmd)
 	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
 	rootCmd.AddCommand(cacheCmd)
 
-	generateCmd.Flags().StringP("style", "s", "conventional", "Commit message style (conventional, imperative, detailed)")
-	generateCmd.Flags().StringP("mode", "m", "", "Operation mode: 'run' (default) or 'server'")
+	hookCmd.AddCommand(hookStatusCmd)
+	rootCmd.AddCommand(hookCmd)
+
+	serverCmd.AddCommand(serverStopCmd)
+	rootCmd.AddCommand(serverCmd)
+
+	generateCmd.Flags().StringP("style", "s", "", "Commit message style (conventional, imperative, detailed, gitmoji, or a custom style); overrides generation.style")
+	generateCmd.Flags().StringP("mode", "m", "", "Operation mode: 'run' (default), 'server', or 'openai'")
 	generateCmd.Flags().BoolP("no-confirm", "n", false, "Skip confirmation prompt and use generated message directly")
 	generateCmd.Flags().Bool("dry-run", false, "Show message without writing to git")
 	generateCmd.Flags().Bool("hook", false, "Internal flag for git hook usage")
 	generateCmd.Flags().Bool("ignore-server-check", false, "Skip checking if OpenCode backend is available")
+	generateCmd.Flags().Bool("full", false, "Keep the full model output (subject, body, footers) instead of the first line")
+	generateCmd.Flags().Bool("force", false, "Proceed even if the prompt exceeds generation.max_cost_tokens, and regenerate even if the diff is unchanged")
+	generateCmd.Flags().BoolP("all", "a", false, "Include unstaged changes to tracked files")
+	generateCmd.Flags().String("diff-file", "", "Describe the unified diff in this file instead of the staged changes")
+	generateCmd.Flags().Bool("diff-stdin", false, "Describe a unified diff read from stdin instead of the staged changes (skips the confirmation prompt)")
+	generateCmd.Flags().Bool("relative", false, "Show diff paths relative to the current directory")
+	generateCmd.Flags().Int("context-lines", 3, "Lines of context around each change sent to the model; overrides git.context_lines")
+	generateCmd.Flags().Bool("amend", false, "Generate a message for amending the last commit")
+	generateCmd.Flags().Bool("reuse-body", false, "With --amend, regenerate only the subject and keep the previous body")
+	generateCmd.Flags().BoolP("verbose", "v", false, "Report output filtered as noise and stripped markdown")
+	generateCmd.Flags().Bool("body", false, "Include a body explaining the change, overriding generation.body")
+	generateCmd.Flags().Bool("no-body", false, "Generate only the subject line, overriding generation.body")
+	generateCmd.MarkFlagsMutuallyExclusive("body", "no-body")
+	generateCmd.Flags().Bool("pick", false, "Interactively choose which staged files to describe (requires a terminal)")
+	generateCmd.Flags().StringSlice("paths", nil, "Describe only these staged paths")
+	generateCmd.Flags().Bool("unstage-rest", false, "With --pick or --paths, unstage the files that were not selected")
+	generateCmd.Flags().String("record", "", "Record OpenCode server requests and responses to a file (server mode only)")
+	generateCmd.Flags().Bool("commit", false, "Run git commit with the message once it is accepted")
+	generateCmd.Flags().BoolP("signoff", "S", false, "Append a Signed-off-by trailer from git user.name/user.email; overrides generation.signoff")
+	generateCmd.Flags().Int("candidates", 1, "Generate this many alternative messages to choose from (1-9); overrides generation.candidates")
+	generateCmd.Flags().StringArray("co-author", nil, "Append a Co-authored-by trailer for \"Name <email>\" (repeatable); adds to generation.co_authors")
+	generateCmd.Flags().Bool("show-usage", false, "Print the token usage reported by the backend, with a cost estimate from generation.pricing; overrides generation.show_usage")
+	generateCmd.Flags().String("output", "text", "Output format: 'text' or 'json' (a JSON object with the message and generation details)")
+
+	cacheClearCmd.Flags().Bool("local-only", false, "Clear only the local cache; in server mode, leave the sessions on the server")
+
+	configCmd.Flags().Bool("explain", false, "Show each effective setting and whether it comes from defaults, the config file, or the environment")
+
+	initCmd.Flags().Bool("print", false, "Print the default configuration to stdout without writing a file")
+
+	doctorCmd.Flags().Bool("json", false, "Print the check results as JSON")
+
+	tokensCmd.Flags().Bool("relative", false, "Show diff paths relative to the current directory")
+	tokensCmd.Flags().Bool("full", false, "Estimate for full-message generation")
 
-	previewCmd.Flags().StringP("mode", "m", "", "Operation mode: 'run' (default) or 'server'")
+	commitCmd.Flags().StringP("mode", "m", "", "Operation mode: 'run' (default), 'server', or 'openai'")
+	commitCmd.Flags().BoolP("no-confirm", "n", false, "Skip confirmation prompt and commit with the generated message")
+	commitCmd.Flags().Int("candidates", 1, "Generate this many alternative messages to choose from (1-9); overrides generation.candidates")
+	commitCmd.Flags().Bool("show-usage", false, "Print the token usage reported by the backend, with a cost estimate from generation.pricing; overrides generation.show_usage")
+	commitCmd.Flags().Bool("ignore-server-check", false, "Skip checking if OpenCode backend is available")
+	commitCmd.Flags().String("author", "", "Override the commit author (\"Name <email>\")")
+	commitCmd.Flags().String("date", "", "Override the author date (RFC 2822 or ISO 8601)")
+	commitCmd.Flags().Bool("committer-date", false, "With --date, also set the committer date")
+
+	testCmd.Flags().StringP("mode", "m", "", "Operation mode: 'run' (default), 'server', or 'openai'")
+
+	modelsCmd.Flags().StringP("mode", "m", "", "Operation mode: 'run' (default) or 'server'")
+
+	lintCmd.Flags().StringP("style", "s", "", "Commit message style to check against; overrides generation.style")
+
+	releaseNotesCmd.Flags().String("since", "", "Tag or revision to collect commits from (exclusive)")
+
+	previewCmd.Flags().StringP("style", "s", "", "Commit message style (conventional, imperative, detailed, gitmoji, or a custom style); overrides generation.style")
+	previewCmd.Flags().StringP("mode", "m", "", "Operation mode: 'run' (default), 'server', or 'openai'")
 	previewCmd.Flags().Bool("ignore-server-check", false, "Skip checking if OpenCode backend is available")
+	previewCmd.Flags().Bool("full", false, "Keep the full model output (subject, body, footers) instead of the first line")
+	previewCmd.Flags().Bool("force", false, "Proceed even if the prompt exceeds generation.max_cost_tokens")
+	previewCmd.Flags().BoolP("all", "a", false, "Include unstaged changes to tracked files")
+	previewCmd.Flags().String("diff-file", "", "Describe the unified diff in this file instead of the staged changes")
+	previewCmd.Flags().Bool("diff-stdin", false, "Describe a unified diff read from stdin instead of the staged changes")
+	previewCmd.Flags().Bool("relative", false, "Show diff paths relative to the current directory")
+	previewCmd.Flags().Int("context-lines", 3, "Lines of context around each change sent to the model; overrides git.context_lines")
+	previewCmd.Flags().Bool("pretty", false, "Group the diff by file with colored headers and line counts")
+	previewCmd.Flags().BoolP("verbose", "v", false, "Report output filtered as noise and stripped markdown")
+	previewCmd.Flags().Bool("stream", false, "Print the message as it is generated (server mode only)")
+	previewCmd.Flags().String("record", "", "Record OpenCode server requests and responses to a file (server mode only)")
+	previewCmd.Flags().Bool("show-usage", false, "Print the token usage reported by the backend, with a cost estimate from generation.pricing; overrides generation.show_usage")
+	previewCmd.Flags().String("output", "text", "Output format: 'text' or 'json' (a JSON object with the message and generation details)")
+
+	installCmd.Flags().Bool("chain", false, "Keep an existing prepare-commit-msg hook and run it before commit-gen")
+	installCmd.Flags().Bool("lint", false, "Also install a commit-msg hook that rejects messages failing 'commit-gen lint'")
+	installCmd.Flags().Bool("global", false, "Install into the git template directory for repositories created or cloned afterward")
+	uninstallCmd.Flags().Bool("global", false, "Remove the hook from the git template directory and unset init.templateDir")
+
+	registerFlagCompletions(generateCmd, previewCmd, commitCmd, testCmd, modelsCmd, lintCmd)
 }
 
 func initConfig() {
 	_ = config.Initialize(cfgFile)
+	git.SetExcludePaths(config.Get().Git.ExcludePaths)
+	if quiet {
+		silenceDecorations()
+	}
 }
 
+// silenceDecorations disables colors and discards colored and informational
+// output, leaving stdout to the final message.
+func silenceDecorations() {
+	color.NoColor = true
+	color.Output = io.Discard
+	infoOut = io.Discard
+}
+
+/**
+ * checkBackendAvailability verifies the configured backend can be reached
+ * before generating. The check is skipped with --ignore-server-check,
+ * --skip-backend-check, or generation.skip_backend_check; an unreachable
+ * backend then fails the generation request itself.
+ *
+ * @param cfg - The configuration with the resolved mode
+ * @param ignoreCheck - Whether the command's --ignore-server-check flag is set
+ * @returns An error if the backend is unavailable
+ */
 func checkBackendAvailability(cfg *config.Config, ignoreCheck bool) error {
-	if ignoreCheck {
+	if ignoreCheck || skipBackendCheck || cfg.Generation.SkipBackendCheck {
 		return nil
 	}
 
@@ -75,45 +198,80 @@ func checkBackendAvailability(cfg *config.Config, ignoreCheck bool) error {
 		mode = "run"
 	}
 
-	if mode == "server" {
+	switch mode {
+	case "server":
 		return checkOpenCodeHealth(cfg)
+	case "openai":
+		return nil
 	}
-	return checkOpenCodeRunner()
+	return checkOpenCodeRunner(cfg)
 }
 
-func checkOpenCodeRunner() error {
-	runner := opencode.NewRunner(10)
+func checkOpenCodeRunner(cfg *config.Config) error {
+	opencode.SetAvailabilityCacheDir(serverStateDir(cfg))
+	runner := opencode.NewRunnerWithBinary(cfg.OpenCode.Binary, 10)
 	available, err := runner.CheckAvailable()
 	if err != nil || !available {
-		return fmt.Errorf("opencode binary not found in PATH. Please install opencode first")
+		return fmt.Errorf("%s binary not found in PATH. Please install opencode first or set opencode.binary", opencode.ResolveBinary(cfg.OpenCode.Binary))
 	}
 	return nil
 }
 
+// newServerClient creates an OpenCode server client with the configured API key.
+func newServerClient(cfg *config.Config) *opencode.Client {
+	baseURL := opencode.BaseURL(cfg.OpenCode.Scheme, cfg.OpenCode.Host, cfg.OpenCode.Port, cfg.OpenCode.BasePath)
+	client := opencode.NewClientWithBaseURL(baseURL, cfg.OpenCode.Timeout)
+	client.SetAPIKey(cfg.OpenCode.APIKey)
+	return client
+}
+
+// probeOpenAI lists the OpenAI-compatible API's models with the configured
+// key, so a wrong base_url or a rejected key is reported before generating.
+func probeOpenAI(cfg *config.Config) error {
+	client := openai.NewClient(cfg.OpenAI.BaseURL, cfg.OpenAI.APIKey, cfg.OpenCode.Timeout)
+	return client.Ping(context.Background())
+}
+
+/**
+ * checkOpenCodeHealth verifies the OpenCode server is healthy. If it is not,
+ * it waits for a server started by an earlier invocation that is still
+ * running, or else starts `opencode serve`, polling for up to
+ * opencode.startup_timeout seconds. Started servers log and record their PID
+ * in the cache directory.
+ *
+ * @param cfg - The configuration with the server address
+ * @returns An error if the server cannot be reached or started
+ */
 func checkOpenCodeHealth(cfg *config.Config) error {
-	client := opencode.NewClient(
-		cfg.OpenCode.Host,
-		cfg.OpenCode.Port,
-		cfg.OpenCode.Timeout,
-	)
+	client := newServerClient(cfg)
 
 	healthy, err := client.CheckHealth()
 	if err == nil && healthy {
 		return nil
 	}
 
-	cmd := exec.Command(
-		"opencode",
-		"serve",
-		"--port", strconv.Itoa(cfg.OpenCode.Port),
-	)
-
-	setSysProcAttr(cmd)
+	timeout := time.Duration(cfg.OpenCode.StartupTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultStartupTimeout
+	}
+	// Bound each probe so a server that accepts connections but hangs cannot
+	// outlast the startup timeout.
+	check := func() (bool, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		return client.CheckHealthCtx(ctx)
+	}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	dir := serverStateDir(cfg)
+	if process, pid, running := opencode.RunningServer(dir); running {
+		if err := process.WaitHealthy(check, timeout); err != nil {
+			return fmt.Errorf("%w; the server started earlier (pid %d) is not answering, stop it with 'commit-gen server stop'", err, pid)
+		}
+		return nil
+	}
 
-	if err := cmd.Start(); err != nil {
+	process, err := opencode.StartServer(cfg.OpenCode.Binary, cfg.OpenCode.Port, dir)
+	if err != nil {
 		ErrServerNotRunning := errors.New("opencode server is not running")
 		return fmt.Errorf(
 			"%w at %s:%d: %v",
@@ -123,21 +281,20 @@ func checkOpenCodeHealth(cfg *config.Config) error {
 			err,
 		)
 	}
+	return process.WaitHealthy(check, timeout)
+}
 
-	go func() {
-		if err := cmd.Wait(); err != nil {
-			fmt.Printf("opencode server exited: %v\n", err)
-		}
-	}()
-
-	time.Sleep(2 * time.Second)
+// defaultStartupTimeout applies when opencode.startup_timeout is 0.
+const defaultStartupTimeout = 15 * time.Second
 
-	healthy, err = client.CheckHealth()
-	if err != nil || !healthy {
-		return fmt.Errorf("opencode server failed to become healthy")
+// serverStateDir returns the cache directory, where an auto-started server
+// keeps its log and PID file.
+func serverStateDir(cfg *config.Config) string {
+	sessionCache, err := cache.FromConfig(cfg)
+	if err != nil {
+		return cache.DefaultDir()
 	}
-
-	return nil
+	return sessionCache.Dir()
 }
 
 func main() {
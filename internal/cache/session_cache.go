@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/avgt93/commit-gen/internal/config"
 	"github.com/avgt93/commit-gen/internal/git"
 )
 
@@ -28,25 +31,110 @@ type SessionCache struct {
 }
 
 var (
-	instance *SessionCache
-	once     sync.Once
+	instances   = make(map[string]*SessionCache)
+	instancesMu sync.Mutex
 )
 
+/**
+ * GetCache returns the cache stored in cachedir, loading it on first use.
+ * Callers asking for the same directory share one instance; the latest ttl
+ * wins.
+ *
+ * @param ttl - How long a session stays valid
+ * @param cachedir - The directory holding the cache files
+ * @returns The cache for cachedir
+ */
 func GetCache(ttl time.Duration, cachedir string) *SessionCache {
-	once.Do(func() {
-		instance = &SessionCache{
-			cache:    make(map[string]*CachedSession),
-			ttl:      ttl,
-			cachedir: cachedir,
-		}
-		err := instance.load()
-		if err != nil {
-			fmt.Printf("Warning: failed to load session cache: %v\n", err)
-		}
-	})
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+
+	key := filepath.Clean(cachedir)
+	if instance, ok := instances[key]; ok {
+		instance.mu.Lock()
+		instance.ttl = ttl
+		instance.mu.Unlock()
+		return instance
+	}
+
+	instance := &SessionCache{
+		cache:    make(map[string]*CachedSession),
+		ttl:      ttl,
+		cachedir: cachedir,
+	}
+	if err := instance.load(); err != nil {
+		fmt.Printf("Warning: failed to load session cache: %v\n", err)
+	}
+	instances[key] = instance
 	return instance
 }
 
+// DefaultTTL is how long sessions stay valid when cache.ttl is not set.
+const DefaultTTL = 24 * time.Hour
+
+/**
+ * DefaultDir returns the cache directory used when cache.location is not set.
+ *
+ * @returns $HOME/.cache/commit-gen
+ */
+func DefaultDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".cache", "commit-gen")
+}
+
+/**
+ * ParseTTL parses a cache.ttl value such as "24h" or "90m".
+ *
+ * @param value - The configured TTL; empty uses DefaultTTL
+ * @returns The TTL
+ * @returns An error if the value is not a positive duration
+ */
+func ParseTTL(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DefaultTTL, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cache.ttl %q: use a duration like 24h or 90m", value)
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("invalid cache.ttl %q: must be positive", value)
+	}
+	return ttl, nil
+}
+
+/**
+ * FromConfig returns the cache at cache.location (default DefaultDir) with
+ * the TTL from cache.ttl. A leading ~ in the location is expanded.
+ *
+ * @param cfg - The application configuration
+ * @returns The session cache
+ * @returns An error if cache.ttl is invalid
+ */
+func FromConfig(cfg *config.Config) (*SessionCache, error) {
+	ttl, err := ParseTTL(cfg.Cache.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := strings.TrimSpace(cfg.Cache.Location)
+	switch {
+	case dir == "":
+		dir = DefaultDir()
+	case dir == "~" || strings.HasPrefix(dir, "~/"):
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+		}
+	}
+	return GetCache(ttl, dir), nil
+}
+
+/**
+ * Dir returns the directory holding the cache files.
+ */
+func (sc *SessionCache) Dir() string {
+	return sc.cachedir
+}
+
 func (sc *SessionCache) Get() (*CachedSession, error) {
 	sc.mu.RLock()
 	defer sc.mu.RUnlock()
@@ -105,11 +193,34 @@ func (sc *SessionCache) UpdateLastUsed(sessionID string) error {
 	return fmt.Errorf("session not found in cache")
 }
 
+/**
+ * Sessions returns every cached session, expired ones included, ordered by
+ * creation time.
+ *
+ * @returns Copies of the cached sessions
+ */
+func (sc *SessionCache) Sessions() []CachedSession {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	sessions := make([]CachedSession, 0, len(sc.cache))
+	for _, session := range sc.cache {
+		sessions = append(sessions, *session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
 func (sc *SessionCache) Clear() error {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 
 	sc.cache = make(map[string]*CachedSession)
+	if err := os.Remove(filepath.Join(sc.cachedir, "last_messages.json")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return sc.save()
 }
 
@@ -129,6 +240,35 @@ func (sc *SessionCache) Status() (int, int, error) {
 	return totalEntries, validEntries, nil
 }
 
+/**
+ * Prune removes sessions older than the TTL and rewrites the cache file.
+ *
+ * @returns The number of sessions removed
+ * @returns An error if the cache file cannot be written
+ */
+func (sc *SessionCache) Prune() (int, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	removed := sc.pruneExpired()
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, sc.save()
+}
+
+// pruneExpired drops expired sessions from memory; the caller holds the lock.
+func (sc *SessionCache) pruneExpired() int {
+	removed := 0
+	for key, session := range sc.cache {
+		if time.Since(session.CreatedAt) > sc.ttl {
+			delete(sc.cache, key)
+			removed++
+		}
+	}
+	return removed
+}
+
 func hashRepoPath(path string) string {
 	hash := md5.Sum([]byte(path))
 	return fmt.Sprintf("%x", hash)
@@ -150,7 +290,14 @@ func (sc *SessionCache) load() error {
 		return err
 	}
 
+	if cached == nil {
+		cached = make(map[string]*CachedSession)
+	}
 	sc.cache = cached
+
+	if sc.pruneExpired() > 0 {
+		return sc.save()
+	}
 	return nil
 }
 
@@ -167,3 +314,79 @@ func (sc *SessionCache) save() error {
 
 	return os.WriteFile(cacheFile, data, 0o644)
 }
+
+/**
+ * LastMessage is the most recently generated message for a repository and the
+ * hash of the input it was generated from.
+ */
+type LastMessage struct {
+	InputHash string    `json:"input_hash"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+/**
+ * GetLastMessage returns the last message generated in the current repository
+ * if it was generated from an input with the same hash.
+ *
+ * @param inputHash - Hash of the generation input (diff and prompt settings)
+ * @returns The cached message and true, or empty string and false
+ */
+func (sc *SessionCache) GetLastMessage(inputHash string) (string, bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	repoPath, err := git.GetRepositoryRoot()
+	if err != nil {
+		return "", false
+	}
+
+	last, exists := sc.loadLastMessages()[hashRepoPath(repoPath)]
+	if !exists || last.InputHash != inputHash || time.Since(last.CreatedAt) > sc.ttl {
+		return "", false
+	}
+	return last.Message, true
+}
+
+/**
+ * SetLastMessage records the message generated in the current repository.
+ *
+ * @param inputHash - Hash of the generation input
+ * @param message - The generated message
+ * @returns An error if the cache file cannot be written
+ */
+func (sc *SessionCache) SetLastMessage(inputHash, message string) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	repoPath, err := git.GetRepositoryRoot()
+	if err != nil {
+		return err
+	}
+
+	messages := sc.loadLastMessages()
+	messages[hashRepoPath(repoPath)] = &LastMessage{
+		InputHash: inputHash,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+
+	if err := os.MkdirAll(sc.cachedir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(sc.cachedir, "last_messages.json"), data, 0o644)
+}
+
+func (sc *SessionCache) loadLastMessages() map[string]*LastMessage {
+	messages := make(map[string]*LastMessage)
+	data, err := os.ReadFile(filepath.Join(sc.cachedir, "last_messages.json"))
+	if err != nil {
+		return messages
+	}
+	_ = json.Unmarshal(data, &messages)
+	return messages
+}
@@ -2,10 +2,13 @@
 package cache
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/avgt93/commit-gen/internal/config"
 )
 
 func TestCacheInitialization(t *testing.T) {
@@ -162,3 +165,117 @@ func TestHashRepoPath(t *testing.T) {
 		t.Logf("✓ Hash format: %s", hash1)
 	}
 }
+
+func TestCachePrune(t *testing.T) {
+	tmpDir := t.TempDir()
+	seeded := map[string]*CachedSession{
+		"expired": {SessionID: "old", CreatedAt: time.Now().Add(-48 * time.Hour)},
+		"fresh":   {SessionID: "new", CreatedAt: time.Now().Add(-time.Hour)},
+	}
+	data, _ := json.Marshal(seeded)
+	if err := os.WriteFile(filepath.Join(tmpDir, "sessions.json"), data, 0o644); err != nil {
+		t.Fatalf("Failed to seed cache: %v", err)
+	}
+
+	sc := &SessionCache{cache: make(map[string]*CachedSession), ttl: 24 * time.Hour, cachedir: tmpDir}
+	if err := sc.load(); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if _, ok := sc.cache["expired"]; ok {
+		t.Error("Expired session survived load")
+	}
+	if _, ok := sc.cache["fresh"]; !ok {
+		t.Error("Fresh session was pruned on load")
+	}
+
+	data, _ = os.ReadFile(filepath.Join(tmpDir, "sessions.json"))
+	var onDisk map[string]*CachedSession
+	_ = json.Unmarshal(data, &onDisk)
+	if len(onDisk) != 1 || onDisk["fresh"] == nil {
+		t.Errorf("Cache file not rewritten: %s", data)
+	}
+
+	sc.cache["stale"] = &CachedSession{SessionID: "stale", CreatedAt: time.Now().Add(-25 * time.Hour)}
+	removed, err := sc.Prune()
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if removed != 1 || len(sc.cache) != 1 {
+		t.Errorf("Expected 1 removed and 1 left, got %d removed and %d left", removed, len(sc.cache))
+	}
+}
+
+func TestGetCacheSeparatesDirectories(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+
+	cacheA := GetCache(time.Hour, dirA)
+	cacheB := GetCache(2*time.Hour, dirB)
+	if cacheA == cacheB {
+		t.Fatal("Caches for different directories share an instance")
+	}
+	if cacheA.cachedir != dirA || cacheB.cachedir != dirB {
+		t.Errorf("Cache directories mixed up: %q, %q", cacheA.cachedir, cacheB.cachedir)
+	}
+	if cacheB.ttl != 2*time.Hour {
+		t.Errorf("Second cache ignored its ttl: %v", cacheB.ttl)
+	}
+
+	cacheA.mu.Lock()
+	cacheA.cache["key"] = &CachedSession{SessionID: "a", CreatedAt: time.Now()}
+	cacheA.mu.Unlock()
+	if _, ok := cacheB.cache["key"]; ok {
+		t.Error("Caches for different directories share state")
+	}
+
+	if GetCache(time.Hour, dirA+string(filepath.Separator)) != cacheA {
+		t.Error("Same directory should return the same instance")
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"", DefaultTTL, false},
+		{"24h", 24 * time.Hour, false},
+		{" 90m ", 90 * time.Minute, false},
+		{"1d", 0, true},
+		{"0s", 0, true},
+		{"-1h", 0, true},
+	}
+
+	for _, tt := range tests {
+		ttl, err := ParseTTL(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTTL(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if ttl != tt.expected {
+			t.Errorf("ParseTTL(%q) = %v, expected %v", tt.value, ttl, tt.expected)
+		}
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Cache.TTL = "2h"
+	cfg.Cache.Location = t.TempDir()
+
+	sc, err := FromConfig(cfg)
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	if sc.Dir() != cfg.Cache.Location {
+		t.Errorf("Location ignored: got %q", sc.Dir())
+	}
+	if sc.ttl != 2*time.Hour {
+		t.Errorf("TTL ignored: got %v", sc.ttl)
+	}
+
+	cfg.Cache.TTL = "forever"
+	if _, err := FromConfig(cfg); err == nil {
+		t.Error("Expected an error for an invalid TTL")
+	}
+}
@@ -1,32 +1,107 @@
 package config
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/avgt93/commit-gen/internal/git"
 	"github.com/spf13/viper"
 )
 
+/**
+ * ModelConfig selects the provider and model used for generation.
+ */
+type ModelConfig struct {
+	Provider string `mapstructure:"provider"`
+	ModelID  string `mapstructure:"model_id"`
+}
+
+/**
+ * Override replaces the style and/or model when most changed files match PathGlob.
+ * Empty fields keep the global setting.
+ */
+type Override struct {
+	PathGlob string      `mapstructure:"path_glob"`
+	Style    string      `mapstructure:"style"`
+	Model    ModelConfig `mapstructure:"model"`
+}
+
+/**
+ * ModelPricing is the price of a model in currency units per million tokens.
+ * Model matches either "provider/model" or just the model id.
+ */
+type ModelPricing struct {
+	Model  string  `mapstructure:"model"`
+	Input  float64 `mapstructure:"input"`
+	Output float64 `mapstructure:"output"`
+}
+
 /**
  * Config holds all configuration settings for commit-gen.
  */
 type Config struct {
 	OpenCode struct {
-		Mode    string `mapstructure:"mode"`
-		Host    string `mapstructure:"host"`
-		Port    int    `mapstructure:"port"`
-		Timeout int    `mapstructure:"timeout"`
+		Mode     string `mapstructure:"mode"`
+		Scheme   string `mapstructure:"scheme"`
+		Host     string `mapstructure:"host"`
+		Port     int    `mapstructure:"port"`
+		BasePath string `mapstructure:"base_path"`
+		Timeout  int    `mapstructure:"timeout"`
+
+		Binary         string `mapstructure:"binary"`
+		RecordRequests string `mapstructure:"record_requests"`
+		MaxRetries     int    `mapstructure:"max_retries"`
+		APIKey         string `mapstructure:"api_key"`
+		StartupTimeout int    `mapstructure:"startup_timeout"`
 	} `mapstructure:"opencode"`
 
+	OpenAI struct {
+		BaseURL string `mapstructure:"base_url"`
+		APIKey  string `mapstructure:"api_key"`
+		Model   string `mapstructure:"model"`
+	} `mapstructure:"openai"`
+
 	Generation struct {
-		Style   string `mapstructure:"style"`
-		Confirm bool   `mapstructure:"confirm"`
-		Model   struct {
-			Provider string `mapstructure:"provider"`
-			ModelID  string `mapstructure:"model_id"`
-		} `mapstructure:"model"`
+		Style            string            `mapstructure:"style"`
+		Confirm          bool              `mapstructure:"confirm"`
+		Model            ModelConfig       `mapstructure:"model"`
+		TypeMap          map[string]string `mapstructure:"type_map"`
+		CustomStyles     map[string]string `mapstructure:"custom_styles"`
+		StripPrefixes    []string          `mapstructure:"strip_prefixes"`
+		AllowedProviders []string          `mapstructure:"allowed_providers"`
+		MaxCostTokens    int               `mapstructure:"max_cost_tokens"`
+		IssueFooter      bool              `mapstructure:"issue_footer"`
+		IssueKeyword     string            `mapstructure:"issue_keyword"`
+		Signoff          bool              `mapstructure:"signoff"`
+		CoAuthors        []string          `mapstructure:"co_authors"`
+		Candidates       int               `mapstructure:"candidates"`
+		ShowUsage        bool              `mapstructure:"show_usage"`
+		Pricing          []ModelPricing    `mapstructure:"pricing"`
+		DetectTrivial    bool              `mapstructure:"detect_trivial"`
+		DetectBreaking   bool              `mapstructure:"detect_breaking"`
+		SkipBackendCheck bool              `mapstructure:"skip_backend_check"`
+		FallbackModel    string            `mapstructure:"fallback_model"`
+		UsePRTemplate    bool              `mapstructure:"use_pr_template"`
+		Body             bool              `mapstructure:"body"`
+		Overrides        []Override        `mapstructure:"overrides"`
+
+		MaxSubjectLength int    `mapstructure:"max_subject_length"`
+		OnTooLong        string `mapstructure:"on_too_long"`
+		OnSummarized     string `mapstructure:"on_summarized"`
+
+		IncludeBranch      bool   `mapstructure:"include_branch"`
+		TicketPattern      string `mapstructure:"ticket_pattern"`
+		TicketPosition     string `mapstructure:"ticket_position"`
+		ForceInferredScope bool   `mapstructure:"force_inferred_scope"`
+		OverrideModelScope bool   `mapstructure:"override_model_scope"`
 	} `mapstructure:"generation"`
 
 	Cache struct {
@@ -39,13 +114,59 @@ type Config struct {
 		StagedOnly  bool   `mapstructure:"staged_only"`
 		Editor      string `mapstructure:"editor"`
 		MaxDiffSize int    `mapstructure:"max_diff_size"`
+
+		RelativePaths bool   `mapstructure:"relative_paths"`
+		DiffAlgorithm string `mapstructure:"diff_algorithm"`
+
+		CollapseLargeFiles bool `mapstructure:"collapse_large_files"`
+		LargeFileThreshold int  `mapstructure:"large_file_threshold"`
+
+		NormalizeBlankLines bool `mapstructure:"normalize_blank_lines"`
+		MergeTemplate       bool `mapstructure:"merge_template"`
+
+		ExcludePaths []string `mapstructure:"exclude_paths"`
+		ContextLines int      `mapstructure:"context_lines"`
 	} `mapstructure:"git"`
+
+	Hook struct {
+		OnAmend string `mapstructure:"on_amend"`
+	} `mapstructure:"hook"`
 }
 
 var cfg *Config
 
+// BuiltinStyles are the commit styles available without generation.custom_styles.
+var BuiltinStyles = []string{"conventional", "imperative", "detailed", "gitmoji"}
+
+// MaxCandidates is the largest generation.candidates, so each can be picked with one key.
+const MaxCandidates = 9
+
+// RepoConfigName is the per-repository config file looked up at the git repository root.
+const RepoConfigName = ".commit-gen.yaml"
+
+// repoFile is the repository config merged by Initialize, if any.
+var repoFile string
+
+// DefaultStripPrefixes are lead-in phrases removed from the start of model output.
+var DefaultStripPrefixes = []string{
+	"here is the commit message",
+	"here's the commit message",
+	"here is a commit message",
+	"here's a commit message",
+	"here is your commit message",
+	"here's your commit message",
+	"here is your commit",
+	"here's your commit",
+	"suggested commit message",
+	"commit message",
+	"sure",
+}
+
 /**
- * Initialize loads and parses the configuration from file, environment, and defaults.
+ * Initialize loads and parses the configuration. Later sources win:
+ * defaults, the global config file, the repository's .commit-gen.yaml,
+ * then COMMIT_GEN_* environment variables. Command-line flags are applied
+ * on top by the caller.
  *
  * @param cfgFile - Path to a specific config file, or empty for default locations
  * @returns An error if config loading fails
@@ -54,19 +175,65 @@ func Initialize(cfgFile string) error {
 	viper.SetDefault("opencode.mode", "run")
 	viper.SetDefault("opencode.host", "localhost")
 	viper.SetDefault("opencode.port", 4096)
+	viper.SetDefault("opencode.scheme", "http")
+	viper.SetDefault("opencode.base_path", "")
 	viper.SetDefault("opencode.timeout", 120)
+	viper.SetDefault("opencode.startup_timeout", 15)
+	viper.SetDefault("opencode.binary", "opencode")
+	viper.SetDefault("opencode.record_requests", "")
+	viper.SetDefault("opencode.max_retries", 3)
+	viper.SetDefault("opencode.api_key", "")
+
+	viper.SetDefault("openai.base_url", "https://api.openai.com")
+	viper.SetDefault("openai.api_key", "")
+	viper.SetDefault("openai.model", "")
 
 	viper.SetDefault("generation.style", "conventional")
 	viper.SetDefault("generation.confirm", true)
 	viper.SetDefault("generation.model.provider", "opencode")
 	viper.SetDefault("generation.model.model_id", "gpt-5-nano")
+	viper.SetDefault("generation.strip_prefixes", DefaultStripPrefixes)
+	viper.SetDefault("generation.max_cost_tokens", 0)
+	viper.SetDefault("generation.issue_footer", false)
+	viper.SetDefault("generation.issue_keyword", "")
+	viper.SetDefault("generation.signoff", false)
+	viper.SetDefault("generation.co_authors", []string{})
+	viper.SetDefault("generation.candidates", 1)
+	viper.SetDefault("generation.show_usage", false)
+	viper.SetDefault("generation.pricing", []ModelPricing{})
+	viper.SetDefault("generation.detect_trivial", true)
+	viper.SetDefault("generation.detect_breaking", false)
+	viper.SetDefault("generation.skip_backend_check", false)
+	viper.SetDefault("generation.fallback_model", "")
+	viper.SetDefault("generation.use_pr_template", false)
+	viper.SetDefault("generation.body", false)
+	viper.SetDefault("generation.custom_styles", map[string]string{})
+	viper.SetDefault("generation.max_subject_length", 72)
+	viper.SetDefault("generation.on_too_long", "truncate")
+	viper.SetDefault("generation.on_summarized", "warn")
+	viper.SetDefault("generation.include_branch", false)
+	viper.SetDefault("generation.ticket_pattern", "")
+	viper.SetDefault("generation.ticket_position", "trailer")
+	viper.SetDefault("generation.force_inferred_scope", false)
+	viper.SetDefault("generation.override_model_scope", false)
 
 	viper.SetDefault("cache.enabled", true)
 	viper.SetDefault("cache.ttl", "24h")
+	viper.SetDefault("cache.location", "")
 
 	viper.SetDefault("git.staged_only", true)
 	viper.SetDefault("git.editor", "")
 	viper.SetDefault("git.max_diff_size", 32*1024)
+	viper.SetDefault("git.relative_paths", false)
+	viper.SetDefault("git.diff_algorithm", "")
+	viper.SetDefault("git.collapse_large_files", true)
+	viper.SetDefault("git.large_file_threshold", 8*1024)
+	viper.SetDefault("git.normalize_blank_lines", true)
+	viper.SetDefault("git.merge_template", false)
+	viper.SetDefault("git.exclude_paths", git.DefaultExcludePaths)
+	viper.SetDefault("git.context_lines", 3)
+
+	viper.SetDefault("hook.on_amend", "skip")
 
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
@@ -87,7 +254,12 @@ func Initialize(cfgFile string) error {
 		}
 	}
 
-	viper.SetEnvPrefix("COMMIT_GEN")
+	if err := mergeRepoConfig(); err != nil {
+		return err
+	}
+
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	cfg = &Config{}
@@ -98,6 +270,128 @@ func Initialize(cfgFile string) error {
 	return nil
 }
 
+/**
+ * mergeRepoConfig merges .commit-gen.yaml from the repository root over the
+ * global config. Outside a git repository, or without the file, it does nothing.
+ *
+ * @returns An error if the file exists but cannot be parsed
+ */
+func mergeRepoConfig() error {
+	repoFile = ""
+
+	root, err := git.GetRepositoryRoot()
+	if err != nil {
+		return nil
+	}
+
+	path := filepath.Join(root, RepoConfigName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	viper.SetConfigType("yaml")
+	if err := viper.MergeConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	repoFile = path
+	return nil
+}
+
+/**
+ * RepoFileUsed returns the path of the merged repository config file.
+ *
+ * @returns The .commit-gen.yaml path, or empty string if none was found
+ */
+func RepoFileUsed() string {
+	return repoFile
+}
+
+// secretKeys are settings whose values are never shown by Explain.
+var secretKeys = map[string]bool{"opencode.api_key": true, "openai.api_key": true}
+
+// EnvPrefix is prepended to environment variables that override settings,
+// e.g. COMMIT_GEN_OPENCODE_HOST for opencode.host.
+const EnvPrefix = "COMMIT_GEN"
+
+/**
+ * Setting is one effective configuration value and where it came from.
+ */
+type Setting struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source string `json:"source"` // "default", "file", "repo", or "env"
+}
+
+/**
+ * Explain lists every known setting with its effective value and source,
+ * sorted by key. Environment variables win over the repository config, which
+ * wins over the global config file and then built-in defaults. Secrets such
+ * as opencode.api_key are masked.
+ *
+ * @returns The effective settings
+ */
+func Explain() []Setting {
+	fileKeys := keysIn(FileUsed())
+	repoKeys := keysIn(RepoFileUsed())
+
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+
+	settings := make([]Setting, 0, len(keys))
+	for _, key := range keys {
+		source := "default"
+		if _, ok := os.LookupEnv(EnvVar(key)); ok {
+			source = "env"
+		} else if repoKeys[key] {
+			source = "repo"
+		} else if fileKeys[key] {
+			source = "file"
+		}
+		value := viper.Get(key)
+		if secretKeys[key] && viper.GetString(key) != "" {
+			value = "****"
+		}
+		settings = append(settings, Setting{Key: key, Value: value, Source: source})
+	}
+	return settings
+}
+
+// keysIn returns the settings present in the config file at path.
+func keysIn(path string) map[string]bool {
+	keys := map[string]bool{}
+	if path == "" {
+		return keys
+	}
+	fileViper := viper.New()
+	fileViper.SetConfigFile(path)
+	if err := fileViper.ReadInConfig(); err == nil {
+		for _, key := range fileViper.AllKeys() {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+/**
+ * FileUsed returns the path of the loaded config file.
+ *
+ * @returns The config file path, or empty string if only defaults are in use
+ */
+func FileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
+/**
+ * EnvVar returns the environment variable that overrides key.
+ *
+ * @param key - A dotted configuration key such as "opencode.host"
+ * @returns The variable name, e.g. COMMIT_GEN_OPENCODE_HOST
+ */
+func EnvVar(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
 /**
  * Get returns the current configuration, initializing it if necessary.
  *
@@ -202,53 +496,105 @@ func ConfigExists() bool {
 	return err == nil
 }
 
-/**
- * CreateConfig creates the configuration directory and file with default values.
- *
- * @returns An error if creation fails
- */
-func CreateConfig() error {
-	configDir, err := GetConfigDir()
-	if err != nil {
-		return err
-	}
-
-	if err := os.MkdirAll(configDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	configPath, err := GetConfigPath()
-	if err != nil {
-		return err
-	}
-
-	defaultConfig := `# commit-gen configuration file
+// DefaultConfigYAML is the commented default configuration written by CreateConfig.
+const DefaultConfigYAML = `# commit-gen configuration file
 # See https://github.com/avgt93/commit-gen for documentation
 
 opencode:
-  mode: run              # "run" (default) or "server"
+  mode: run              # "run" (default), "server", or "openai" for an OpenAI-compatible API (see openai below)
+  scheme: http           # server mode only: http or https
   host: localhost        # server mode only
   port: 4096             # server mode only
+  base_path: ""          # server mode only: path prefix when behind a reverse proxy, e.g. /opencode
   timeout: 120           # timeout in seconds
+  binary: opencode       # opencode executable name or path (~ is expanded)
+  record_requests: ""    # server mode only: append HTTP exchanges to this file for debugging
+  max_retries: 3         # server mode only: retries on 429/5xx and network errors, with exponential backoff
+  api_key: ""            # server mode only: sent as a bearer token (or set COMMIT_GEN_OPENCODE_API_KEY)
+  startup_timeout: 15    # server mode only: seconds to wait for an auto-started server to become healthy
+
+openai:                  # used when opencode.mode is "openai"; opencode.timeout still applies
+  base_url: https://api.openai.com # API root, e.g. http://localhost:11434/v1 for Ollama
+  api_key: ""            # sent as a bearer token (or set COMMIT_GEN_OPENAI_API_KEY)
+  model: ""              # model name, e.g. gpt-4o-mini (defaults to generation.model.model_id)
 
 generation:
-  style: conventional    # conventional, imperative, detailed
+  style: conventional    # conventional, imperative, detailed, gitmoji, or a name from custom_styles
+  custom_styles: {}      # your own style guides by name, e.g. {house: "Start with the ticket id..."}
   confirm: true          # prompt to confirm/edit message before committing
   model:
     provider: opencode
     model_id: gpt-5-nano
+  fallback_model: ""     # "provider/model_id" to retry with when the model is unavailable, e.g. opencode/grok-code
+  type_map: {}           # rename commit types after generation, e.g. {feat: feature}
+  allowed_providers: []  # restrict usable providers, e.g. [internal] (empty allows all)
+  max_cost_tokens: 0     # refuse prompts estimated above this many tokens (0 disables)
+  issue_footer: false    # append "Fixes #N"/"Refs #N" when the branch names an issue
+  issue_keyword: ""      # footer keyword override (default: Fixes for fix commits, otherwise Refs)
+  signoff: false         # append "Signed-off-by: Name <email>" from git user.name/user.email (or use --signoff)
+  co_authors: []         # append "Co-authored-by:" trailers, e.g. ["Jane Doe <jane@example.com>"] (or use --co-author)
+  candidates: 1          # number of alternative messages to choose from (1-9, or use --candidates)
+  show_usage: false      # print the tokens the backend reports after generation (or use --show-usage)
+  pricing: []            # per-million-token rates for a cost estimate, e.g. [{model: gpt-4o-mini, input: 0.15, output: 0.6}]
+  detect_trivial: true   # hint docs/style types when only comments or whitespace changed
+  detect_breaking: false # mark removed or changed exported Go funcs/types with "!" and a BREAKING CHANGE footer
+  skip_backend_check: false # skip the availability probe before generating (or use --skip-backend-check); backend errors still surface
+  overrides: []          # per-path rules, e.g. [{path_glob: "frontend/**", style: imperative, model: {model_id: gpt-5}}]
+  body: false            # add a body explaining what and why (override with --body/--no-body)
+  use_pr_template: false # detailed/--full only: structure the body like .github/PULL_REQUEST_TEMPLATE.md
+  max_subject_length: 72 # longest allowed subject in characters (0 disables the check)
+  on_too_long: truncate  # "truncate" cuts at a word boundary, "reprompt" asks the model once for a shorter subject
+  on_summarized: warn    # when the diff exceeds git.max_diff_size: "warn", "silent", or "split" (describe up to 20 files one by one, then combine)
+  include_branch: false  # mention the current branch name (e.g. a ticket id) in the prompt
+  ticket_pattern: ""     # regex matched against the branch, e.g. "[A-Z]+-[0-9]+" (first group used if present)
+  ticket_position: trailer # "trailer" appends "Refs: PROJ-123", "scope" puts the ticket in the subject scope
+  force_inferred_scope: false # conventional/detailed only: add the inferred scope when the model omits it
+  override_model_scope: false # with force_inferred_scope, also replace a scope the model chose
 
 cache:
   enabled: true          # server mode only
-  ttl: 24h
+  ttl: 24h               # how long a session is reused, e.g. 24h or 90m
+  location: ""           # cache directory (defaults to ~/.cache/commit-gen)
 
 git:
   staged_only: true
   editor: ""               # editor for commit messages (defaults to $EDITOR or vim)
   max_diff_size: 32768   # bytes before summarizing (32KB default)
+  context_lines: 3       # lines of context around each change; 0 sends only changed lines
+  relative_paths: false  # show diff paths relative to the current directory
+  diff_algorithm: ""     # myers, minimal, patience, histogram (empty uses git's default)
+  collapse_large_files: true  # replace oversized per-file diffs with a size note before summarizing
+  large_file_threshold: 8192  # per-file diff size in bytes considered large
+  normalize_blank_lines: true # drop leading blank lines and end the message with a single newline
+  merge_template: false # append the trailers of git's commit.template (e.g. "Refs:") when generate or commit writes the message
+  # files left out of the diff; patterns without a / match in any directory
+  exclude_paths: [package-lock.json, yarn.lock, pnpm-lock.yaml, go.sum, Cargo.lock, poetry.lock, composer.lock, Gemfile.lock]
+
+hook:
+  on_amend: skip         # on git commit --amend: "skip" keeps the message, "regenerate" replaces it
 `
 
-	if err := os.WriteFile(configPath, []byte(defaultConfig), 0o644); err != nil {
+/**
+ * CreateConfig creates the configuration directory and file with default values.
+ *
+ * @returns An error if creation fails
+ */
+func CreateConfig() error {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(configPath, []byte(DefaultConfigYAML), 0o644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -259,3 +605,271 @@ git:
 
 	return nil
 }
+
+/**
+ * settingType returns the Go type of the setting at a dotted key, found by
+ * walking the mapstructure tags of Config.
+ *
+ * @param key - A dotted configuration key such as "opencode.port"
+ * @returns The field type, or nil if the key is unknown
+ */
+func settingType(key string) reflect.Type {
+	t := reflect.TypeOf(Config{})
+	for _, part := range strings.Split(strings.ToLower(key), ".") {
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		var next reflect.Type
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Tag.Get("mapstructure") == part {
+				next = t.Field(i).Type
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		t = next
+	}
+	if t.Kind() == reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+/**
+ * ParseValue checks that key is a known setting and converts raw to its type:
+ * integers and booleans are parsed, string lists are split on commas. Maps
+ * and lists of objects must be edited in the config file.
+ *
+ * @param key - A dotted configuration key
+ * @param raw - The value as typed on the command line
+ * @returns The typed value
+ * @returns An error if the key is unknown or the value does not fit its type
+ */
+func ParseValue(key, raw string) (any, error) {
+	t := settingType(key)
+	if t == nil {
+		return nil, fmt.Errorf("unknown setting %q", key)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer, got %q", key, raw)
+		}
+		return n, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", key, raw)
+		}
+		return b, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			var items []string
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			return items, nil
+		}
+	}
+	return nil, fmt.Errorf("%s cannot be set from the command line; edit the config file instead", key)
+}
+
+/**
+ * GetValue returns the effective value of a known setting. Secrets are masked.
+ *
+ * @param key - A dotted configuration key
+ * @returns The value
+ * @returns An error if the key is unknown
+ */
+func GetValue(key string) (any, error) {
+	key = strings.ToLower(key)
+	if settingType(key) == nil {
+		return nil, fmt.Errorf("unknown setting %q", key)
+	}
+	if secretKeys[key] && viper.GetString(key) != "" {
+		return "****", nil
+	}
+	return viper.Get(key), nil
+}
+
+/**
+ * SetValue validates and sets a single setting, then writes it to the config
+ * file in use (or the default path, creating it if needed). Only the file's
+ * own settings are written back, so defaults and environment overrides are
+ * not copied into it.
+ *
+ * @param key - A dotted configuration key
+ * @param raw - The value as typed on the command line
+ * @returns The config file written
+ * @returns An error if validation or writing fails
+ */
+func SetValue(key, raw string) (string, error) {
+	key = strings.ToLower(key)
+	value, err := ParseValue(key, raw)
+	if err != nil {
+		return "", err
+	}
+
+	path := FileUsed()
+	if path == "" {
+		if path, err = GetConfigPath(); err != nil {
+			return "", err
+		}
+	}
+
+	fileViper := viper.New()
+	fileViper.SetConfigFile(path)
+	if _, err := os.Stat(path); err == nil {
+		if err := fileViper.ReadInConfig(); err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	} else if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	fileViper.Set(key, value)
+	if err := fileViper.WriteConfigAs(path); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	Set(key, value)
+	if cfg != nil {
+		if err := viper.Unmarshal(cfg); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+/**
+ * Validate checks settings that would otherwise fail late or be silently
+ * ignored.
+ *
+ * @param c - The configuration to check
+ * @returns One descriptive error per problem, or nil if the config is valid
+ */
+func Validate(c *Config) []error {
+	var problems []error
+
+	if c.OpenCode.Mode != "" && c.OpenCode.Mode != "run" && c.OpenCode.Mode != "server" && c.OpenCode.Mode != "openai" {
+		problems = append(problems, fmt.Errorf("opencode.mode %q is not valid; use \"run\", \"server\", or \"openai\"", c.OpenCode.Mode))
+	}
+	if c.OpenCode.Mode == "openai" && c.OpenAI.BaseURL != "" && !strings.HasPrefix(c.OpenAI.BaseURL, "http://") && !strings.HasPrefix(c.OpenAI.BaseURL, "https://") {
+		problems = append(problems, fmt.Errorf("openai.base_url %q must start with http:// or https://", c.OpenAI.BaseURL))
+	}
+	if c.OpenCode.Scheme != "" && c.OpenCode.Scheme != "http" && c.OpenCode.Scheme != "https" {
+		problems = append(problems, fmt.Errorf("opencode.scheme %q is not valid; use \"http\" or \"https\"", c.OpenCode.Scheme))
+	}
+	if c.OpenCode.Port < 1 || c.OpenCode.Port > 65535 {
+		problems = append(problems, fmt.Errorf("opencode.port %d is out of range (1-65535)", c.OpenCode.Port))
+	}
+	if c.OpenCode.Timeout < 0 {
+		problems = append(problems, fmt.Errorf("opencode.timeout %d must not be negative", c.OpenCode.Timeout))
+	}
+	if c.OpenCode.StartupTimeout < 0 {
+		problems = append(problems, fmt.Errorf("opencode.startup_timeout %d must not be negative", c.OpenCode.StartupTimeout))
+	}
+	if c.OpenCode.MaxRetries < 0 {
+		problems = append(problems, fmt.Errorf("opencode.max_retries %d must not be negative", c.OpenCode.MaxRetries))
+	}
+
+	if c.Git.ContextLines < 0 {
+		problems = append(problems, fmt.Errorf("git.context_lines %d must not be negative", c.Git.ContextLines))
+	}
+
+	if c.Git.DiffAlgorithm != "" && !isOneOf(c.Git.DiffAlgorithm, git.DiffAlgorithms) {
+		problems = append(problems, fmt.Errorf("git.diff_algorithm %q is not valid; use one of: %s", c.Git.DiffAlgorithm, strings.Join(git.DiffAlgorithms, ", ")))
+	}
+
+	if c.Hook.OnAmend != "" && c.Hook.OnAmend != "skip" && c.Hook.OnAmend != "regenerate" {
+		problems = append(problems, fmt.Errorf("hook.on_amend %q is not valid; use \"skip\" or \"regenerate\"", c.Hook.OnAmend))
+	}
+
+	if ttl := strings.TrimSpace(c.Cache.TTL); ttl != "" {
+		if d, err := time.ParseDuration(ttl); err != nil || d <= 0 {
+			problems = append(problems, fmt.Errorf("cache.ttl %q is not a positive duration like 24h or 90m", c.Cache.TTL))
+		}
+	}
+
+	if c.Generation.Candidates < 1 || c.Generation.Candidates > MaxCandidates {
+		problems = append(problems, fmt.Errorf("generation.candidates %d is out of range (1-%d)", c.Generation.Candidates, MaxCandidates))
+	}
+
+	for _, author := range c.Generation.CoAuthors {
+		if err := git.ValidateAuthor(author); err != nil {
+			problems = append(problems, fmt.Errorf("generation.co_authors: %w", err))
+		}
+	}
+
+	switch c.Generation.OnTooLong {
+	case "", "truncate", "reprompt":
+	default:
+		problems = append(problems, fmt.Errorf("generation.on_too_long %q is not valid; use \"truncate\" or \"reprompt\"", c.Generation.OnTooLong))
+	}
+
+	switch c.Generation.TicketPosition {
+	case "", "trailer", "scope":
+	default:
+		problems = append(problems, fmt.Errorf("generation.ticket_position %q is not valid; use \"trailer\" or \"scope\"", c.Generation.TicketPosition))
+	}
+
+	switch c.Generation.OnSummarized {
+	case "", "warn", "silent", "split":
+	default:
+		problems = append(problems, fmt.Errorf("generation.on_summarized %q is not valid; use \"warn\", \"silent\", or \"split\"", c.Generation.OnSummarized))
+	}
+
+	if fallback := c.Generation.FallbackModel; fallback != "" {
+		if provider, model, ok := strings.Cut(fallback, "/"); !ok || provider == "" || model == "" {
+			problems = append(problems, fmt.Errorf("generation.fallback_model %q is not valid; use \"provider/model_id\"", fallback))
+		}
+	}
+
+	for _, price := range c.Generation.Pricing {
+		if price.Model == "" {
+			problems = append(problems, fmt.Errorf("generation.pricing entries need a model"))
+		} else if price.Input < 0 || price.Output < 0 {
+			problems = append(problems, fmt.Errorf("generation.pricing for %q must not be negative", price.Model))
+		}
+	}
+
+	if !isKnownStyle(c.Generation.Style, c.Generation.CustomStyles) {
+		problems = append(problems, fmt.Errorf("generation.style %q is not a built-in style (%s) or defined in generation.custom_styles", c.Generation.Style, strings.Join(BuiltinStyles, ", ")))
+	}
+
+	return problems
+}
+
+// isOneOf reports whether value is in allowed.
+func isOneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// isKnownStyle reports whether style is built in or defined in custom.
+func isKnownStyle(style string, custom map[string]string) bool {
+	for _, builtin := range BuiltinStyles {
+		if style == builtin {
+			return true
+		}
+	}
+	// viper lowercases map keys, so also look the style up in lower case.
+	for _, name := range []string{style, strings.ToLower(style)} {
+		if _, ok := custom[name]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,13 @@
 package config
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/spf13/viper"
 )
 
 func TestConfigInitialization(t *testing.T) {
@@ -129,3 +135,264 @@ func TestCommitStyles(t *testing.T) {
 		t.Logf("✓ Valid commit style: %s", style)
 	}
 }
+
+func TestDefaultConfigYAML(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(DefaultConfigYAML)); err != nil {
+		t.Fatalf("Default config is not valid YAML: %v", err)
+	}
+
+	for _, key := range []string{"opencode", "generation", "cache", "git"} {
+		if !v.IsSet(key) {
+			t.Errorf("Default config missing top-level key %q", key)
+		}
+	}
+
+	t.Log("✓ Default config contains all top-level keys")
+}
+
+func TestExplainLabelsEnvOverride(t *testing.T) {
+	t.Setenv("COMMIT_GEN_OPENCODE_HOST", "env-host")
+	if err := Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer func() { _ = Initialize("") }()
+
+	if Get().OpenCode.Host != "env-host" {
+		t.Errorf("Env override not applied: got %q", Get().OpenCode.Host)
+	}
+
+	found := false
+	for _, s := range Explain() {
+		switch s.Key {
+		case "opencode.host":
+			found = true
+			if s.Source != "env" || s.Value != "env-host" {
+				t.Errorf("Expected env-host from env, got %v from %s", s.Value, s.Source)
+			}
+		case "opencode.port":
+			if s.Source == "env" {
+				t.Errorf("opencode.port should not be labeled env")
+			}
+		}
+	}
+	if !found {
+		t.Fatal("opencode.host missing from Explain output")
+	}
+
+	t.Log("✓ Env-overridden value labeled as env")
+}
+
+func TestExplainMasksAPIKey(t *testing.T) {
+	t.Setenv("COMMIT_GEN_OPENCODE_API_KEY", "sk-secret")
+	if err := Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer func() { _ = Initialize("") }()
+
+	if Get().OpenCode.APIKey != "sk-secret" {
+		t.Errorf("API key not read from env: got %q", Get().OpenCode.APIKey)
+	}
+
+	for _, s := range Explain() {
+		if s.Key == "opencode.api_key" && s.Value != "****" {
+			t.Errorf("API key not masked: got %v", s.Value)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		key      string
+		raw      string
+		expected any
+		wantErr  bool
+	}{
+		{"generation.style", "imperative", "imperative", false},
+		{"opencode.port", "8080", 8080, false},
+		{"opencode.timeout", "soon", nil, true},
+		{"cache.enabled", "false", false, false},
+		{"cache.enabled", "maybe", nil, true},
+		{"generation.model.provider", "anthropic", "anthropic", false},
+		{"generation.type_map", "feat=feature", nil, true},
+		{"generation.nonexistent", "x", nil, true},
+		{"opencode", "x", nil, true},
+	}
+
+	for _, tt := range tests {
+		value, err := ParseValue(tt.key, tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseValue(%q, %q) error = %v, wantErr %v", tt.key, tt.raw, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && value != tt.expected {
+			t.Errorf("ParseValue(%q, %q) = %#v, expected %#v", tt.key, tt.raw, value, tt.expected)
+		}
+	}
+
+	items, err := ParseValue("generation.allowed_providers", "opencode, anthropic")
+	if err != nil {
+		t.Fatalf("ParseValue failed for list: %v", err)
+	}
+	if list, ok := items.([]string); !ok || len(list) != 2 || list[1] != "anthropic" {
+		t.Errorf("Unexpected list value: %#v", items)
+	}
+}
+
+func TestSetValuePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("generation:\n  style: conventional\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := Initialize(path); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer func() {
+		viper.Reset()
+		_ = Initialize("")
+	}()
+
+	if _, err := SetValue("generation.style", "imperative"); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+	if _, err := SetValue("opencode.port", "8080"); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+	if _, err := SetValue("opencode.port", "eighty"); err == nil {
+		t.Error("Expected an error for a non-integer port")
+	}
+
+	if Get().Generation.Style != "imperative" || Get().OpenCode.Port != 8080 {
+		t.Errorf("In-memory config not updated: style=%q port=%d", Get().Generation.Style, Get().OpenCode.Port)
+	}
+	if value, _ := GetValue("opencode.port"); value != 8080 {
+		t.Errorf("GetValue mismatch: got %#v", value)
+	}
+
+	fileViper := viper.New()
+	fileViper.SetConfigFile(path)
+	if err := fileViper.ReadInConfig(); err != nil {
+		t.Fatalf("Failed to reread config: %v", err)
+	}
+	if fileViper.GetString("generation.style") != "imperative" || fileViper.GetInt("opencode.port") != 8080 {
+		t.Errorf("Values not persisted: %v", fileViper.AllSettings())
+	}
+	if fileViper.IsSet("git.max_diff_size") {
+		t.Error("Defaults should not be written to the config file")
+	}
+}
+
+func TestRepoConfigOverridesGlobal(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	if err := os.WriteFile(global, []byte("generation:\n  style: detailed\n  body: true\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+
+	repo := filepath.Join(dir, "repo")
+	if err := os.Mkdir(repo, 0o755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	if err := exec.Command("git", "init", repo).Run(); err != nil {
+		t.Skipf("git not available: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, RepoConfigName), []byte("generation:\n  style: imperative\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write repo config: %v", err)
+	}
+
+	oldCwd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldCwd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("Failed to chdir: %v", err)
+	}
+	defer func() {
+		viper.Reset()
+		_ = Initialize("")
+	}()
+
+	if err := Initialize(global); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if Get().Generation.Style != "imperative" {
+		t.Errorf("Repo style should win: got %q", Get().Generation.Style)
+	}
+	if !Get().Generation.Body {
+		t.Error("Global settings not in the repo config should be kept")
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to chdir: %v", err)
+	}
+	viper.Reset()
+	if err := Initialize(global); err != nil {
+		t.Fatalf("Initialize outside a repository failed: %v", err)
+	}
+	if Get().Generation.Style != "detailed" || RepoFileUsed() != "" {
+		t.Errorf("Outside a repository the global style should apply: got %q", Get().Generation.Style)
+	}
+}
+
+// validConfig returns a configuration that passes Validate.
+func validConfig() *Config {
+	c := &Config{}
+	c.OpenCode.Mode = "run"
+	c.OpenCode.Scheme = "http"
+	c.OpenCode.Port = 4096
+	c.OpenCode.Timeout = 120
+	c.Cache.TTL = "24h"
+	c.Generation.Style = "conventional"
+	c.Generation.Candidates = 1
+	return c
+}
+
+func TestValidate(t *testing.T) {
+	if problems := Validate(validConfig()); len(problems) != 0 {
+		t.Fatalf("Valid config reported problems: %v", problems)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(c *Config)
+		want   string
+	}{
+		{"mode", func(c *Config) { c.OpenCode.Mode = "daemon" }, "opencode.mode"},
+		{"openai base url", func(c *Config) { c.OpenCode.Mode = "openai"; c.OpenAI.BaseURL = "localhost:11434" }, "openai.base_url"},
+		{"scheme", func(c *Config) { c.OpenCode.Scheme = "ftp" }, "opencode.scheme"},
+		{"port", func(c *Config) { c.OpenCode.Port = 70000 }, "opencode.port"},
+		{"timeout", func(c *Config) { c.OpenCode.Timeout = -1 }, "opencode.timeout"},
+		{"retries", func(c *Config) { c.OpenCode.MaxRetries = -2 }, "opencode.max_retries"},
+		{"startup timeout", func(c *Config) { c.OpenCode.StartupTimeout = -1 }, "opencode.startup_timeout"},
+		{"ttl", func(c *Config) { c.Cache.TTL = "1 day" }, "cache.ttl"},
+		{"negative ttl", func(c *Config) { c.Cache.TTL = "-1h" }, "cache.ttl"},
+		{"style", func(c *Config) { c.Generation.Style = "haiku" }, "generation.style"},
+		{"candidates", func(c *Config) { c.Generation.Candidates = 12 }, "generation.candidates"},
+		{"zero candidates", func(c *Config) { c.Generation.Candidates = 0 }, "generation.candidates"},
+		{"on_too_long", func(c *Config) { c.Generation.OnTooLong = "trim" }, "generation.on_too_long"},
+		{"ticket_position", func(c *Config) { c.Generation.TicketPosition = "subject" }, "generation.ticket_position"},
+		{"diff_algorithm", func(c *Config) { c.Git.DiffAlgorithm = "fast" }, "git.diff_algorithm"},
+		{"on_summarized", func(c *Config) { c.Generation.OnSummarized = "truncate" }, "generation.on_summarized"},
+		{"fallback model", func(c *Config) { c.Generation.FallbackModel = "gpt-4o-mini" }, "generation.fallback_model"},
+		{"pricing", func(c *Config) { c.Generation.Pricing = []ModelPricing{{Model: "gpt-4o-mini", Input: -1}} }, "generation.pricing"},
+		{"co-author", func(c *Config) { c.Generation.CoAuthors = []string{"Jane Doe <jane@example.com>", "sam@example.com"} }, "generation.co_authors"},
+	}
+
+	for _, tt := range tests {
+		c := validConfig()
+		tt.mutate(c)
+		problems := Validate(c)
+		if len(problems) != 1 || !strings.Contains(problems[0].Error(), tt.want) {
+			t.Errorf("%s: expected one problem mentioning %s, got %v", tt.name, tt.want, problems)
+		}
+	}
+}
+
+func TestValidateAcceptsCustomStyle(t *testing.T) {
+	c := validConfig()
+	c.Generation.Style = "House"
+	c.Generation.CustomStyles = map[string]string{"house": "Start with the ticket id."}
+
+	if problems := Validate(c); len(problems) != 0 {
+		t.Errorf("Custom style reported as invalid: %v", problems)
+	}
+}
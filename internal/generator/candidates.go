@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// candidateMarkerPattern matches the start of a numbered list item such as
+// "1. feat: x", "2) fix: y", "**3.** docs: z", "[1] chore: w", or "Option 2: ...".
+var candidateMarkerPattern = regexp.MustCompile(`^(?:[-*]\s+)?(?:\*\*)?(?:\[(\d+)\]|(?:(?i:option|candidate|message)\s+)?(\d+)[.):])(?:\*\*)?\s+(.*)$`)
+
+/**
+ * candidatesNote asks the model for several alternative messages as a
+ * numbered list.
+ *
+ * @param n - The number of candidates requested
+ * @returns The prompt note, or empty string for a single message
+ */
+func candidatesNote(n int) string {
+	if n <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("\n\nInstead of a single message, write %d distinct alternative commit messages as a numbered list (1., 2., ...). Indent any body lines under their number. Output only the list.", n)
+}
+
+/**
+ * extractCandidates parses a numbered list of commit messages from a model
+ * response. Code fences, bold markers, wrapping quotes or backticks, and
+ * prose before, between, or after the list are ignored. Indented lines under
+ * an item are kept as its body.
+ *
+ * @param response - The raw model response
+ * @param n - The maximum number of candidates to return
+ * @returns The distinct candidates in order, or nil if fewer than two were found
+ */
+func extractCandidates(response string, n int) []string {
+	var candidates []string
+	var current []string
+	seen := make(map[string]bool)
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		message := strings.TrimSpace(strings.Join(current, "\n"))
+		current = nil
+		if message != "" && !seen[message] {
+			seen[message] = true
+			candidates = append(candidates, message)
+		}
+	}
+
+	markerIndent := -1
+	for _, line := range strings.Split(response, "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		if strings.HasPrefix(trimmed, "```") {
+			continue
+		}
+		if current != nil && trimmed == "" {
+			current = append(current, "")
+			continue
+		}
+		// Lines indented under an item are its body, even if they look like a list.
+		if current != nil && indent > markerIndent {
+			current = append(current, trimmed)
+			continue
+		}
+		if match := candidateMarkerPattern.FindStringSubmatch(trimmed); match != nil {
+			flush()
+			if markerIndent < 0 {
+				markerIndent = indent
+			}
+			current = []string{unwrapCandidate(match[3])}
+			continue
+		}
+		// Anything else at the list's indentation is prose, not a body.
+		flush()
+	}
+	flush()
+
+	if len(candidates) < 2 {
+		return nil
+	}
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+/**
+ * unwrapCandidate removes markdown emphasis, backticks, and quotes wrapped
+ * around a list item's text.
+ */
+func unwrapCandidate(text string) string {
+	text = strings.TrimSpace(text)
+	for _, wrapper := range []string{"**", "`", "\"", "'"} {
+		if len(text) > 2*len(wrapper) && strings.HasPrefix(text, wrapper) && strings.HasSuffix(text, wrapper) {
+			text = strings.TrimSpace(text[len(wrapper) : len(text)-len(wrapper)])
+		}
+	}
+	return text
+}
@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractCandidatesFromMessyResponse(t *testing.T) {
+	response := "Sure! Here are 3 options for your commit message:\n\n" +
+		"```\n" +
+		"1. **feat(export): add CSV export**\n" +
+		"2) `fix(export): escape commas in CSV cells`\n" +
+		"   Quote fields that contain separators.\n" +
+		"   \n" +
+		"   1. covers names with commas\n" +
+		"**3.** \"refactor(export): split writer from formatter\"\n" +
+		"```\n\n" +
+		"Let me know if you'd like any changes!\n"
+
+	candidates := extractCandidates(response, 3)
+
+	expected := []string{
+		"feat(export): add CSV export",
+		"fix(export): escape commas in CSV cells\nQuote fields that contain separators.\n\n1. covers names with commas",
+		"refactor(export): split writer from formatter",
+	}
+	if len(candidates) != len(expected) {
+		t.Fatalf("Expected %d candidates, got %d: %q", len(expected), len(candidates), candidates)
+	}
+	for i := range expected {
+		if candidates[i] != expected[i] {
+			t.Errorf("Candidate %d:\n  got: %q\n  expected: %q", i+1, candidates[i], expected[i])
+		}
+	}
+
+	t.Log("✓ Three candidates parsed from a messy response")
+}
+
+func TestExtractCandidatesVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		n        int
+		expected []string
+	}{
+		{"brackets", "[1] feat: a\n[2] feat: b", 2, []string{"feat: a", "feat: b"}},
+		{"option labels", "Option 1: fix: a\nOption 2: fix: b", 2, []string{"fix: a", "fix: b"}},
+		{"dashes with numbers", "- 1. docs: a\n- 2. docs: b", 2, []string{"docs: a", "docs: b"}},
+		{"duplicates dropped", "1. feat: a\n2. feat: a\n3. feat: c", 3, []string{"feat: a", "feat: c"}},
+		{"capped at n", "1. feat: a\n2. feat: b\n3. feat: c", 2, []string{"feat: a", "feat: b"}},
+		{"single message", "feat: just one", 3, nil},
+		{"one item", "1. feat: only", 3, nil},
+	}
+
+	for _, tt := range tests {
+		got := extractCandidates(tt.response, tt.n)
+		if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+			t.Errorf("%s: got %q, expected %q", tt.name, got, tt.expected)
+		}
+	}
+}
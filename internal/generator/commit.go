@@ -1,27 +1,76 @@
 package generator
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/avgt93/commit-gen/internal/cache"
 	"github.com/avgt93/commit-gen/internal/config"
 	"github.com/avgt93/commit-gen/internal/git"
+	"github.com/avgt93/commit-gen/internal/llm/openai"
 	"github.com/avgt93/commit-gen/internal/opencode"
 )
 
 var ErrServerNotRunning = errors.New("opencode server is not running")
 
+// ErrEmptyMessage is returned when the model output is empty after extraction and cleanup.
+var ErrEmptyMessage = errors.New("generated commit message is empty")
+
+// ErrBudgetExceeded is returned when the estimated prompt size exceeds generation.max_cost_tokens.
+var ErrBudgetExceeded = errors.New("prompt exceeds token budget")
+
+// ErrProviderNotAllowed is returned when the selected provider is not in generation.allowed_providers.
+var ErrProviderNotAllowed = errors.New("provider is not allowed")
+
+// ErrInvalidConfig is returned when a generation setting cannot be used, such as an invalid ticket_pattern.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
 /**
- * Generator handles commit message generation using either server or run mode.
+ * Generator handles commit message generation using run, server, or openai mode.
  */
 type Generator struct {
 	client *opencode.Client
 	runner *opencode.Runner
+	chat   *openai.Client
 	cache  *cache.SessionCache
 	config *config.Config
 	mode   string
+	full   bool
+	noBody bool
+	force  bool
+
+	amend     bool
+	reuseBody bool
+
+	partiallyStaged []string
+	significance    git.ChangeSignificance
+	breaking        []string
+	binaryOnly      bool
+	prHeadings      []string
+	paths           []string
+	providedDiff    *string
+	scope           string
+	branch          string
+	diffLimit       int
+
+	onChunk func(string)
+	ctx     context.Context
+	usage   usageTally
+
+	// fallback is generation.fallback_model once the primary model was
+	// unavailable. It is kept apart from config so applyOverrides restoring
+	// the configuration cannot undo the switch.
+	fallback *config.ModelConfig
 }
 
 /**
@@ -43,10 +92,19 @@ func NewGenerator(cfg *config.Config, cacheInstance *cache.SessionCache) *Genera
 		mode:   mode,
 	}
 
-	if mode == "server" {
-		gen.client = opencode.NewClient(cfg.OpenCode.Host, cfg.OpenCode.Port, cfg.OpenCode.Timeout)
-	} else {
-		gen.runner = opencode.NewRunner(cfg.OpenCode.Timeout)
+	switch mode {
+	case "server":
+		baseURL := opencode.BaseURL(cfg.OpenCode.Scheme, cfg.OpenCode.Host, cfg.OpenCode.Port, cfg.OpenCode.BasePath)
+		gen.client = opencode.NewClientWithBaseURL(baseURL, cfg.OpenCode.Timeout)
+		gen.client.SetRetries(cfg.OpenCode.MaxRetries)
+		gen.client.SetAPIKey(cfg.OpenCode.APIKey)
+		if cfg.OpenCode.RecordRequests != "" {
+			gen.client.RecordTo(cfg.OpenCode.RecordRequests)
+		}
+	case "openai":
+		gen.chat = openai.NewClient(cfg.OpenAI.BaseURL, cfg.OpenAI.APIKey, cfg.OpenCode.Timeout)
+	default:
+		gen.runner = opencode.NewRunnerWithBinary(cfg.OpenCode.Binary, cfg.OpenCode.Timeout)
 	}
 
 	return gen
@@ -55,7 +113,7 @@ func NewGenerator(cfg *config.Config, cacheInstance *cache.SessionCache) *Genera
 /**
  * GetMode returns the current operation mode.
  *
- * @returns "run" or "server"
+ * @returns "run", "server", or "openai"
  */
 func (g *Generator) GetMode() string {
 	return g.mode
@@ -71,38 +129,874 @@ func (g *Generator) GetConfig() *config.Config {
 }
 
 /**
- * Generate creates a commit message from staged changes.
+ * SetFull controls whether Generate returns the complete model output
+ * (subject, body, and footers) instead of only the subject line.
+ *
+ * @param full - true to keep the full multi-line output
+ */
+func (g *Generator) SetFull(full bool) {
+	g.full = full
+}
+
+/**
+ * SetNoBody restricts the message to the subject line, overriding the
+ * detailed style and generation.body (--no-body).
+ *
+ * @param noBody - true to generate only the subject line
+ */
+func (g *Generator) SetNoBody(noBody bool) {
+	g.noBody = noBody
+}
+
+/**
+ * SetForce allows generation to proceed when the prompt exceeds
+ * generation.max_cost_tokens, printing a warning instead of failing, and
+ * regenerates even when the diff is unchanged since the last message.
+ *
+ * @param force - true to bypass the token budget and the last-message reuse
+ */
+func (g *Generator) SetForce(force bool) {
+	g.force = force
+}
+
+/**
+ * SetAmend makes Generate describe the HEAD commit as it will look after
+ * amending, diffing the index against HEAD's parent. With reuseBody, only the
+ * subject is regenerated and the previous body and trailers are kept verbatim.
+ *
+ * @param amend - true to generate for git commit --amend
+ * @param reuseBody - true to keep the previous commit's body
+ */
+func (g *Generator) SetAmend(amend, reuseBody bool) {
+	g.amend = amend
+	g.reuseBody = amend && reuseBody
+}
+
+/**
+ * SetPaths limits generation to the staged changes of the given paths.
+ *
+ * @param paths - Staged paths to describe; empty includes every staged file
+ */
+func (g *Generator) SetPaths(paths []string) {
+	g.paths = paths
+}
+
+/**
+ * SetDiff makes generation describe diff instead of reading the staged
+ * changes from git. The size limit and summarization still apply.
+ *
+ * @param diff - A unified diff, e.g. read from a file or stdin
+ */
+func (g *Generator) SetDiff(diff string) {
+	g.providedDiff = &diff
+}
+
+/**
+ * SetContext sets the context used for server requests, so that cancelling
+ * it aborts an in-flight generation.
+ *
+ * @param ctx - The context; nil means context.Background
+ */
+func (g *Generator) SetContext(ctx context.Context) {
+	g.ctx = ctx
+}
+
+/**
+ * requestContext returns the context for server requests.
+ */
+func (g *Generator) requestContext() context.Context {
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
+}
+
+/**
+ * SetStream makes server mode stream the model's reply, calling onChunk with
+ * each piece of raw text as it arrives. Run mode does not stream.
+ *
+ * @param onChunk - Called with each chunk; nil disables streaming
+ */
+func (g *Generator) SetStream(onChunk func(string)) {
+	g.onChunk = onChunk
+}
+
+/**
+ * GenerateResult carries the generated message along with metadata about how it was produced.
+ */
+type GenerateResult struct {
+	Message        string        `json:"message"`
+	IsSummarized   bool          `json:"summarized"`
+	CollapsedFiles []string      `json:"collapsed_files,omitempty"`
+	Mode           string        `json:"mode"`
+	Model          string        `json:"model"`
+	Style          string        `json:"style"`
+	TokenEstimate  int           `json:"token_estimate"`
+	Elapsed        time.Duration `json:"elapsed"`
+	// FilteredLines counts output lines dropped as log noise (run mode only).
+	FilteredLines int `json:"filtered_lines"`
+	// StrippedMarkdown is true when a markdown code fence was removed from the response.
+	StrippedMarkdown bool `json:"stripped_markdown"`
+	// PartialResponses holds truncated server response bodies that were retried (server mode only).
+	PartialResponses []string `json:"partial_responses,omitempty"`
+	// DownsizedTo is the reduced diff size in bytes after a context-length error, or zero.
+	DownsizedTo int `json:"downsized_to,omitempty"`
+	// Cached is true when the input was unchanged since the last generation and its message was reused.
+	Cached bool `json:"cached"`
+	// Candidates holds the alternative messages when generation.candidates is above one; Message is the first.
+	Candidates []string `json:"candidates,omitempty"`
+	// Usage is the token usage reported by the backend, or nil if it was not reported.
+	Usage *Usage `json:"usage,omitempty"`
+	// OriginalDiffSize is the diff size in bytes before any summarizing or collapsing.
+	OriginalDiffSize int `json:"original_diff_size"`
+	// Files lists the changed paths the message describes.
+	Files []string `json:"files_changed"`
+	// SplitFiles is how many files were described separately and then combined
+	// (generation.on_summarized: split), or zero.
+	SplitFiles int `json:"split_files,omitempty"`
+}
+
+/**
+ * MessageGenerator produces a commit message. *Generator, backed by OpenCode,
+ * is the default implementation; other backends and test fakes can be
+ * substituted wherever only a message is needed.
+ */
+type MessageGenerator interface {
+	Generate() (string, error)
+}
+
+/**
+ * ResultGenerator is a MessageGenerator that also reports how the message
+ * was produced, including any candidates.
+ */
+type ResultGenerator interface {
+	MessageGenerator
+	GenerateResult() (*GenerateResult, error)
+}
+
+var _ ResultGenerator = (*Generator)(nil)
+
+/**
+ * Generate creates a commit message from staged changes. Callers that need
+ * the diff handling or backend details use GenerateResult instead.
  *
  * @returns The generated commit message
  * @returns An error if generation fails
  */
 func (g *Generator) Generate() (string, error) {
-	maxSize := g.config.Git.MaxDiffSize
-	if maxSize <= 0 {
-		maxSize = git.DefaultMaxDiffSize
+	result, err := g.GenerateResult()
+	if err != nil {
+		return "", err
+	}
+	return result.Message, nil
+}
+
+/**
+ * GenerateResult creates a commit message from staged changes and reports
+ * the diff handling (summarizing, original size, changed files), backend,
+ * model, style, token estimate, and elapsed time, in every mode.
+ *
+ * @returns The generation result
+ * @returns An error if generation fails
+ */
+func (g *Generator) GenerateResult() (*GenerateResult, error) {
+	start := time.Now()
+	g.usage = usageTally{}
+
+	defer g.applyOverrides()()
+
+	if err := g.checkProviderAllowed(); err != nil {
+		return nil, err
+	}
+
+	if err := validateCustomStyles(g.config.Generation.CustomStyles); err != nil {
+		return nil, err
 	}
 
-	diffResult, err := git.GetStagedDiffWithLimit(maxSize)
+	ticketPattern, err := g.ticketPattern()
 	if err != nil {
-		return "", fmt.Errorf("failed to get git diff: %w", err)
+		return nil, err
+	}
+
+	diffResult, prompt, err := g.preparePrompt()
+	if err != nil {
+		return nil, err
+	}
+
+	inputHash := g.inputHash(prompt)
+	// Only the chosen message is cached, so asking for candidates always generates.
+	if !g.force && g.config.Generation.Candidates <= 1 {
+		if message, ok := g.cache.GetLastMessage(inputHash); ok {
+			return &GenerateResult{
+				Message:       message,
+				IsSummarized:  diffResult.IsSummarized,
+				Mode:          g.mode,
+				Model:         g.modelName(),
+				Style:         g.config.Generation.Style,
+				TokenEstimate: EstimateTokens(prompt),
+				Elapsed:       time.Since(start),
+				Cached:        true,
+
+				OriginalDiffSize: diffResult.OriginalSize,
+				Files:            diffResult.Files,
+			}, nil
+		}
+	}
+
+	if err := g.checkBudget(prompt); err != nil {
+		return nil, err
+	}
+
+	splitFiles := 0
+	if g.config.Generation.OnSummarized == "split" {
+		synthesis, files, ok, err := g.splitPrompt(diffResult)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			prompt, splitFiles = synthesis, files
+			diffResult.IsSummarized = false
+		}
+	}
+
+	candidates := g.config.Generation.Candidates
+	defer func() { g.diffLimit = 0 }()
+	response, err := g.send(prompt + candidatesNote(candidates))
+	downsizedTo := 0
+	for attempt := 0; attempt < maxContextRetries && isContextLengthError(err); attempt++ {
+		limit := min(g.maxDiffSize(), diffResult.OriginalSize) / 2
+		if limit <= 0 {
+			break
+		}
+		g.diffLimit = limit
+		fmt.Fprintf(os.Stderr, "Prompt exceeded the model's context length; retrying with the diff reduced to %d bytes\n", limit)
+
+		diffResult, prompt, err = g.preparePrompt()
+		if err != nil {
+			return nil, err
+		}
+		downsizedTo = limit
+		splitFiles = 0
+		response, err = g.send(prompt + candidatesNote(candidates))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	chain := NewFormatterChain(g.config, g.wantsBody() && !g.reuseBody)
+	if g.reuseBody {
+		previous, err := git.GetLastCommitMessage()
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, reuseBodyFormatter{previous: previous})
+	}
+	if g.config.Generation.ForceInferredScope && g.scope != "" {
+		chain = append(chain, scopeFormatter{scope: g.scope, override: g.config.Generation.OverrideModelScope})
+	}
+	if ticketPattern != nil {
+		branch, _ := git.GetBranchName()
+		chain = append(chain, ticketFormatter{
+			ticket:   extractTicket(branch, ticketPattern),
+			position: g.config.Generation.TicketPosition,
+		})
+	}
+	if g.config.Generation.IssueFooter {
+		branch, _ := git.GetBranchName()
+		chain = append(chain, issueFooterFormatter{
+			issue:   extractIssueNumber(branch),
+			keyword: g.config.Generation.IssueKeyword,
+		})
+	}
+
+	if len(g.breaking) > 0 {
+		chain = append(chain, breakingFormatter{changes: g.breaking})
+	}
+
+	if len(g.config.Generation.CoAuthors) > 0 {
+		chain = append(chain, coAuthorFormatter{authors: g.config.Generation.CoAuthors})
+	}
+	if g.config.Generation.Signoff {
+		identity, err := git.GetUserIdentity()
+		if err != nil {
+			return nil, fmt.Errorf("cannot sign off: %w", err)
+		}
+		chain = append(chain, signoffFormatter{identity: identity})
+	}
+
+	filtered := 0
+	if g.runner != nil {
+		filtered = g.runner.FilteredLines()
+	}
+	var partials []string
+	if g.client != nil {
+		partials = g.client.PartialResponses()
+	}
+
+	responses := []string{response}
+	if candidates > 1 {
+		if found := extractCandidates(response, candidates); found != nil {
+			responses = found
+		}
+	}
+
+	var messages []string
+	for _, response := range responses {
+		message, err := chain.Format(response)
+		if err != nil {
+			if len(responses) > 1 {
+				continue
+			}
+			if errors.Is(err, ErrEmptyMessage) && filtered > 0 {
+				return nil, fmt.Errorf("%w (%d output lines were filtered as noise; rerun with --verbose)", err, filtered)
+			}
+			return nil, err
+		}
+
+		message, err = g.enforceSubjectLength(message, prompt, chain)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("%w; try regenerating or using a different model", ErrEmptyMessage)
+	}
+	message := messages[0]
+	if len(messages) == 1 {
+		messages = nil
+	}
+
+	// The last message is cached per repository; a provided diff may have none.
+	if git.IsGitRepository() {
+		if err := g.cache.SetLastMessage(inputHash, message); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to cache generated message: %v\n", err)
+		}
+	}
+
+	return &GenerateResult{
+		Message:        message,
+		IsSummarized:   diffResult.IsSummarized,
+		CollapsedFiles: diffResult.CollapsedFiles,
+		Mode:           g.mode,
+		Model:          g.modelName(),
+		Style:          g.config.Generation.Style,
+		TokenEstimate:  EstimateTokens(prompt),
+		Elapsed:        time.Since(start),
+
+		FilteredLines:    filtered,
+		StrippedMarkdown: strings.Contains(response, "```"),
+		PartialResponses: partials,
+		DownsizedTo:      downsizedTo,
+		Candidates:       messages,
+		Usage:            g.usage.result(),
+		OriginalDiffSize: diffResult.OriginalSize,
+		Files:            diffResult.Files,
+		SplitFiles:       splitFiles,
+	}, nil
+}
+
+/**
+ * enforceSubjectLength checks the subject against generation.max_subject_length.
+ * A subject that is too long is either truncated at a word boundary or, with
+ * generation.on_too_long set to "reprompt", regenerated once with a request
+ * for a shorter line and truncated if it is still too long.
+ *
+ * @param message - The formatted commit message
+ * @param prompt - The prompt the message was generated from
+ * @param chain - The formatter chain applied to a regenerated response
+ * @returns The message with a subject within the limit
+ * @returns An error if regeneration fails
+ */
+func (g *Generator) enforceSubjectLength(message, prompt string, chain Chain) (string, error) {
+	limit := g.config.Generation.MaxSubjectLength
+	subject, rest, _ := strings.Cut(message, "\n")
+	if limit <= 0 || utf8.RuneCountInString(subject) <= limit {
+		return message, nil
+	}
+
+	if g.config.Generation.OnTooLong == "reprompt" {
+		retry := fmt.Sprintf("%s\n\nYour previous subject line was %d characters long:\n%s\nWrite the commit message again with a subject of at most %d characters.",
+			prompt, utf8.RuneCountInString(subject), subject, limit)
+		response, err := g.send(retry)
+		if err != nil {
+			return "", err
+		}
+		if message, err = chain.Format(response); err != nil {
+			return "", err
+		}
+		subject, rest, _ = strings.Cut(message, "\n")
+	}
+
+	subject = truncateSubject(subject, limit)
+	if rest == "" {
+		return subject, nil
+	}
+	return subject + "\n" + rest, nil
+}
+
+/**
+ * truncateSubject shortens a subject to at most limit characters, cutting at
+ * the last word boundary when there is one. It counts runes, so multi-byte
+ * characters are never split.
+ *
+ * @param subject - The subject line
+ * @param limit - The maximum length in characters
+ * @returns The truncated subject
+ */
+func truncateSubject(subject string, limit int) string {
+	runes := []rune(subject)
+	if len(runes) <= limit {
+		return subject
+	}
+
+	cut := string(runes[:limit])
+	if !unicode.IsSpace(runes[limit]) {
+		// Only back up to a word boundary if that keeps most of the line;
+		// scripts without spaces are cut at the limit instead.
+		if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 && utf8.RuneCountInString(cut[:i]) >= limit/2 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(",;-", r)
+	})
+}
+
+/**
+ * applyOverrides switches to the first generation.overrides rule matching
+ * most changed files: those of the diff passed to SetDiff, otherwise those of
+ * the diff git produces for the request (the working tree when staged_only is
+ * off, HEAD's changes too when amending).
+ *
+ * @returns A function restoring the global configuration
+ */
+func (g *Generator) applyOverrides() func() {
+	if len(g.config.Generation.Overrides) == 0 {
+		return func() {}
+	}
+
+	files, err := g.changedFiles()
+	if err != nil {
+		return func() {}
+	}
+
+	rule := matchOverride(g.config.Generation.Overrides, files)
+	if rule == nil {
+		return func() {}
+	}
+
+	base := g.config
+	g.config = withOverride(base, rule)
+	return func() { g.config = base }
+}
+
+// changedFiles lists the files of the diff being described: the diff passed
+// to SetDiff, otherwise the files git diffs with the resolved options.
+func (g *Generator) changedFiles() ([]string, error) {
+	if g.providedDiff != nil {
+		return git.ProvidedDiffFiles(*g.providedDiff), nil
+	}
+	opts, err := g.resolvedDiffOptions()
+	if err != nil {
+		return nil, err
+	}
+	return git.GetChangedFilesWithOptions(opts)
+}
+
+/**
+ * inputHash identifies a generation request by its prompt, model, and output
+ * options, so an unchanged diff (e.g. a hook firing twice) can reuse the last message.
+ */
+func (g *Generator) inputHash(prompt string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%t\x00%t\x00%t\x00%t\x00%s\x00%d\x00%s\x00%t\x00", g.modelName(), g.full, g.amend, g.reuseBody, g.config.Generation.Signoff, strings.Join(g.config.Generation.CoAuthors, "\x00"), g.config.Generation.Candidates, g.config.Generation.OnSummarized, g.config.Generation.DetectBreaking)
+	h.Write([]byte(prompt))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// maxDiffSize returns the diff size limit: a reduced limit after a
+// context-length error, otherwise git.max_diff_size or the default.
+func (g *Generator) maxDiffSize() int {
+	if g.diffLimit > 0 {
+		return g.diffLimit
+	}
+	if g.config.Git.MaxDiffSize <= 0 {
+		return git.DefaultMaxDiffSize
+	}
+	return g.config.Git.MaxDiffSize
+}
+
+/**
+ * preparePrompt loads the diff and assembles the prompt with all notes
+ * derived from it and the repository state.
+ *
+ * @returns The diff result, the prompt, and an error if the diff is unavailable or empty
+ */
+func (g *Generator) preparePrompt() (*git.DiffResult, string, error) {
+	diffResult, err := g.loadDiff()
+	if err != nil {
+		return nil, "", err
+	}
+
+	g.binaryOnly = diffResult.BinaryOnly
+
+	g.significance = ""
+	if g.config.Generation.DetectTrivial && !diffResult.IsSummarized {
+		g.significance = git.ClassifyChangeSignificance(diffResult.Diff)
+	}
+
+	g.breaking = nil
+	if g.config.Generation.DetectBreaking {
+		g.breaking = git.DetectBreakingChanges(diffResult.Diff)
+	}
+
+	g.scope = ""
+	if g.config.Generation.Style != "imperative" {
+		g.scope = git.InferScopeFromMetadata(diffResult.Files)
+	}
+
+	g.branch = ""
+	if g.config.Generation.IncludeBranch {
+		g.branch, _ = git.GetBranchName()
+	}
+
+	g.prHeadings = nil
+	if g.config.Generation.UsePRTemplate && g.wantsBody() {
+		if root, err := git.GetRepositoryRoot(); err == nil {
+			g.prHeadings = prTemplateHeadings(root)
+		}
+	}
+
+	return diffResult, g.buildPrompt(diffResult.Diff, diffResult.IsSummarized), nil
+}
+
+/**
+ * loadDiff returns the diff to describe, from SetDiff or from git, limited
+ * to the maximum diff size, and records which files are partially staged.
+ *
+ * @returns The diff result, or an error if the diff is unavailable or empty
+ */
+func (g *Generator) loadDiff() (*git.DiffResult, error) {
+	maxSize := g.maxDiffSize()
+	g.partiallyStaged = nil
+
+	if g.providedDiff != nil {
+		threshold := 0
+		if g.config.Git.CollapseLargeFiles {
+			threshold = g.config.Git.LargeFileThreshold
+		}
+		diffResult, err := git.LimitProvidedDiff(*g.providedDiff, maxSize, threshold)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read provided diff: %w", err)
+		}
+		return diffResult, nil
+	}
+
+	opts, err := g.resolvedDiffOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	diffResult, err := git.GetStagedDiffWithOptions(maxSize, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git diff: %w", err)
 	}
 
 	if strings.TrimSpace(diffResult.Diff) == "" {
-		return "", fmt.Errorf("no staged changes found")
+		if opts.Working {
+			return nil, fmt.Errorf("no changes found")
+		}
+		return nil, fmt.Errorf("no staged changes found")
 	}
 
-	if g.mode == "server" {
-		return g.generateWithServer(diffResult.Diff, diffResult.IsSummarized)
+	if !opts.Working {
+		g.partiallyStaged, err = git.GetPartiallyStagedFiles()
+		if err != nil {
+			g.partiallyStaged = nil
+		}
+	}
+	return diffResult, nil
+}
+
+/**
+ * wantsBody reports whether the generated message includes a body, which is
+ * the case for the detailed style and for --full, unless --no-body was given.
+ */
+func (g *Generator) wantsBody() bool {
+	if g.noBody {
+		return false
 	}
-	return g.generateWithRunner(diffResult.Diff, diffResult.IsSummarized)
+	return g.full || g.config.Generation.Body || g.config.Generation.Style == "detailed"
 }
 
-func (g *Generator) generateWithRunner(diff string, isSummarized bool) (string, error) {
-	prompt := g.buildPrompt(diff, isSummarized)
+/**
+ * modelName returns the configured model as "provider/model".
+ *
+ * @returns The model identifier
+ */
+func (g *Generator) modelName() string {
+	if g.mode == "openai" {
+		return "openai/" + g.openAIModel()
+	}
+	model := g.currentModel()
+	return fmt.Sprintf("%s/%s", model.Provider, model.ModelID)
+}
 
+// currentModel returns the fallback model after a switch, otherwise generation.model.
+func (g *Generator) currentModel() config.ModelConfig {
+	if g.fallback != nil {
+		return *g.fallback
+	}
+	return g.config.Generation.Model
+}
+
+// probePrompt is the canned prompt sent by Probe.
+const probePrompt = "Respond with OK"
+
+/**
+ * ProbeResult describes the outcome of a canned round trip to the backend.
+ */
+type ProbeResult struct {
+	Response string
+	Duration time.Duration
+	OK       bool
+}
+
+/**
+ * Probe sends a tiny fixed prompt through the full generation path (runner or
+ * server) and reports whether the model produced a usable response. Unlike a
+ * health check, this catches authentication and model-name errors.
+ *
+ * @returns The probe result with the raw response and round-trip time
+ * @returns An error if the backend call fails
+ */
+func (g *Generator) Probe() (*ProbeResult, error) {
+	if err := g.checkProviderAllowed(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	response, err := g.send(probePrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProbeResult{
+		Response: strings.TrimSpace(response),
+		Duration: time.Since(start),
+		OK:       strings.Contains(strings.ToUpper(response), "OK"),
+	}, nil
+}
+
+/**
+ * resolvedDiffOptions returns diffOptions with the comparison base filled in
+ * for amending or for working-tree diffs.
+ */
+func (g *Generator) resolvedDiffOptions() (git.DiffOptions, error) {
+	opts := g.diffOptions()
+	if g.amend {
+		base, err := git.GetAmendBase()
+		if err != nil {
+			return opts, err
+		}
+		opts.Base = base
+	}
+	if opts.Working && opts.Base == "" {
+		opts.Base = git.GetWorkingBase()
+	}
+	return opts, nil
+}
+
+/**
+ * diffOptions builds the git diff options from the git configuration.
+ *
+ * @returns The options used to fetch the staged diff
+ */
+func (g *Generator) diffOptions() git.DiffOptions {
+	opts := git.DiffOptions{
+		Relative:  g.config.Git.RelativePaths,
+		Algorithm: g.config.Git.DiffAlgorithm,
+		Working:   !g.config.Git.StagedOnly,
+	}
+	contextLines := g.config.Git.ContextLines
+	opts.ContextLines = &contextLines
+	if g.config.Git.CollapseLargeFiles {
+		opts.CollapseThreshold = g.config.Git.LargeFileThreshold
+	}
+	opts.Paths = g.paths
+	return opts
+}
+
+/**
+ * checkBudget estimates the prompt's token count and enforces generation.max_cost_tokens.
+ * A limit of zero or less disables the check.
+ *
+ * @param prompt - The prompt about to be sent
+ * @returns ErrBudgetExceeded if the estimate is over the limit and force is not set
+ */
+func (g *Generator) checkBudget(prompt string) error {
+	limit := g.config.Generation.MaxCostTokens
+	if limit <= 0 {
+		return nil
+	}
+
+	estimated := EstimateTokens(prompt)
+	if estimated <= limit {
+		return nil
+	}
+
+	if g.force {
+		fmt.Fprintf(os.Stderr, "Warning: prompt is ~%d tokens (%d bytes), over the %d token budget; continuing due to --force\n", estimated, len(prompt), limit)
+		return nil
+	}
+
+	return fmt.Errorf("%w: ~%d tokens (%d bytes) exceeds generation.max_cost_tokens=%d; use --force to proceed", ErrBudgetExceeded, estimated, len(prompt), limit)
+}
+
+/**
+ * checkProviderAllowed verifies the configured provider against generation.allowed_providers.
+ * An empty allowlist permits every provider.
+ *
+ * @returns ErrProviderNotAllowed if the provider is not in the allowlist
+ */
+func (g *Generator) checkProviderAllowed() error {
+	return g.providerAllowed(g.config.Generation.Model.Provider)
+}
+
+// providerAllowed checks one provider against generation.allowed_providers.
+func (g *Generator) providerAllowed(provider string) error {
+	allowed := g.config.Generation.AllowedProviders
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	for _, p := range allowed {
+		if strings.EqualFold(strings.TrimSpace(p), provider) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q (allowed: %s)", ErrProviderNotAllowed, provider, strings.Join(allowed, ", "))
+}
+
+/**
+ * validateCustomStyles rejects generation.custom_styles entries that reuse a
+ * built-in style name or have an empty guide.
+ *
+ * @param custom - Custom style guides by name
+ * @returns ErrInvalidConfig describing the first invalid entry
+ */
+func validateCustomStyles(custom map[string]string) error {
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, builtin := range config.BuiltinStyles {
+			if strings.EqualFold(name, builtin) {
+				return fmt.Errorf("%w: generation.custom_styles %q collides with a built-in style", ErrInvalidConfig, name)
+			}
+		}
+		if strings.TrimSpace(custom[name]) == "" {
+			return fmt.Errorf("%w: generation.custom_styles %q has an empty guide", ErrInvalidConfig, name)
+		}
+	}
+	return nil
+}
+
+/**
+ * ticketPattern compiles generation.ticket_pattern and checks generation.ticket_position.
+ *
+ * @returns The compiled pattern, or nil if no pattern is configured
+ * @returns ErrInvalidConfig if the pattern or position is invalid
+ */
+func (g *Generator) ticketPattern() (*regexp.Regexp, error) {
+	pattern := g.config.Generation.TicketPattern
+	if pattern == "" {
+		return nil, nil
+	}
+
+	switch g.config.Generation.TicketPosition {
+	case "", "trailer", "scope":
+	default:
+		return nil, fmt.Errorf("%w: generation.ticket_position %q (expected trailer or scope)", ErrInvalidConfig, g.config.Generation.TicketPosition)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: generation.ticket_pattern %q: %v", ErrInvalidConfig, pattern, err)
+	}
+	return re, nil
+}
+
+/**
+ * send delivers the prompt through the configured backend, switching to
+ * generation.fallback_model once if the model is unavailable.
+ *
+ * @param prompt - The prompt to send
+ * @returns The raw model response
+ */
+func (g *Generator) send(prompt string) (string, error) {
+	response, err := g.sendToModel(prompt)
+	if err != nil && g.switchToFallback(err) {
+		response, err = g.sendToModel(prompt)
+	}
+	if err == nil {
+		g.usage.add(g.lastUsage())
+	}
+	return response, err
+}
+
+// sendToModel sends prompt to the current model through the mode's backend.
+func (g *Generator) sendToModel(prompt string) (string, error) {
+	switch g.mode {
+	case "server":
+		return g.generateWithServer(prompt)
+	case "openai":
+		return g.generateWithOpenAI(prompt)
+	default:
+		return g.generateWithRunner(prompt)
+	}
+}
+
+// maxContextRetries is how many times generation is retried with a halved diff after a context-length error.
+const maxContextRetries = 2
+
+// contextLengthMarkers are lower-cased fragments of provider errors for prompts that exceed the context window.
+var contextLengthMarkers = []string{
+	"context length",
+	"context_length_exceeded",
+	"context window",
+	"maximum context",
+	"too many tokens",
+	"prompt is too long",
+	"input is too long",
+}
+
+/**
+ * isContextLengthError reports whether err says the prompt exceeded the model's context window.
+ */
+func isContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Generator) generateWithRunner(prompt string) (string, error) {
 	model := &opencode.Model{
-		ProviderID: g.config.Generation.Model.Provider,
-		ModelID:    g.config.Generation.Model.ModelID,
+		ProviderID: g.currentModel().Provider,
+		ModelID:    g.currentModel().ModelID,
 	}
 
 	response, err := g.runner.Generate(prompt, model)
@@ -110,15 +1004,39 @@ func (g *Generator) generateWithRunner(diff string, isSummarized bool) (string,
 		return "", fmt.Errorf("failed to generate commit message: %w", err)
 	}
 
-	message := extractCommitMessage(response)
-	return message, nil
+	return response, nil
 }
 
-func (g *Generator) generateWithServer(diff string, isSummarized bool) (string, error) {
-	healthy, err := g.client.CheckHealth()
-	if err != nil || !healthy {
-		fmt.Printf("%v at %s:%d", ErrServerNotRunning, g.config.OpenCode.Host, g.config.OpenCode.Port)
-		return "", fmt.Errorf("failed to start opencode server: %w", err)
+func (g *Generator) generateWithOpenAI(prompt string) (string, error) {
+	response, err := g.chat.Complete(g.requestContext(), g.openAIModel(), prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	return response, nil
+}
+
+/**
+ * openAIModel returns the fallback model after a switch, otherwise
+ * openai.model, falling back to generation.model.model_id.
+ */
+func (g *Generator) openAIModel() string {
+	if g.fallback != nil {
+		return g.fallback.ModelID
+	}
+	if g.config.OpenAI.Model != "" {
+		return g.config.OpenAI.Model
+	}
+	return g.config.Generation.Model.ModelID
+}
+
+func (g *Generator) generateWithServer(prompt string) (string, error) {
+	healthy, err := g.client.CheckHealthCtx(g.requestContext())
+	if err != nil {
+		return "", fmt.Errorf("%w at %s:%d: %v", ErrServerNotRunning, g.config.OpenCode.Host, g.config.OpenCode.Port, err)
+	}
+	if !healthy {
+		return "", fmt.Errorf("%w at %s:%d: server reported unhealthy", ErrServerNotRunning, g.config.OpenCode.Host, g.config.OpenCode.Port)
 	}
 
 	var sessionID string
@@ -131,7 +1049,7 @@ func (g *Generator) generateWithServer(diff string, isSummarized bool) (string,
 			repoName = "project"
 		}
 
-		session, err := g.client.CreateSession(fmt.Sprintf("commit-gen: %s", repoName))
+		session, err := g.client.CreateSessionCtx(g.requestContext(), fmt.Sprintf("commit-gen: %s", repoName))
 		if err != nil {
 			return "", fmt.Errorf("failed to create OpenCode session: %w", err)
 		}
@@ -146,20 +1064,22 @@ func (g *Generator) generateWithServer(diff string, isSummarized bool) (string,
 		fmt.Printf("Warning: failed to update last used: %v\n", err)
 	}
 
-	prompt := g.buildPrompt(diff, isSummarized)
-
 	model := &opencode.Model{
-		ProviderID: g.config.Generation.Model.Provider,
-		ModelID:    g.config.Generation.Model.ModelID,
+		ProviderID: g.currentModel().Provider,
+		ModelID:    g.currentModel().ModelID,
 	}
 
-	response, err := g.client.SendMessage(sessionID, prompt, model)
+	var response string
+	if g.onChunk != nil {
+		response, err = g.client.SendMessageStreamCtx(g.requestContext(), sessionID, prompt, model, g.onChunk)
+	} else {
+		response, err = g.client.SendMessageCtx(g.requestContext(), sessionID, prompt, model)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to generate commit message: %w", err)
 	}
 
-	message := extractCommitMessage(response)
-	return message, nil
+	return response, nil
 }
 
 /**
@@ -171,7 +1091,7 @@ func (g *Generator) generateWithServer(diff string, isSummarized bool) (string,
  */
 func (g *Generator) buildPrompt(diff string, isSummarized bool) string {
 	style := g.config.Generation.Style
-	styleGuide := getStyleGuide(style)
+	styleGuide := getStyleGuide(style, g.config.Generation.CustomStyles)
 
 	var summarizedNote string
 	if isSummarized {
@@ -184,23 +1104,152 @@ Focus on the file list, diff stat, and available code changes to understand the
 	prompt := fmt.Sprintf(`You are a git commit message generator. Your task is to generate a concise, meaningful commit message based on the following code changes.
 
 %s
-%s
+%s%s%s%s%s%s%s%s%s
 Generate ONLY the commit message, nothing else. No explanation, no markdown formatting, just the message.
 
 Here are the staged changes:
 
-%s`, styleGuide, summarizedNote, diff)
+%s`, styleGuide, summarizedNote, partialStagingNote(g.partiallyStaged), binaryNote(g.binaryOnly), significanceNote(g.significance), breakingNote(g.breaking), prTemplateNote(g.prHeadings), bodyNote(g.config.Generation.Body, g.noBody), scopeNote(g.scope), branchNote(g.branch), diff)
 
 	return prompt
 }
 
+/**
+ * partialStagingNote tells the model which files are only partially staged,
+ * so it describes the staged hunks rather than the whole file.
+ *
+ * @param files - Files with both staged and unstaged changes
+ * @returns The prompt note, or empty string if every file is fully staged
+ */
+func partialStagingNote(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\nNOTE: The following files are only partially staged. Describe only the staged hunks shown in the diff, not other changes to these files:\n")
+	for _, f := range files {
+		sb.WriteString("- " + f + "\n")
+	}
+	return sb.String()
+}
+
+/**
+ * binaryNote tells the model that only binary files changed, so it describes
+ * what the files are rather than their (absent) contents.
+ *
+ * @param binaryOnly - Whether every changed file is binary
+ * @returns The prompt note, or empty string if any text file changed
+ */
+func binaryNote(binaryOnly bool) string {
+	if !binaryOnly {
+		return ""
+	}
+	return "\nNOTE: Only binary files changed (e.g. images, fonts or other assets). Describe what the files are from their paths, e.g. \"chore: add image assets\".\n"
+}
+
+/**
+ * branchNote gives the current branch name as context, since it often
+ * encodes a ticket id or the feature being worked on.
+ *
+ * @param branch - The current branch name
+ * @returns The prompt note, or empty string on a detached HEAD
+ */
+func branchNote(branch string) string {
+	if branch == "" {
+		return ""
+	}
+	return fmt.Sprintf("\nCurrent branch: %s (use it as context, e.g. for a ticket id)\n", branch)
+}
+
+/**
+ * scopeNote suggests the scope inferred from package metadata or, failing
+ * that, from the directory shared by the changed files.
+ *
+ * @param scope - The inferred scope
+ * @returns The prompt note, or empty string if no scope was inferred
+ */
+func scopeNote(scope string) string {
+	if scope == "" {
+		return ""
+	}
+	return fmt.Sprintf("\nSuggested scope (from package metadata or the changed directory): %s\n", scope)
+}
+
+/**
+ * bodyNote asks for a body explaining the change when generation.body is set,
+ * or for the subject line alone when --no-body was given.
+ *
+ * @param body - Whether a body was requested
+ * @param noBody - Whether the body was explicitly turned off
+ * @returns The prompt note, or empty string when neither applies
+ */
+func bodyNote(body, noBody bool) string {
+	if noBody {
+		return "\nWrite only the subject line, with no body, even if the style suggests one.\n"
+	}
+	if !body {
+		return ""
+	}
+	return "\nAfter the subject line, add a blank line and a short body (wrapped at 72 characters) explaining what changed and why.\n"
+}
+
+/**
+ * significanceNote suggests a conservative docs/style message when the diff
+ * only touches comments or whitespace.
+ *
+ * @param significance - The classification of the staged diff
+ * @returns The prompt note, or empty string for logic changes
+ */
+func significanceNote(significance git.ChangeSignificance) string {
+	switch significance {
+	case git.SignificanceComments:
+		return "\nNOTE: These changes only touch comments or whitespace. Use the `docs` type and a conservative message; do not describe behavior changes.\n"
+	case git.SignificanceWhitespace:
+		return "\nNOTE: These changes only touch whitespace or formatting. Use the `style` type and a conservative message; do not describe behavior changes.\n"
+	default:
+		return ""
+	}
+}
+
+/**
+ * breakingNote lists the detected API-breaking changes and asks for the
+ * conventional "!" marker and "BREAKING CHANGE:" footer.
+ *
+ * @param changes - The breaking changes found in the diff
+ * @returns The prompt note, or empty string if none were found
+ */
+func breakingNote(changes []string) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\nNOTE: These changes break the public API:\n")
+	for _, change := range changes {
+		sb.WriteString("- " + change + "\n")
+	}
+	sb.WriteString("Add \"!\" after the type/scope (e.g. \"feat(api)!: ...\") and end the message with a \"BREAKING CHANGE: <description>\" footer.\n")
+	return sb.String()
+}
+
 /**
  * getStyleGuide returns the prompt instructions for the specified style.
+ * Styles defined in generation.custom_styles are used verbatim and take
+ * precedence over the built-ins.
  *
- * @param style - The commit style (conventional, imperative, detailed)
+ * @param style - The commit style (conventional, imperative, detailed, gitmoji, or a custom style)
+ * @param custom - Custom style guides by name from generation.custom_styles
  * @returns The style guide instructions
  */
-func getStyleGuide(style string) string {
+func getStyleGuide(style string, custom map[string]string) string {
+	// viper lowercases map keys, so also look the style up in lower case.
+	for _, name := range []string{style, strings.ToLower(style)} {
+		if guide, ok := custom[name]; ok && strings.TrimSpace(guide) != "" {
+			return strings.TrimSpace(guide)
+		}
+	}
+
 	switch style {
 	case "imperative":
 		return `Follow the imperative mood style:
@@ -213,22 +1262,106 @@ func getStyleGuide(style string) string {
 		return `Use a detailed style with scope:
 - Format: type(scope): description
 - Scope should be short. dont write the whole file name. make it short and clear
-- Types: feat, fix, docs, style, refactor, perf, test, chore
+- Types: ` + strings.Join(conventionalTypes, ", ") + `
 - Include a brief description in the body if needed
 - Example: "feat(auth): add user authentication to login page
 - Example if long filenames(eg. client_domain_person_check): "feat(domain): add user authentication to login page"`
 
+	case "gitmoji":
+		return `Follow the gitmoji style:
+- Format: <emoji> type(scope): description
+- Start the subject with the single gitmoji that best fits the change:
+  ✨ new feature, 🐛 bug fix, ♻️ refactor, 📝 docs, 🎨 code style or structure,
+  ⚡️ performance, ✅ tests, 🔧 configuration, ⬆️ dependency upgrade, 🔥 removal
+- Types: ` + strings.Join(conventionalTypes, ", ") + `
+- Keep the whole subject under 72 characters
+- Example: "✨ feat(auth): add user authentication"
+- Example: "🐛 fix(api): handle empty response body"`
+
 	default:
 		return `Follow the Conventional Commits style:
 - Format: type(scope): description
 - Scope should be short. dont write the whole file name. make it short and clear
-- Types: feat, fix, docs, style, refactor, perf, test, chore
+- Types: ` + strings.Join(conventionalTypes, ", ") + `
 - Keep the description under 72 characters
 - Example: "feat(auth): add user authentication
 - Example if long filenames(eg. client_domain_person_check): "feat(domain): add user authentication to login page"`
 	}
 }
 
+/**
+ * stripLeadIn removes conversational lead-ins such as "Here is the commit message:"
+ * from the start of the response. Prefixes match case-insensitively at the start of
+ * the first non-empty line; a line left empty after stripping is dropped entirely.
+ *
+ * @param response - The raw AI response
+ * @param prefixes - The lead-in phrases to remove
+ * @returns The response without leading lead-in phrases
+ */
+func stripLeadIn(response string, prefixes []string) string {
+	if len(prefixes) == 0 {
+		return response
+	}
+
+	sorted := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	lines := strings.Split(strings.TrimSpace(response), "\n")
+	for len(lines) > 0 {
+		line, stripped := stripLinePrefixes(lines[0], sorted)
+		if !stripped {
+			break
+		}
+		if line != "" {
+			lines[0] = line
+			break
+		}
+		lines = lines[1:]
+		for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+			lines = lines[1:]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+/**
+ * stripLinePrefixes repeatedly removes matching prefixes from the start of a line.
+ * A prefix only matches on a word boundary.
+ *
+ * @returns The remaining text and whether any prefix was removed
+ */
+func stripLinePrefixes(line string, prefixes []string) (string, bool) {
+	const separators = " \t:,.!-*"
+
+	stripped := false
+	line = strings.ReplaceAll(strings.TrimSpace(line), "’", "'")
+	for {
+		matched := false
+		for _, prefix := range prefixes {
+			if len(line) < len(prefix) || !strings.EqualFold(line[:len(prefix)], prefix) {
+				continue
+			}
+			rest := line[len(prefix):]
+			if rest != "" && !strings.ContainsRune(separators, rune(rest[0])) {
+				continue
+			}
+			line = strings.TrimLeft(rest, separators)
+			matched = true
+			stripped = true
+			break
+		}
+		if !matched {
+			return line, stripped
+		}
+	}
+}
+
 /**
  * extractCommitMessage extracts the clean commit message from AI response.
  *
@@ -236,6 +1369,35 @@ func getStyleGuide(style string) string {
  * @returns The cleaned commit message (first line only)
  */
 func extractCommitMessage(response string) string {
+	lines := strings.Split(stripCodeFence(response), "\n")
+	message := strings.TrimSpace(lines[0])
+
+	return message
+}
+
+/**
+ * extractFullMessage extracts the complete commit message (subject, body, and
+ * footers) from the AI response, removing only markdown fences and surrounding whitespace.
+ *
+ * @param response - The raw AI response
+ * @returns The cleaned multi-line commit message
+ */
+func extractFullMessage(response string) string {
+	lines := strings.Split(stripCodeFence(response), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+/**
+ * stripCodeFence trims the response and removes a surrounding markdown code fence.
+ *
+ * @param response - The raw AI response
+ * @returns The response without the code fence
+ */
+func stripCodeFence(response string) string {
 	response = strings.TrimSpace(response)
 
 	if strings.HasPrefix(response, "```") {
@@ -249,8 +1411,66 @@ func extractCommitMessage(response string) string {
 		response = before
 	}
 
-	lines := strings.Split(response, "\n")
-	message := strings.TrimSpace(lines[0])
+	return response
+}
 
-	return message
+// typePrefixPattern matches a conventional "type(scope)!:" prefix, optionally
+// after a leading gitmoji such as "✨ feat(auth):".
+var typePrefixPattern = regexp.MustCompile(`^(?:[^\x00-\x7F]\S* )?([A-Za-z]+)(\([^)]*\))?(!)?:`)
+
+/**
+ * applyTypeMap replaces the conventional commit type of the message subject
+ * using the given mapping. Types without a mapping are left unchanged.
+ *
+ * @param message - The commit message
+ * @param typeMap - Mapping from generated type to displayed type
+ * @returns The message with its type rewritten
+ */
+func applyTypeMap(message string, typeMap map[string]string) string {
+	if len(typeMap) == 0 {
+		return message
+	}
+
+	match := typePrefixPattern.FindStringSubmatchIndex(message)
+	if match == nil {
+		return message
+	}
+
+	commitType := message[match[2]:match[3]]
+	replacement, ok := typeMap[strings.ToLower(commitType)]
+	if !ok || replacement == "" {
+		return message
+	}
+
+	return message[:match[2]] + replacement + message[match[3]:]
+}
+
+/**
+ * applyScope rewrites a conventional subject such as "feat: x" to
+ * "feat(scope): x". A scope the model already chose is kept unless override
+ * is set.
+ *
+ * @param message - The commit message
+ * @param scope - The inferred scope
+ * @param override - true to replace a scope already present in the subject
+ * @returns The message with the scope applied
+ */
+func applyScope(message, scope string, override bool) string {
+	if scope == "" {
+		return message
+	}
+
+	match := typePrefixPattern.FindStringSubmatchIndex(message)
+	if match == nil {
+		return message
+	}
+	if match[4] >= 0 && !override {
+		return message
+	}
+
+	rest := match[3]
+	if match[4] >= 0 {
+		rest = match[5]
+	}
+	return message[:match[3]] + "(" + scope + ")" + message[rest:]
 }
@@ -2,11 +2,27 @@
 package generator
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/avgt93/commit-gen/internal/cache"
 	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/avgt93/commit-gen/internal/git"
+	"github.com/avgt93/commit-gen/internal/git/gittest"
+	"github.com/avgt93/commit-gen/internal/llm/openai/openaitest"
+	"github.com/avgt93/commit-gen/internal/opencode"
 )
 
 func TestGeneratorCreation(t *testing.T) {
@@ -40,7 +56,7 @@ func TestGeneratorCreation(t *testing.T) {
 }
 
 func TestStyleGuideConventional(t *testing.T) {
-	guide := getStyleGuide("conventional")
+	guide := getStyleGuide("conventional", nil)
 
 	if guide == "" {
 		t.Error("Style guide is empty")
@@ -57,7 +73,7 @@ func TestStyleGuideConventional(t *testing.T) {
 }
 
 func TestStyleGuideImperative(t *testing.T) {
-	guide := getStyleGuide("imperative")
+	guide := getStyleGuide("imperative", nil)
 
 	if guide == "" {
 		t.Error("Style guide is empty")
@@ -74,7 +90,7 @@ func TestStyleGuideImperative(t *testing.T) {
 }
 
 func TestStyleGuideDetailed(t *testing.T) {
-	guide := getStyleGuide("detailed")
+	guide := getStyleGuide("detailed", nil)
 
 	if guide == "" {
 		t.Error("Style guide is empty")
@@ -90,8 +106,27 @@ func TestStyleGuideDetailed(t *testing.T) {
 	t.Logf("✓ Detailed style guide contains expected content")
 }
 
+func TestStyleGuideGitmoji(t *testing.T) {
+	guide := getStyleGuide("gitmoji", nil)
+
+	for _, keyword := range []string{"gitmoji", "✨", "🐛", "♻️"} {
+		if !contains(guide, keyword) {
+			t.Errorf("Style guide missing keyword: %s", keyword)
+		}
+	}
+
+	if result := extractCommitMessage("✨ feat(auth): add login\n\nbody"); result != "✨ feat(auth): add login" {
+		t.Errorf("Leading emoji should be kept, got %q", result)
+	}
+	if result := extractCommitMessage("```\n♻️ refactor: split parser\n```"); result != "♻️ refactor: split parser" {
+		t.Errorf("Leading emoji should be kept after fence removal, got %q", result)
+	}
+
+	t.Logf("✓ Gitmoji style guide contains expected content")
+}
+
 func TestStyleGuideUnknown(t *testing.T) {
-	guide := getStyleGuide("unknown-style")
+	guide := getStyleGuide("unknown-style", nil)
 
 	if guide == "" {
 		t.Error("Style guide is empty for unknown style")
@@ -193,10 +228,10 @@ func TestExtractCommitMessageFirstLineOnly(t *testing.T) {
 }
 
 func TestAllCommitStyles(t *testing.T) {
-	styles := []string{"conventional", "imperative", "detailed"}
+	styles := []string{"conventional", "imperative", "detailed", "gitmoji"}
 
 	for _, style := range styles {
-		guide := getStyleGuide(style)
+		guide := getStyleGuide(style, nil)
 		if guide == "" {
 			t.Errorf("Empty guide for style: %s", style)
 		} else {
@@ -288,3 +323,1092 @@ func contains(str, substr string) bool {
 	}
 	return false
 }
+
+// stubServerConfig starts a fake OpenCode server that answers every message
+// with reply and returns a server-mode config pointing at it.
+func stubServerConfig(t *testing.T, reply string) config.Config {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/global/health":
+			_ = json.NewEncoder(w).Encode(opencode.HealthResponse{Healthy: true})
+		case r.URL.Path == "/session":
+			_ = json.NewEncoder(w).Encode(opencode.Session{ID: "session-stub"})
+		default:
+			_ = json.NewEncoder(w).Encode(opencode.Message{
+				Parts: []opencode.MessagePart{{Type: "text", Text: reply}},
+			})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %v", err)
+	}
+	port, _ := strconv.Atoi(serverURL.Port())
+
+	_ = config.Initialize("")
+	cfg := *config.Get()
+	cfg.OpenCode.Mode = "server"
+	cfg.OpenCode.Host = serverURL.Hostname()
+	cfg.OpenCode.Port = port
+	return cfg
+}
+
+func TestGenerateWithServerReportsUnreachableServer(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	serverURL, _ := url.Parse(server.URL)
+	server.Close()
+
+	_ = config.Initialize("")
+	cfg := *config.Get()
+	cfg.OpenCode.Mode = "server"
+	cfg.OpenCode.Host = serverURL.Hostname()
+	cfg.OpenCode.Port, _ = strconv.Atoi(serverURL.Port())
+
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	_, err := gen.generateWithServer("prompt")
+	if !errors.Is(err, ErrServerNotRunning) || strings.Contains(err.Error(), "%!") {
+		t.Errorf("Expected a readable ErrServerNotRunning, got %v", err)
+	}
+}
+
+func TestGenerateRecordsRequests(t *testing.T) {
+	cfg := stubServerConfig(t, "feat: record requests")
+	cfg.OpenCode.RecordRequests = filepath.Join(t.TempDir(), "requests.jsonl")
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	gen := NewGenerator(&cfg, sessionCache)
+	message, err := gen.generateWithServer(gen.buildPrompt("diff --git a/x b/x", false))
+	if err != nil {
+		t.Fatalf("generateWithServer failed: %v", err)
+	}
+	if message != "feat: record requests" {
+		t.Errorf("Unexpected message: %q", message)
+	}
+
+	exchanges, err := opencode.ReadRecording(cfg.OpenCode.RecordRequests)
+	if err != nil {
+		t.Fatalf("ReadRecording failed: %v", err)
+	}
+
+	counts := map[string]int{}
+	for _, exchange := range exchanges {
+		exchangeURL, _ := url.Parse(exchange.URL)
+		switch {
+		case exchangeURL.Path == "/global/health":
+			counts["health"]++
+		case exchangeURL.Path == "/session":
+			counts["session"]++
+		case strings.HasSuffix(exchangeURL.Path, "/message"):
+			counts["message"]++
+		}
+	}
+
+	for _, kind := range []string{"health", "session", "message"} {
+		if counts[kind] != 1 {
+			t.Errorf("Expected exactly one %s request recorded, got %d", kind, counts[kind])
+		}
+	}
+	if len(exchanges) != 3 {
+		t.Errorf("Expected 3 recorded exchanges, got %d", len(exchanges))
+	}
+
+	t.Log("✓ Generation recorded health, session, and message requests")
+}
+
+func TestApplyTypeMap(t *testing.T) {
+	typeMap := map[string]string{"feat": "feature"}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"feat: add login", "feature: add login"},
+		{"feat(auth): add login", "feature(auth): add login"},
+		{"feat(api)!: drop v1", "feature(api)!: drop v1"},
+		{"fix: handle nil", "fix: handle nil"},
+		{"Add login page", "Add login page"},
+		{"✨ feat(auth): add login", "✨ feature(auth): add login"},
+	}
+
+	for _, tt := range tests {
+		result := applyTypeMap(tt.input, typeMap)
+		if result != tt.expected {
+			t.Errorf("Type map mismatch:\n  input: %q\n  got: %q\n  expected: %q", tt.input, result, tt.expected)
+		}
+	}
+
+	if result := applyTypeMap("feat: add login", nil); result != "feat: add login" {
+		t.Errorf("Empty type map should not rewrite: got %q", result)
+	}
+
+	t.Log("✓ Commit type remapped in subject")
+}
+
+func TestApplyScope(t *testing.T) {
+	tests := []struct {
+		input    string
+		override bool
+		expected string
+	}{
+		{"feat: add login", false, "feat(auth): add login"},
+		{"feat!: drop v1", false, "feat(auth)!: drop v1"},
+		{"feat(session): add login", false, "feat(session): add login"},
+		{"feat(session): add login", true, "feat(auth): add login"},
+		{"feat(session)!: drop v1", true, "feat(auth)!: drop v1"},
+		{"feat: add login\n\nBody text.", false, "feat(auth): add login\n\nBody text."},
+		{"Add login page", true, "Add login page"},
+		{"✨ feat: add login", false, "✨ feat(auth): add login"},
+	}
+
+	for _, tt := range tests {
+		result := applyScope(tt.input, "auth", tt.override)
+		if result != tt.expected {
+			t.Errorf("Scope mismatch:\n  input: %q (override=%v)\n  got: %q\n  expected: %q", tt.input, tt.override, result, tt.expected)
+		}
+	}
+
+	if result := applyScope("feat: add login", "", true); result != "feat: add login" {
+		t.Errorf("Empty scope should not rewrite: got %q", result)
+	}
+
+	t.Log("✓ Inferred scope inserted and overridden in subject")
+}
+
+func TestTruncateSubject(t *testing.T) {
+	tests := []struct {
+		input    string
+		limit    int
+		expected string
+	}{
+		{"feat: add login", 72, "feat: add login"},
+		{"feat: add login page with remember me", 20, "feat: add login page"},
+		{"feat: add login page, with remember me", 21, "feat: add login page"},
+		{"feat: supercalifragilistic", 10, "feat:"},
+		{"feat:supercalifragilistic", 10, "feat:super"},
+		{"fix: handle naïve café résumé parsing", 26, "fix: handle naïve café"},
+		{"docs: 日本語のドキュメントを更新する", 12, "docs: 日本語のドキ"},
+	}
+
+	for _, tt := range tests {
+		result := truncateSubject(tt.input, tt.limit)
+		if result != tt.expected {
+			t.Errorf("truncateSubject(%q, %d): got %q, expected %q", tt.input, tt.limit, result, tt.expected)
+		}
+		if !utf8.ValidString(result) {
+			t.Errorf("truncateSubject(%q, %d) split a rune: %q", tt.input, tt.limit, result)
+		}
+	}
+
+	t.Log("✓ Subjects truncated at word boundaries without splitting runes")
+}
+
+func TestEnforceSubjectLength(t *testing.T) {
+	cfg := stubServerConfig(t, "feat: add login")
+	cfg.Generation.MaxSubjectLength = 20
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	long := "feat: add a login page with remember me\n\nBody stays."
+	chain := NewFormatterChain(&cfg, true)
+
+	cfg.Generation.OnTooLong = "truncate"
+	message, err := NewGenerator(&cfg, sessionCache).enforceSubjectLength(long, "prompt", chain)
+	if err != nil || message != "feat: add a login\n\nBody stays." {
+		t.Errorf("Expected truncated subject with body, got %q, %v", message, err)
+	}
+
+	cfg.Generation.OnTooLong = "reprompt"
+	message, err = NewGenerator(&cfg, sessionCache).enforceSubjectLength(long, "prompt", chain)
+	if err != nil || message != "feat: add login" {
+		t.Errorf("Expected regenerated short subject, got %q, %v", message, err)
+	}
+
+	cfg.Generation.MaxSubjectLength = 0
+	if message, _ := NewGenerator(&cfg, sessionCache).enforceSubjectLength(long, "prompt", chain); message != long {
+		t.Errorf("Zero limit should disable the check, got %q", message)
+	}
+
+	t.Log("✓ Long subjects truncated or regenerated")
+}
+
+func TestStripLeadIn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Here's your commit:\nfeat: x", "feat: x"},
+		{"Here is the commit message:\n\nfeat: x", "feat: x"},
+		{"Sure! Here's the commit message:\nfix: handle nil", "fix: handle nil"},
+		{"Commit message: feat(auth): add login", "feat(auth): add login"},
+		{"HERE IS YOUR COMMIT MESSAGE:\n```\nfeat: x\n```", "feat: x"},
+		{"Here’s your commit message:\nchore: bump deps", "chore: bump deps"},
+		{"feat: add commit message parsing", "feat: add commit message parsing"},
+		{"Surely fix: nothing", "Surely fix: nothing"},
+	}
+
+	for _, tt := range tests {
+		result := extractCommitMessage(stripLeadIn(tt.input, config.DefaultStripPrefixes))
+		if result != tt.expected {
+			t.Errorf("Lead-in not stripped:\n  input: %q\n  got: %q\n  expected: %q", tt.input, result, tt.expected)
+		}
+	}
+
+	t.Log("✓ Lead-in phrases stripped")
+}
+
+func TestStripLeadInCustomPrefixes(t *testing.T) {
+	input := "Voici le message:\nfeat: x"
+
+	if result := stripLeadIn(input, nil); result != input {
+		t.Errorf("Nil prefixes should leave response unchanged: got %q", result)
+	}
+
+	result := extractCommitMessage(stripLeadIn(input, []string{"voici le message"}))
+	if result != "feat: x" {
+		t.Errorf("Custom prefix not stripped: got %q", result)
+	}
+
+	t.Log("✓ Custom lead-in prefixes honored")
+}
+
+func TestExtractFullOutput(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := config.Get()
+
+	response := "```\nfeat(api): add pagination\n\nAdds cursor-based pagination.\n\nCloses #12\n```"
+
+	result, err := NewFormatterChain(cfg, false).Format(response)
+	if err != nil || result != "feat(api): add pagination" {
+		t.Errorf("Default extraction should collapse to first line: got %q, %v", result, err)
+	}
+
+	expected := "feat(api): add pagination\n\nAdds cursor-based pagination.\n\nCloses #12"
+	result, err = NewFormatterChain(cfg, true).Format(response)
+	if err != nil || result != expected {
+		t.Errorf("Full extraction mismatch:\n  got: %q\n  expected: %q", result, expected)
+	}
+
+	t.Log("✓ Full output preserves body and footers")
+}
+
+func TestGenerateRejectsDisallowedProvider(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := *config.Get()
+	cfg.Generation.AllowedProviders = []string{"internal"}
+	cfg.Generation.Model.Provider = "openai"
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	gen := NewGenerator(&cfg, sessionCache)
+
+	_, err := gen.Generate()
+	if !errors.Is(err, ErrProviderNotAllowed) {
+		t.Fatalf("Expected ErrProviderNotAllowed, got %v", err)
+	}
+
+	cfg.Generation.Model.Provider = "Internal"
+	if err := gen.checkProviderAllowed(); err != nil {
+		t.Errorf("Allowed provider rejected: %v", err)
+	}
+
+	t.Log("✓ Disallowed provider override rejected")
+}
+
+func TestBuildPromptWithPartiallyStagedFiles(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := config.Get()
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	gen := NewGenerator(cfg, sessionCache)
+
+	if prompt := gen.buildPrompt("test diff", false); contains(prompt, "partially staged") {
+		t.Error("Prompt should not mention partial staging when all files are fully staged")
+	}
+
+	gen.partiallyStaged = []string{"internal/app.go"}
+	prompt := gen.buildPrompt("test diff", false)
+
+	if !contains(prompt, "partially staged") || !contains(prompt, "- internal/app.go") {
+		t.Error("Prompt should list partially staged files")
+	}
+
+	t.Log("✓ Prompt notes partially staged files")
+}
+
+func TestBuildPromptWithBreakingChanges(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := config.Get()
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	gen := NewGenerator(cfg, sessionCache)
+
+	if prompt := gen.buildPrompt("test diff", false); contains(prompt, "BREAKING CHANGE") {
+		t.Error("Prompt should not ask for a breaking change footer when none was detected")
+	}
+
+	gen.breaking = []string{"removed func Fetch (api/client.go)"}
+	prompt := gen.buildPrompt("test diff", false)
+
+	if !contains(prompt, "- removed func Fetch (api/client.go)") || !contains(prompt, "BREAKING CHANGE: <description>") {
+		t.Error("Prompt should list breaking changes and ask for the footer")
+	}
+
+	t.Log("✓ Prompt notes breaking changes")
+}
+
+func TestBuildPromptWithCommentOnlyHint(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := config.Get()
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	gen := NewGenerator(cfg, sessionCache)
+
+	gen.significance = git.SignificanceLogic
+	if prompt := gen.buildPrompt("test diff", false); contains(prompt, "only touch") {
+		t.Error("Prompt should not include a trivial-change hint for logic changes")
+	}
+
+	gen.significance = git.SignificanceComments
+	if prompt := gen.buildPrompt("test diff", false); !contains(prompt, "`docs` type") {
+		t.Error("Prompt should suggest docs for comment-only changes")
+	}
+
+	t.Log("✓ Prompt hints docs type for comment-only changes")
+}
+
+func TestBuildPromptWithBinaryOnlyHint(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := config.Get()
+
+	gen := NewGenerator(cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	diff := "diff --git a/assets/logo.png b/assets/logo.png\nnew file mode 100644\nbinary: assets/logo.png (2048 bytes)\n"
+
+	if prompt := gen.buildPrompt(diff, false); contains(prompt, "Only binary files changed") {
+		t.Error("Prompt should not include the binary hint unless every file is binary")
+	}
+
+	gen.binaryOnly = true
+	if prompt := gen.buildPrompt(diff, false); !contains(prompt, "Only binary files changed") {
+		t.Error("Prompt should note that only binary files changed")
+	}
+}
+
+func TestBuildPromptUsesConfiguredStyle(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := *config.Get()
+	cfg.Generation.Style = "imperative"
+
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	prompt := gen.buildPrompt("test diff", false)
+
+	if !contains(prompt, getStyleGuide("imperative", nil)) {
+		t.Error("Prompt should use the imperative style guide")
+	}
+	if contains(prompt, getStyleGuide("conventional", nil)) {
+		t.Error("Prompt should not use the default style guide")
+	}
+}
+
+func TestBuildPromptUsesCustomStyle(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := *config.Get()
+	guide := "Start the subject with the component in brackets, e.g. \"[auth] Add login\"."
+	cfg.Generation.CustomStyles = map[string]string{"house": guide}
+	cfg.Generation.Style = "House"
+
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	prompt := gen.buildPrompt("test diff", false)
+
+	if !contains(prompt, guide) {
+		t.Error("Prompt should use the custom style guide verbatim")
+	}
+	if contains(prompt, getStyleGuide("conventional", nil)) {
+		t.Error("Prompt should not fall back to the default style guide")
+	}
+
+	if err := validateCustomStyles(cfg.Generation.CustomStyles); err != nil {
+		t.Errorf("Valid custom style rejected: %v", err)
+	}
+	if err := validateCustomStyles(map[string]string{"detailed": guide}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for a built-in name, got %v", err)
+	}
+	if err := validateCustomStyles(map[string]string{"empty": " "}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for an empty guide, got %v", err)
+	}
+
+	t.Log("✓ Custom style flows through buildPrompt")
+}
+
+func TestBuildPromptWithScope(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := config.Get()
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	gen := NewGenerator(cfg, sessionCache)
+
+	if prompt := gen.buildPrompt("test diff", false); contains(prompt, "Suggested scope") {
+		t.Error("Prompt should not suggest a scope when none was inferred")
+	}
+
+	gen.scope = "sqlstore"
+	if prompt := gen.buildPrompt("test diff", false); !contains(prompt, "Suggested scope (from package metadata or the changed directory): sqlstore") {
+		t.Error("Prompt should suggest the inferred scope")
+	}
+}
+
+func TestBuildPromptWithBranch(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := config.Get()
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	gen := NewGenerator(cfg, sessionCache)
+
+	if prompt := gen.buildPrompt("test diff", false); contains(prompt, "Current branch") {
+		t.Error("Prompt should not mention a branch when none is set")
+	}
+
+	gen.branch = "feature/PROJ-123-login"
+	if prompt := gen.buildPrompt("test diff", false); !contains(prompt, "Current branch: feature/PROJ-123-login") {
+		t.Error("Prompt should include the branch name")
+	}
+
+	t.Log("✓ Branch name included in prompt")
+}
+
+func TestBuildPromptWithPRTemplate(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := config.Get()
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".github"), 0o755); err != nil {
+		t.Fatalf("Failed to create .github: %v", err)
+	}
+	template := "## Summary\n\nDescribe the change.\n\n## Testing\n- [ ] unit tests\n\n### Rollout\n"
+	if err := os.WriteFile(filepath.Join(root, ".github", "PULL_REQUEST_TEMPLATE.md"), []byte(template), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	gen := NewGenerator(cfg, sessionCache)
+	gen.prHeadings = prTemplateHeadings(root)
+
+	prompt := gen.buildPrompt("test diff", false)
+	for _, heading := range []string{"- Summary", "- Testing", "- Rollout"} {
+		if !contains(prompt, heading) {
+			t.Errorf("Prompt missing PR template heading %q", heading)
+		}
+	}
+
+	if headings := prTemplateHeadings(t.TempDir()); headings != nil {
+		t.Errorf("Expected no headings without a template, got %v", headings)
+	}
+
+	t.Log("✓ PR template headings included in prompt")
+}
+
+func TestFormatRejectsEmptyMessage(t *testing.T) {
+	_ = config.Initialize("")
+	chain := NewFormatterChain(config.Get(), false)
+
+	for _, response := range []string{"```\n```", "   \n  ", "Here is the commit message:"} {
+		_, err := chain.Format(response)
+		if !errors.Is(err, ErrEmptyMessage) {
+			t.Errorf("Expected ErrEmptyMessage for %q, got %v", response, err)
+		}
+	}
+
+	message, err := chain.Format("feat: add feature")
+	if err != nil || message != "feat: add feature" {
+		t.Errorf("Unexpected result for non-empty message: %q, %v", message, err)
+	}
+
+	t.Log("✓ Empty extracted message rejected")
+}
+
+func TestCheckBudgetBlocksOversizePrompt(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := *config.Get()
+	cfg.Generation.MaxCostTokens = 1000
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	gen := NewGenerator(&cfg, sessionCache)
+
+	if err := gen.checkBudget("small prompt"); err != nil {
+		t.Errorf("Small prompt should be within budget: %v", err)
+	}
+
+	oversize := strings.Repeat("x", 200*1024)
+	if err := gen.checkBudget(oversize); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded without --force, got %v", err)
+	}
+
+	gen.SetForce(true)
+	if err := gen.checkBudget(oversize); err != nil {
+		t.Errorf("Oversize prompt should be allowed with --force: %v", err)
+	}
+
+	t.Log("✓ Oversize prompt blocked unless forced")
+}
+
+func TestProbeStubbedBackend(t *testing.T) {
+	cfg := stubServerConfig(t, "OK")
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	gen := NewGenerator(&cfg, sessionCache)
+	result, err := gen.Probe()
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+
+	if !result.OK {
+		t.Errorf("Expected usable response, got %q", result.Response)
+	}
+	if result.Duration <= 0 {
+		t.Error("Expected probe duration to be recorded")
+	}
+
+	t.Logf("✓ Probe got %q in %v", result.Response, result.Duration)
+}
+
+func TestProbeUnusableResponse(t *testing.T) {
+	cfg := stubServerConfig(t, "I cannot help with that.")
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	result, err := NewGenerator(&cfg, sessionCache).Probe()
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+
+	if result.OK {
+		t.Errorf("Response without OK should not be usable: %q", result.Response)
+	}
+}
+
+func TestGenerateBodyToggle(t *testing.T) {
+	reply := "feat: add main package\n\nAdd an entry point so the module builds a binary."
+	cfg := stubServerConfig(t, reply)
+	gittest.StagedRepo(t)
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	cfg.Generation.Body = true
+	message, err := NewGenerator(&cfg, sessionCache).Generate()
+	if err != nil {
+		t.Fatalf("Generate with body failed: %v", err)
+	}
+	if message != reply {
+		t.Errorf("Expected multi-line message with body, got %q", message)
+	}
+
+	cfg.Generation.Body = false
+	message, err = NewGenerator(&cfg, sessionCache).Generate()
+	if err != nil {
+		t.Fatalf("Generate without body failed: %v", err)
+	}
+	if message != "feat: add main package" {
+		t.Errorf("Expected subject only, got %q", message)
+	}
+
+	t.Log("✓ Body toggle controls multi-line output")
+}
+
+func TestGenerateDetailedKeepsBody(t *testing.T) {
+	reply := "```\nfeat(main): add entry point\n\nAdd a main package so the module\nbuilds a binary.\n```"
+	cfg := stubServerConfig(t, reply)
+	gittest.StagedRepo(t)
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	cfg.Generation.Style = "detailed"
+	message, err := NewGenerator(&cfg, sessionCache).Generate()
+	if err != nil {
+		t.Fatalf("Generate with detailed style failed: %v", err)
+	}
+	expected := "feat(main): add entry point\n\nAdd a main package so the module\nbuilds a binary."
+	if message != expected {
+		t.Errorf("Expected subject and body, got %q", message)
+	}
+
+	cfg.Generation.Style = "conventional"
+	message, err = NewGenerator(&cfg, sessionCache).Generate()
+	if err != nil {
+		t.Fatalf("Generate with conventional style failed: %v", err)
+	}
+	if message != "feat(main): add entry point" {
+		t.Errorf("Expected subject only, got %q", message)
+	}
+
+	cfg.Generation.Style = "detailed"
+	gen := NewGenerator(&cfg, sessionCache)
+	gen.SetNoBody(true)
+	message, err = gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate with detailed style and no body failed: %v", err)
+	}
+	if message != "feat(main): add entry point" {
+		t.Errorf("Expected --no-body to keep only the subject, got %q", message)
+	}
+
+	t.Log("✓ Detailed style keeps the body unless --no-body, conventional keeps the subject")
+}
+
+func TestGenerateAppliesPathOverride(t *testing.T) {
+	cfg := stubServerConfig(t, "Add login form")
+	gittest.StagedRepo(t, "frontend/app.js", "frontend/ui/form.js", "backend/main.go")
+
+	cfg.Generation.Style = "conventional"
+	cfg.Generation.Overrides = []config.Override{
+		{PathGlob: "backend/**", Style: "detailed"},
+		{PathGlob: "frontend/**", Style: "imperative", Model: config.ModelConfig{ModelID: "frontend-model"}},
+	}
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	gen := NewGenerator(&cfg, sessionCache)
+	result, err := gen.GenerateResult()
+	if err != nil {
+		t.Fatalf("GenerateResult failed: %v", err)
+	}
+
+	if result.Style != "imperative" {
+		t.Errorf("Expected override style imperative, got %q", result.Style)
+	}
+	if result.Model != cfg.Generation.Model.Provider+"/frontend-model" {
+		t.Errorf("Expected override model, got %q", result.Model)
+	}
+	if gen.GetConfig().Generation.Style != "conventional" {
+		t.Error("Override should not leak into the generator's configuration")
+	}
+
+	t.Log("✓ Path override selected for majority of changed files")
+}
+
+func TestGenerateOverrideFollowsWorkingTreeDiff(t *testing.T) {
+	cfg := stubServerConfig(t, "Update login form")
+	dir := gittest.StagedRepo(t, "frontend/app.js", "frontend/ui/form.js", "backend/main.go")
+	if out, err := exec.Command("git", "commit", "-q", "-m", "init").CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %v\n%s", err, out)
+	}
+
+	// Only the backend change is staged; --all also describes the frontend edits.
+	for _, name := range []string{"frontend/app.js", "frontend/ui/form.js", "backend/main.go"} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte("package main\n\nfunc edited() {}\n"), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if out, err := exec.Command("git", "add", "backend/main.go").CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v\n%s", err, out)
+	}
+
+	cfg.Generation.Style = "conventional"
+	cfg.Generation.Overrides = []config.Override{
+		{PathGlob: "backend/**", Style: "detailed"},
+		{PathGlob: "frontend/**", Style: "imperative"},
+	}
+
+	for _, tt := range []struct {
+		stagedOnly bool
+		expected   string
+	}{
+		{true, "detailed"},
+		{false, "imperative"},
+	} {
+		cfg.Git.StagedOnly = tt.stagedOnly
+		sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+		result, err := NewGenerator(&cfg, sessionCache).GenerateResult()
+		if err != nil {
+			t.Fatalf("GenerateResult failed: %v", err)
+		}
+		if result.Style != tt.expected {
+			t.Errorf("staged_only=%v: expected override style %q, got %q", tt.stagedOnly, tt.expected, result.Style)
+		}
+	}
+}
+
+func TestPreparePromptInfersScopeWhenAmending(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := *config.Get()
+	cfg.Generation.Style = "conventional"
+
+	dir := gittest.StagedRepo(t, "README.md")
+	commit := func(message string) {
+		t.Helper()
+		if out, err := exec.Command("git", "commit", "-q", "-m", message).CombinedOutput(); err != nil {
+			t.Fatalf("git commit failed: %v\n%s", err, out)
+		}
+	}
+	commit("init")
+
+	path := filepath.Join(dir, "internal", "git", "diff.go")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("package git\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if out, err := exec.Command("git", "add", "internal/git/diff.go").CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v\n%s", err, out)
+	}
+	commit("add git package")
+
+	// The index matches HEAD, so only the amend diff names the changed files.
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	gen.SetAmend(true, false)
+	if _, _, err := gen.preparePrompt(); err != nil {
+		t.Fatalf("preparePrompt failed: %v", err)
+	}
+	if gen.scope != "git" {
+		t.Errorf("Expected scope %q for the amended commit, got %q", "git", gen.scope)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, file string
+		expected      bool
+	}{
+		{"frontend/**", "frontend/app.js", true},
+		{"frontend/**", "frontend/ui/form.js", true},
+		{"frontend/**", "backend/main.go", false},
+		{"*/docs/**", "pkg/docs/readme.md", true},
+		{"*.md", "README.md", true},
+		{"*.md", "docs/README.md", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.file); got != tt.expected {
+			t.Errorf("matchGlob(%q, %q) = %v, expected %v", tt.pattern, tt.file, got, tt.expected)
+		}
+	}
+}
+
+func TestGenerateReusesMessageForIdenticalDiff(t *testing.T) {
+	cfg := stubServerConfig(t, "feat: add main package")
+	gittest.StagedRepo(t)
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	first, err := NewGenerator(&cfg, sessionCache).GenerateResult()
+	if err != nil {
+		t.Fatalf("First GenerateResult failed: %v", err)
+	}
+	if first.Cached {
+		t.Error("First generation should not be cached")
+	}
+
+	cfg.OpenCode.Port = 1 // any backend call would now fail
+	second, err := NewGenerator(&cfg, sessionCache).GenerateResult()
+	if err != nil {
+		t.Fatalf("Second GenerateResult failed: %v", err)
+	}
+	if !second.Cached || second.Message != first.Message {
+		t.Errorf("Expected cached %q, got cached=%v %q", first.Message, second.Cached, second.Message)
+	}
+
+	forced := NewGenerator(&cfg, sessionCache)
+	forced.SetForce(true)
+	if _, err := forced.GenerateResult(); err == nil {
+		t.Error("--force should bypass the cached message and call the backend")
+	}
+
+	t.Log("✓ Identical diff reuses the previous message")
+}
+
+func TestGenerateCandidates(t *testing.T) {
+	reply := "Here are three options:\n1. feat: add main package\n2. feat(main): add entry point\n3. chore: scaffold binary"
+	cfg := stubServerConfig(t, reply)
+	gittest.StagedRepo(t)
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	cfg.Generation.Candidates = 3
+	result, err := NewGenerator(&cfg, sessionCache).GenerateResult()
+	if err != nil {
+		t.Fatalf("GenerateResult failed: %v", err)
+	}
+
+	expected := []string{"feat: add main package", "feat(main): add entry point", "chore: scaffold binary"}
+	if strings.Join(result.Candidates, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected candidates: %q", result.Candidates)
+	}
+	if result.Message != expected[0] {
+		t.Errorf("Message should be the first candidate, got %q", result.Message)
+	}
+
+	t.Log("✓ Candidates parsed and formatted")
+}
+
+func TestGenerateRetriesWithReducedDiffOnContextLengthError(t *testing.T) {
+	cfg := stubServerConfig(t, "")
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/global/health":
+			_ = json.NewEncoder(w).Encode(opencode.HealthResponse{Healthy: true})
+		case "/session":
+			_ = json.NewEncoder(w).Encode(opencode.Session{ID: "session-ctx"})
+		default:
+			var req opencode.PromptRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			prompts = append(prompts, req.Parts[0].Text)
+			if !strings.Contains(req.Parts[0].Text, "DIFF SUMMARY") {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"This model's maximum context length is 8192 tokens"}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(opencode.Message{
+				Parts: []opencode.MessagePart{{Type: "text", Text: "feat: add main package"}},
+			})
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	cfg.OpenCode.Port, _ = strconv.Atoi(serverURL.Port())
+	gittest.StagedRepo(t)
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	gen := NewGenerator(&cfg, sessionCache)
+	result, err := gen.GenerateResult()
+	if err != nil {
+		t.Fatalf("GenerateResult failed: %v", err)
+	}
+
+	if len(prompts) != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "DIFF SUMMARY") || !result.IsSummarized {
+		t.Error("Retry should send a summarized diff")
+	}
+	if result.DownsizedTo <= 0 {
+		t.Errorf("Expected downsized diff size to be reported, got %d", result.DownsizedTo)
+	}
+	if gen.maxDiffSize() != cfg.Git.MaxDiffSize {
+		t.Error("Reduced diff limit should not persist after generation")
+	}
+
+	t.Logf("✓ Context-length error retried with diff reduced to %d bytes", result.DownsizedTo)
+}
+
+func TestGenerateResultFields(t *testing.T) {
+	cfg := stubServerConfig(t, "feat: add main package")
+	gittest.StagedRepo(t)
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	_ = sessionCache.Clear()
+	defer func() { _ = sessionCache.Clear() }()
+
+	result, err := NewGenerator(&cfg, sessionCache).GenerateResult()
+	if err != nil {
+		t.Fatalf("GenerateResult failed: %v", err)
+	}
+
+	if result.Message != "feat: add main package" {
+		t.Errorf("Message mismatch: got %q", result.Message)
+	}
+	if result.Mode != "server" {
+		t.Errorf("Mode mismatch: got %q", result.Mode)
+	}
+	expectedModel := cfg.Generation.Model.Provider + "/" + cfg.Generation.Model.ModelID
+	if result.Model != expectedModel {
+		t.Errorf("Model mismatch: got %q, expected %q", result.Model, expectedModel)
+	}
+	if result.IsSummarized {
+		t.Error("Small diff should not be summarized")
+	}
+	if result.TokenEstimate <= 0 {
+		t.Errorf("Expected positive token estimate, got %d", result.TokenEstimate)
+	}
+	if result.Elapsed <= 0 {
+		t.Error("Expected elapsed time to be recorded")
+	}
+
+	t.Logf("✓ Result populated: %+v", result)
+}
+
+func TestGenerateResultFromSummarizedDiff(t *testing.T) {
+	stub := filepath.Join(t.TempDir(), "opencode")
+	if err := os.WriteFile(stub, []byte("#!/bin/sh\necho 'feat: add app packages'\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write opencode stub: %v", err)
+	}
+
+	for _, mode := range []string{"server", "run"} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := stubServerConfig(t, "feat: add app packages")
+			cfg.OpenCode.Mode = mode
+			cfg.OpenCode.Binary = stub
+			cfg.Git.MaxDiffSize = 64
+			cfg.Git.CollapseLargeFiles = false
+			gittest.StagedRepo(t, "cmd/app/main.go", "internal/store/store.go")
+
+			result, err := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir())).GenerateResult()
+			if err != nil {
+				t.Fatalf("GenerateResult failed: %v", err)
+			}
+
+			if result.Message != "feat: add app packages" {
+				t.Errorf("Message mismatch: got %q", result.Message)
+			}
+			if !result.IsSummarized {
+				t.Error("Diff over git.max_diff_size should be summarized")
+			}
+			if result.OriginalDiffSize <= cfg.Git.MaxDiffSize {
+				t.Errorf("Expected the original size above the limit, got %d", result.OriginalDiffSize)
+			}
+			if strings.Join(result.Files, ",") != "cmd/app/main.go,internal/store/store.go" {
+				t.Errorf("Files mismatch: %v", result.Files)
+			}
+			if result.Mode != mode || result.Model != cfg.Generation.Model.Provider+"/"+cfg.Generation.Model.ModelID || result.Style != cfg.Generation.Style {
+				t.Errorf("Backend details mismatch: mode %q, model %q, style %q", result.Mode, result.Model, result.Style)
+			}
+		})
+	}
+}
+
+func TestSendRoutesToOpenAI(t *testing.T) {
+	var model string
+	cfg := openaitest.Config(t, func(req openaitest.Request) openaitest.Response {
+		if req.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected path: %s", req.Path)
+		}
+		model = req.Model
+		return openaitest.Response{Content: "feat: route to openai"}
+	})
+	cfg.OpenAI.Model = ""
+	cfg.Generation.Model.ModelID = "gpt-4o-mini"
+
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	if gen.GetMode() != "openai" || gen.runner != nil || gen.client != nil {
+		t.Fatalf("Expected only the openai backend, got mode %q", gen.GetMode())
+	}
+
+	response, err := gen.send("prompt")
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if response != "feat: route to openai" {
+		t.Errorf("Response mismatch: got %q", response)
+	}
+	if model != "gpt-4o-mini" {
+		t.Errorf("Expected generation.model.model_id as fallback model, got %q", model)
+	}
+	if gen.modelName() != "openai/gpt-4o-mini" {
+		t.Errorf("modelName mismatch: %q", gen.modelName())
+	}
+}
+
+func TestGenerateWithProvidedDiffOutsideRepository(t *testing.T) {
+	var prompt string
+	cfg := openaitest.Config(t, func(req openaitest.Request) openaitest.Response {
+		prompt = req.Prompt
+		return openaitest.Response{Content: "Add widget spinning"}
+	})
+	cfg.Generation.Style = "conventional"
+	cfg.Generation.Overrides = []config.Override{{PathGlob: "pkg/**", Style: "imperative"}}
+
+	oldCwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(oldCwd) })
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	stderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	os.Stderr = w
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	gen.SetDiff("diff --git a/pkg/widget/widget.go b/pkg/widget/widget.go\n+func Spin() {}\n")
+	result, err := gen.GenerateResult()
+	os.Stderr = stderr
+	_ = w.Close()
+	warnings, _ := io.ReadAll(r)
+
+	if err != nil {
+		t.Fatalf("GenerateResult failed: %v", err)
+	}
+	if result.Style != "imperative" || !contains(prompt, "imperative mood") {
+		t.Errorf("Expected the override matching the provided diff's files, got style %q", result.Style)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings outside a repository, got %q", warnings)
+	}
+}
+
+func TestPreparePromptUsesProvidedDiff(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := *config.Get()
+
+	// Outside a repository, so the diff can only come from SetDiff.
+	oldCwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(oldCwd) })
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	diff := `diff --git a/pkg/widget/widget.go b/pkg/widget/widget.go
+--- a/pkg/widget/widget.go
++++ b/pkg/widget/widget.go
+@@ -1 +1,3 @@
+ package widget
++
++func Spin() {}
+`
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	gen.SetDiff(diff)
+
+	diffResult, prompt, err := gen.preparePrompt()
+	if err != nil {
+		t.Fatalf("preparePrompt failed: %v", err)
+	}
+	if diffResult.IsSummarized || !contains(prompt, "+func Spin() {}") {
+		t.Errorf("Expected the provided diff in the prompt, got %q", prompt)
+	}
+	if !contains(prompt, "Suggested scope (from package metadata or the changed directory): widget") {
+		t.Error("Expected the scope to be inferred from the provided diff's files")
+	}
+
+	cfg.Git.MaxDiffSize = 120
+	diffResult, prompt, err = gen.preparePrompt()
+	if err != nil {
+		t.Fatalf("preparePrompt failed: %v", err)
+	}
+	if !diffResult.IsSummarized || !contains(prompt, "=== DIFF SUMMARY") || !contains(prompt, "pkg/widget/widget.go") {
+		t.Errorf("Expected an oversized provided diff to be summarized, got %q", prompt)
+	}
+
+	gen.SetDiff("")
+	if _, _, err := gen.preparePrompt(); !errors.Is(err, git.ErrEmptyDiff) {
+		t.Errorf("Expected ErrEmptyDiff for an empty provided diff, got %v", err)
+	}
+
+	t.Log("✓ A provided diff reaches the prompt and is still size-limited")
+}
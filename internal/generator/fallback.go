@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/avgt93/commit-gen/internal/config"
+)
+
+// modelUnavailableMarkers are lower-cased fragments of backend errors for a
+// model that does not exist, is not configured, or cannot serve requests.
+var modelUnavailableMarkers = []string{
+	"modelnotfound",
+	"model_not_found",
+	"unknown model",
+	"no such model",
+	"model is overloaded",
+}
+
+// modelUnavailablePattern matches messages such as "The model `x` does not
+// exist" or "model gpt-9 not found", naming the model in between.
+var modelUnavailablePattern = regexp.MustCompile(`\bmodel\b[^.:]{0,80}?\b(not found|does not exist|is not available|unavailable)\b`)
+
+/**
+ * isModelUnavailableError reports whether err says the model itself cannot
+ * be used, as opposed to a network, prompt, or repository problem.
+ */
+func isModelUnavailableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range modelUnavailableMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return modelUnavailablePattern.MatchString(msg)
+}
+
+/**
+ * switchToFallback replaces the model with generation.fallback_model after a
+ * model-unavailable error. It switches at most once per generator, and never
+ * to a provider outside generation.allowed_providers; later generations,
+ * such as a regenerate, keep using the fallback.
+ *
+ * @param err - The error from the primary model
+ * @returns true if the generator now uses the fallback model
+ */
+func (g *Generator) switchToFallback(err error) bool {
+	fallback := strings.TrimSpace(g.config.Generation.FallbackModel)
+	if fallback == "" || g.fallback != nil || !isModelUnavailableError(err) {
+		return false
+	}
+	provider, modelID, ok := strings.Cut(fallback, "/")
+	if !ok || g.providerAllowed(provider) != nil {
+		return false
+	}
+
+	primary := g.modelName()
+	g.fallback = &config.ModelConfig{Provider: provider, ModelID: modelID}
+	fmt.Fprintf(os.Stderr, "Model %s is unavailable; falling back to %s\n", primary, g.modelName())
+	return true
+}
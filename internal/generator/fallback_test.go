@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/avgt93/commit-gen/internal/cache"
+	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/avgt93/commit-gen/internal/git/gittest"
+	"github.com/avgt93/commit-gen/internal/llm/openai/openaitest"
+)
+
+// stubFallbackServer answers chat completions for the model "good" and
+// rejects every other model with errorStatus and errorBody.
+func stubFallbackServer(t *testing.T, errorStatus int, errorBody string) (config.Config, *[]string) {
+	var models []string
+	cfg := openaitest.Config(t, func(req openaitest.Request) openaitest.Response {
+		models = append(models, req.Model)
+		if req.Model != "good" {
+			return openaitest.Response{Status: errorStatus, Body: errorBody}
+		}
+		return openaitest.Response{Content: "feat: use the fallback"}
+	})
+	cfg.OpenAI.Model = "retired"
+	cfg.Generation.FallbackModel = "openai/good"
+	return cfg, &models
+}
+
+func TestSendFallsBackWhenModelUnavailable(t *testing.T) {
+	cfg, models := stubFallbackServer(t, http.StatusNotFound, `{"error":{"message":"The model `+"`retired`"+` does not exist or you do not have access to it.","code":"model_not_found"}}`)
+
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	response, err := gen.send("prompt")
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if response != "feat: use the fallback" {
+		t.Errorf("Response mismatch: %q", response)
+	}
+	if len(*models) != 2 || (*models)[0] != "retired" || (*models)[1] != "good" {
+		t.Errorf("Expected the primary then the fallback model, got %v", *models)
+	}
+	if gen.modelName() != "openai/good" {
+		t.Errorf("Expected the result to name the fallback model, got %q", gen.modelName())
+	}
+	if cfg.Generation.Model.ModelID == "good" || cfg.OpenAI.Model != "retired" {
+		t.Error("Falling back must not modify the caller's configuration")
+	}
+
+	// Later requests go straight to the fallback.
+	if _, err := gen.send("prompt"); err != nil || len(*models) != 3 || (*models)[2] != "good" {
+		t.Errorf("Expected the fallback to be reused, got %v (%v)", *models, err)
+	}
+}
+
+func TestSendDoesNotFallBackOnOtherErrors(t *testing.T) {
+	cfg, models := stubFallbackServer(t, http.StatusBadGateway, "upstream connection reset")
+
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	if _, err := gen.send("prompt"); err == nil {
+		t.Fatal("Expected the primary error")
+	}
+	if len(*models) != 1 {
+		t.Errorf("Expected no fallback request for an unrelated error, got %v", *models)
+	}
+}
+
+func TestFallbackSurvivesOverrideRestore(t *testing.T) {
+	cfg, models := stubFallbackServer(t, http.StatusNotFound, `{"error":{"message":"model not found"}}`)
+	cfg.Generation.Overrides = []config.Override{{PathGlob: "*.go", Style: "imperative"}}
+	gittest.StagedRepo(t)
+
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	gen.SetForce(true)
+	if _, err := gen.GenerateResult(); err != nil {
+		t.Fatalf("GenerateResult failed: %v", err)
+	}
+
+	// Regenerating after the override was restored keeps the fallback model
+	result, err := gen.GenerateResult()
+	if err != nil {
+		t.Fatalf("Regenerate failed: %v", err)
+	}
+	if len(*models) != 3 || (*models)[2] != "good" {
+		t.Errorf("Expected the regenerate to go straight to the fallback, got %v", *models)
+	}
+	if result.Model != "openai/good" {
+		t.Errorf("Expected the result to name the fallback model, got %q", result.Model)
+	}
+}
+
+func TestSendFallbackRespectsAllowedProviders(t *testing.T) {
+	cfg, models := stubFallbackServer(t, http.StatusNotFound, `{"error":{"message":"model not found"}}`)
+	cfg.Generation.Model.Provider = "openai"
+	cfg.Generation.AllowedProviders = []string{"openai"}
+	cfg.Generation.FallbackModel = "other/good"
+
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	if _, err := gen.send("prompt"); err == nil {
+		t.Fatal("Expected the primary error when the fallback provider is not allowed")
+	}
+	if len(*models) != 1 || gen.modelName() != "openai/retired" {
+		t.Errorf("Expected no fallback to a disallowed provider, got %v, %q", *models, gen.modelName())
+	}
+}
+
+func TestIsModelUnavailableError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{errors.New("ProviderModelNotFoundError: providerID=opencode modelID=gpt-9"), true},
+		{errors.New("chat completion failed: The model `x` does not exist (status 404)"), true},
+		{errors.New("Unknown model: claude-9"), true},
+		{errors.New("failed to send message: dial tcp 127.0.0.1:4096: connect: connection refused"), false},
+		{errors.New("no staged changes found"), false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := isModelUnavailableError(tt.err); got != tt.expected {
+			t.Errorf("isModelUnavailableError(%v) = %v, expected %v", tt.err, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/avgt93/commit-gen/internal/git"
+)
+
+/**
+ * Formatter is a single post-generation step that rewrites the model output.
+ */
+type Formatter interface {
+	Format(msg string) (string, error)
+}
+
+/**
+ * FormatterFunc adapts a plain function to the Formatter interface.
+ */
+type FormatterFunc func(msg string) (string, error)
+
+// Format calls f(msg).
+func (f FormatterFunc) Format(msg string) (string, error) {
+	return f(msg)
+}
+
+/**
+ * Chain runs formatters in order, feeding each the previous output.
+ */
+type Chain []Formatter
+
+/**
+ * Format applies every formatter in the chain, stopping at the first error.
+ *
+ * @param msg - The raw model output
+ * @returns The formatted commit message
+ * @returns The first error returned by a formatter
+ */
+func (c Chain) Format(msg string) (string, error) {
+	for _, f := range c {
+		var err error
+		msg, err = f.Format(msg)
+		if err != nil {
+			return "", err
+		}
+	}
+	return msg, nil
+}
+
+/**
+ * NewFormatterChain builds the default post-generation chain from the
+ * generation options. Steps run in this order: lead-in removal, code fence
+ * removal, subject or full-message extraction, type remapping, and the
+ * empty-message check.
+ *
+ * @param cfg - The application configuration
+ * @param full - true to keep the full multi-line output instead of the subject only
+ * @returns The configured formatter chain
+ */
+func NewFormatterChain(cfg *config.Config, full bool) Chain {
+	chain := Chain{
+		leadInFormatter{prefixes: cfg.Generation.StripPrefixes},
+		codeFenceFormatter{},
+	}
+
+	if full {
+		chain = append(chain, fullMessageFormatter{})
+	} else {
+		chain = append(chain, subjectFormatter{})
+	}
+
+	return append(chain,
+		typeMapFormatter{typeMap: cfg.Generation.TypeMap},
+		nonEmptyFormatter{},
+	)
+}
+
+// leadInFormatter removes conversational lead-ins such as "Here is the commit message:".
+type leadInFormatter struct {
+	prefixes []string
+}
+
+func (f leadInFormatter) Format(msg string) (string, error) {
+	return stripLeadIn(msg, f.prefixes), nil
+}
+
+// codeFenceFormatter removes a surrounding markdown code fence.
+type codeFenceFormatter struct{}
+
+func (codeFenceFormatter) Format(msg string) (string, error) {
+	return stripCodeFence(msg), nil
+}
+
+// subjectFormatter keeps only the first line of the message.
+type subjectFormatter struct{}
+
+func (subjectFormatter) Format(msg string) (string, error) {
+	return extractCommitMessage(msg), nil
+}
+
+// fullMessageFormatter keeps every line, trimming trailing whitespace.
+type fullMessageFormatter struct{}
+
+func (fullMessageFormatter) Format(msg string) (string, error) {
+	return extractFullMessage(msg), nil
+}
+
+// typeMapFormatter renames the conventional commit type using generation.type_map.
+type typeMapFormatter struct {
+	typeMap map[string]string
+}
+
+func (f typeMapFormatter) Format(msg string) (string, error) {
+	return applyTypeMap(msg, f.typeMap), nil
+}
+
+// scopeFormatter inserts the inferred scope into a conventional subject.
+type scopeFormatter struct {
+	scope    string
+	override bool
+}
+
+func (f scopeFormatter) Format(msg string) (string, error) {
+	return applyScope(msg, f.scope, f.override), nil
+}
+
+// nonEmptyFormatter rejects messages that are empty after formatting.
+type nonEmptyFormatter struct{}
+
+func (nonEmptyFormatter) Format(msg string) (string, error) {
+	if strings.TrimSpace(msg) == "" {
+		return "", fmt.Errorf("%w; try regenerating or using a different model", ErrEmptyMessage)
+	}
+	return msg, nil
+}
+
+// reuseBodyFormatter keeps the generated subject and appends the body and
+// trailers of a previous commit message unchanged.
+type reuseBodyFormatter struct {
+	previous string
+}
+
+func (f reuseBodyFormatter) Format(msg string) (string, error) {
+	return spliceBody(msg, f.previous), nil
+}
+
+/**
+ * spliceBody replaces the subject of a previous commit message, keeping its
+ * body and trailers verbatim.
+ *
+ * @param subject - The new subject line
+ * @param previous - The previous full commit message
+ * @returns The new subject followed by the previous body
+ */
+func spliceBody(subject, previous string) string {
+	subject = strings.TrimSpace(strings.SplitN(subject, "\n", 2)[0])
+
+	lines := strings.Split(strings.TrimSpace(previous), "\n")
+	body := strings.TrimSpace(strings.Join(lines[1:], "\n"))
+	if body == "" {
+		return subject
+	}
+	return subject + "\n\n" + body
+}
+
+var (
+	issueRefPattern = regexp.MustCompile(`(?:(?:^|[/_.-])(?:issue|gh)[-_]?|#)(\d+)(?:[/_.-]|$)|(?:^|/)(\d+)(?:[/_-]|$)`)
+	trailerPattern  = regexp.MustCompile(`^(?:BREAKING CHANGE|[A-Za-z][A-Za-z-]*)(?::\s| #)`)
+)
+
+/**
+ * extractIssueNumber finds an issue number in a branch name such as
+ * "fix/123-nil-pointer" or "feature/issue-42". A number only counts when it
+ * follows an "issue", "gh" or "#" marker or starts a path segment, so version
+ * numbers like "release/1.2.3" or "upgrade-node-18" are not mistaken for issues.
+ *
+ * @param branch - The branch name
+ * @returns The issue number, or empty string if none is found
+ */
+func extractIssueNumber(branch string) string {
+	match := issueRefPattern.FindStringSubmatch(strings.ToLower(branch))
+	if match == nil {
+		return ""
+	}
+	if match[1] != "" {
+		return match[1]
+	}
+	return match[2]
+}
+
+/**
+ * extractTicket applies the ticket pattern to a branch name. The first
+ * capture group is used when the pattern has one, otherwise the whole match.
+ *
+ * @param branch - The branch name
+ * @param pattern - The compiled generation.ticket_pattern
+ * @returns The ticket reference, or empty string if the branch does not match
+ */
+func extractTicket(branch string, pattern *regexp.Regexp) string {
+	match := pattern.FindStringSubmatch(branch)
+	if match == nil {
+		return ""
+	}
+	if len(match) > 1 && match[1] != "" {
+		return match[1]
+	}
+	return match[0]
+}
+
+// ticketFormatter adds the branch ticket as a "Refs:" trailer or as the subject scope.
+type ticketFormatter struct {
+	ticket   string
+	position string
+}
+
+func (f ticketFormatter) Format(msg string) (string, error) {
+	if f.ticket == "" {
+		return msg, nil
+	}
+	if f.position == "scope" && typePrefixPattern.MatchString(msg) {
+		return applyScope(msg, f.ticket, true), nil
+	}
+	if strings.Contains(msg, f.ticket) {
+		return msg, nil
+	}
+	return appendTrailer(msg, "Refs: "+f.ticket), nil
+}
+
+// issueFooterFormatter appends a "Fixes #N" or "Refs #N" footer for the referenced issue.
+type issueFooterFormatter struct {
+	issue   string
+	keyword string
+}
+
+func (f issueFooterFormatter) Format(msg string) (string, error) {
+	if f.issue == "" || strings.Contains(msg, "#"+f.issue) {
+		return msg, nil
+	}
+
+	keyword := f.keyword
+	if keyword == "" {
+		keyword = "Refs"
+		if match := typePrefixPattern.FindStringSubmatch(msg); match != nil && strings.EqualFold(match[1], "fix") {
+			keyword = "Fixes"
+		}
+	}
+
+	return appendTrailer(msg, keyword+" #"+f.issue), nil
+}
+
+// breakingFormatter marks a conventional subject with "!" and adds a
+// "BREAKING CHANGE:" footer listing the detected changes unless the model wrote one.
+type breakingFormatter struct {
+	changes []string
+}
+
+func (f breakingFormatter) Format(msg string) (string, error) {
+	if len(f.changes) == 0 {
+		return msg, nil
+	}
+	if match := typePrefixPattern.FindStringSubmatchIndex(msg); match != nil && match[6] < 0 {
+		colon := match[1] - 1
+		msg = msg[:colon] + "!" + msg[colon:]
+	}
+	for _, line := range strings.Split(msg, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			return msg, nil
+		}
+	}
+	return appendTrailer(msg, "BREAKING CHANGE: "+strings.Join(f.changes, "; ")), nil
+}
+
+// coAuthorFormatter appends a "Co-authored-by:" trailer for each co-author not already credited.
+type coAuthorFormatter struct {
+	authors []string
+}
+
+func (f coAuthorFormatter) Format(msg string) (string, error) {
+	for _, author := range f.authors {
+		msg = appendTrailerOnce(msg, "Co-authored-by: "+strings.TrimSpace(author))
+	}
+	return msg, nil
+}
+
+// signoffFormatter appends a DCO "Signed-off-by:" trailer unless it is already present.
+type signoffFormatter struct {
+	identity string
+}
+
+func (f signoffFormatter) Format(msg string) (string, error) {
+	return appendTrailerOnce(msg, "Signed-off-by: "+f.identity), nil
+}
+
+// appendTrailerOnce appends the trailer unless the message already has that exact line.
+func appendTrailerOnce(message, trailer string) string {
+	for _, line := range strings.Split(message, "\n") {
+		if strings.TrimSpace(line) == trailer {
+			return message
+		}
+	}
+	return appendTrailer(message, trailer)
+}
+
+/**
+ * MergeTemplateTrailers appends the trailer lines of a commit template,
+ * such as "Refs:" or "Reviewed-by:", that the message does not already have.
+ * Comment lines and other template text are ignored.
+ *
+ * @param message - The generated commit message
+ * @param template - The commit template (see git.GetCommitTemplate)
+ * @param commentChar - The template's comment prefix (see git.GetCommentChar)
+ * @returns The message with the template's trailers appended
+ */
+func MergeTemplateTrailers(message, template, commentChar string) string {
+	present := map[string]bool{}
+	for _, line := range strings.Split(message, "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+
+	var missing []string
+	for _, line := range strings.Split(git.StripCommitComments(template, commentChar), "\n") {
+		line = strings.TrimSpace(line)
+		// A placeholder trailer such as "Refs:" has no value yet.
+		if trailerPattern.MatchString(line+" ") && !present[line] {
+			missing = append(missing, line)
+			present[line] = true
+		}
+	}
+	if len(missing) == 0 {
+		return message
+	}
+	return appendTrailer(message, strings.Join(missing, "\n"))
+}
+
+/**
+ * appendTrailer adds a footer line to the message. It joins an existing
+ * trailer block at the end of the message, or starts a new paragraph.
+ *
+ * @param message - The commit message
+ * @param trailer - The footer line to add, e.g. "Fixes #12"
+ * @returns The message with the trailer appended
+ */
+func appendTrailer(message, trailer string) string {
+	message = strings.TrimRight(message, "\n ")
+
+	paragraphs := strings.Split(message, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if len(paragraphs) > 1 && isTrailerBlock(last) {
+		return message + "\n" + trailer
+	}
+	return message + "\n\n" + trailer
+}
+
+// isTrailerBlock reports whether every line of a paragraph looks like a git trailer.
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerPattern.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
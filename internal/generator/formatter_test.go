@@ -0,0 +1,337 @@
+package generator
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/avgt93/commit-gen/internal/config"
+)
+
+func TestLeadInFormatter(t *testing.T) {
+	f := leadInFormatter{prefixes: []string{"here is the commit message"}}
+
+	result, err := f.Format("Here is the commit message:\nfeat: x")
+	if err != nil || result != "feat: x" {
+		t.Errorf("Lead-in not removed: got %q, %v", result, err)
+	}
+}
+
+func TestCodeFenceFormatter(t *testing.T) {
+	result, err := codeFenceFormatter{}.Format("```\nfeat: x\n```")
+	if err != nil || strings.TrimSpace(result) != "feat: x" {
+		t.Errorf("Code fence not removed: got %q, %v", result, err)
+	}
+}
+
+func TestSubjectFormatter(t *testing.T) {
+	result, err := subjectFormatter{}.Format("  feat: x  \n\nbody")
+	if err != nil || result != "feat: x" {
+		t.Errorf("Subject not extracted: got %q, %v", result, err)
+	}
+}
+
+func TestFullMessageFormatter(t *testing.T) {
+	result, err := fullMessageFormatter{}.Format("\nfeat: x   \n\nbody  \n")
+	if err != nil || result != "feat: x\n\nbody" {
+		t.Errorf("Full message not cleaned: got %q, %v", result, err)
+	}
+}
+
+func TestTypeMapFormatter(t *testing.T) {
+	f := typeMapFormatter{typeMap: map[string]string{"fix": "bugfix"}}
+
+	result, err := f.Format("fix(api): handle nil")
+	if err != nil || result != "bugfix(api): handle nil" {
+		t.Errorf("Type not remapped: got %q, %v", result, err)
+	}
+}
+
+func TestNonEmptyFormatter(t *testing.T) {
+	if _, err := (nonEmptyFormatter{}).Format("  "); !errors.Is(err, ErrEmptyMessage) {
+		t.Errorf("Expected ErrEmptyMessage, got %v", err)
+	}
+
+	if result, err := (nonEmptyFormatter{}).Format("feat: x"); err != nil || result != "feat: x" {
+		t.Errorf("Non-empty message changed: got %q, %v", result, err)
+	}
+}
+
+func TestChainAppliesFormattersInOrder(t *testing.T) {
+	var order []string
+	step := func(name string) Formatter {
+		return FormatterFunc(func(msg string) (string, error) {
+			order = append(order, name)
+			return msg + name, nil
+		})
+	}
+
+	result, err := Chain{step("a"), step("b"), step("c")}.Format(">")
+	if err != nil {
+		t.Fatalf("Chain failed: %v", err)
+	}
+
+	if result != ">abc" || strings.Join(order, "") != "abc" {
+		t.Errorf("Chain order wrong: result %q, order %v", result, order)
+	}
+
+	t.Log("✓ Formatters applied in order")
+}
+
+func TestChainStopsAtFirstError(t *testing.T) {
+	failing := FormatterFunc(func(msg string) (string, error) {
+		return "", errors.New("boom")
+	})
+	called := false
+	after := FormatterFunc(func(msg string) (string, error) {
+		called = true
+		return msg, nil
+	})
+
+	if _, err := (Chain{failing, after}).Format("x"); err == nil {
+		t.Error("Expected chain error")
+	}
+	if called {
+		t.Error("Formatter after a failure should not run")
+	}
+}
+
+func TestDefaultChainOrdering(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Generation.StripPrefixes = []string{"here's your commit"}
+	cfg.Generation.TypeMap = map[string]string{"feat": "feature"}
+
+	// The lead-in must be removed before the fence and subject steps run,
+	// and the type map must see the extracted subject.
+	response := "Here's your commit:\n```\nfeat(ui): add toggle\n\nbody\n```"
+
+	result, err := NewFormatterChain(cfg, false).Format(response)
+	if err != nil || result != "feature(ui): add toggle" {
+		t.Errorf("Default chain result: got %q, %v", result, err)
+	}
+
+	t.Log("✓ Default chain runs lead-in, fence, subject, type map in order")
+}
+
+func TestReuseBodyFormatter(t *testing.T) {
+	previous := "fix: old subject\n\nExplain why the change was needed.\nSecond line of body.\n\nSigned-off-by: Dev <dev@example.com>"
+	f := reuseBodyFormatter{previous: previous}
+
+	result, err := f.Format("feat(parser): support nested scopes")
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := "feat(parser): support nested scopes\n\nExplain why the change was needed.\nSecond line of body.\n\nSigned-off-by: Dev <dev@example.com>"
+	if result != expected {
+		t.Errorf("Body not preserved:\n  got: %q\n  expected: %q", result, expected)
+	}
+
+	if result := spliceBody("feat: x", "fix: subject only"); result != "feat: x" {
+		t.Errorf("Subject-only previous message: got %q", result)
+	}
+
+	t.Log("✓ Previous body survives while subject is replaced")
+}
+
+func TestIssueFooterFormatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyword  string
+		input    string
+		expected string
+	}{
+		{"fix uses Fixes", "", "fix(parser): handle nil input", "fix(parser): handle nil input\n\nFixes #123"},
+		{"feat uses Refs", "", "feat: add export button", "feat: add export button\n\nRefs #123"},
+		{"configured keyword", "Closes", "feat: add export button", "feat: add export button\n\nCloses #123"},
+		{"joins trailer block", "", "fix: x\n\nbody\n\nSigned-off-by: A <a@example.com>", "fix: x\n\nbody\n\nSigned-off-by: A <a@example.com>\nFixes #123"},
+		{"already referenced", "", "fix: x\n\nFixes #123", "fix: x\n\nFixes #123"},
+		{"gitmoji fix uses Fixes", "", "🐛 fix(parser): handle nil input", "🐛 fix(parser): handle nil input\n\nFixes #123"},
+	}
+
+	for _, tt := range tests {
+		f := issueFooterFormatter{issue: "123", keyword: tt.keyword}
+		result, err := f.Format(tt.input)
+		if err != nil || result != tt.expected {
+			t.Errorf("%s:\n  got: %q\n  expected: %q", tt.name, result, tt.expected)
+		}
+	}
+
+	t.Log("✓ Issue footers appended with the right keyword")
+}
+
+func TestBreakingFormatter(t *testing.T) {
+	changes := []string{"removed func Fetch (api/client.go)", "changed type Options (api/client.go)"}
+	footer := "BREAKING CHANGE: removed func Fetch (api/client.go); changed type Options (api/client.go)"
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"adds marker and footer", "feat(api): drop Fetch", "feat(api)!: drop Fetch\n\n" + footer},
+		{"keeps model footer", "feat!: drop Fetch\n\nBREAKING CHANGE: use Get instead", "feat!: drop Fetch\n\nBREAKING CHANGE: use Get instead"},
+		{"joins trailer block", "refactor: x\n\nbody\n\nRefs #12", "refactor!: x\n\nbody\n\nRefs #12\n" + footer},
+		{"non-conventional subject", "Drop Fetch", "Drop Fetch\n\n" + footer},
+		{"gitmoji subject", "💥 feat(api): drop Fetch", "💥 feat(api)!: drop Fetch\n\n" + footer},
+	}
+
+	for _, tt := range tests {
+		result, err := breakingFormatter{changes: changes}.Format(tt.input)
+		if err != nil || result != tt.expected {
+			t.Errorf("%s:\n  got: %q\n  expected: %q", tt.name, result, tt.expected)
+		}
+	}
+
+	if result, _ := (breakingFormatter{}).Format("feat: x"); result != "feat: x" {
+		t.Errorf("No detected changes should leave the message alone: %q", result)
+	}
+}
+
+func TestCoAuthorFormatter(t *testing.T) {
+	f := coAuthorFormatter{authors: []string{"Jane Doe <jane@example.com>", " Sam Lee <sam@example.com> ", "Jane Doe <jane@example.com>"}}
+
+	result, err := f.Format("feat: pair on export\n\nAdd CSV output.")
+	expected := "feat: pair on export\n\nAdd CSV output.\n\nCo-authored-by: Jane Doe <jane@example.com>\nCo-authored-by: Sam Lee <sam@example.com>"
+	if err != nil || result != expected {
+		t.Errorf("Multiple co-authors:\n  got: %q\n  expected: %q", result, expected)
+	}
+
+	credited := "fix: x\n\nCo-authored-by: Sam Lee <sam@example.com>"
+	result, _ = f.Format(credited)
+	if strings.Count(result, "Sam Lee") != 1 || !strings.HasSuffix(result, "Co-authored-by: Jane Doe <jane@example.com>") {
+		t.Errorf("Existing co-author should not be duplicated: %q", result)
+	}
+}
+
+func TestSignoffFormatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"subject only", "feat: add export button", "feat: add export button\n\nSigned-off-by: Jane Doe <jane@example.com>"},
+		{"after body", "fix: x\n\nExplain why.\n", "fix: x\n\nExplain why.\n\nSigned-off-by: Jane Doe <jane@example.com>"},
+		{"joins trailer block", "fix: x\n\nbody\n\nRefs #12", "fix: x\n\nbody\n\nRefs #12\nSigned-off-by: Jane Doe <jane@example.com>"},
+		{"already signed", "fix: x\n\nSigned-off-by: Jane Doe <jane@example.com>", "fix: x\n\nSigned-off-by: Jane Doe <jane@example.com>"},
+		{"other signer kept", "fix: x\n\nSigned-off-by: A <a@example.com>", "fix: x\n\nSigned-off-by: A <a@example.com>\nSigned-off-by: Jane Doe <jane@example.com>"},
+	}
+
+	for _, tt := range tests {
+		f := signoffFormatter{identity: "Jane Doe <jane@example.com>"}
+		result, err := f.Format(tt.input)
+		if err != nil || result != tt.expected {
+			t.Errorf("%s:\n  got: %q\n  expected: %q", tt.name, result, tt.expected)
+		}
+	}
+
+	t.Log("✓ Sign-off trailer appended once")
+}
+
+func TestExtractIssueNumber(t *testing.T) {
+	tests := map[string]string{
+		"fix/123-nil-pointer":  "123",
+		"feature/issue-42":     "42",
+		"gh-7_login":           "7",
+		"bugfix/#99":           "99",
+		"main":                 "",
+		"feature/oauth2-login": "",
+		"fix/upgrade-node-18":  "",
+		"release/1.2.3":        "",
+		"deps/bump-go-1.22":    "",
+		"release/v2":           "",
+		"42-quick-fix":         "42",
+	}
+
+	for branch, expected := range tests {
+		if got := extractIssueNumber(branch); got != expected {
+			t.Errorf("extractIssueNumber(%q): got %q, expected %q", branch, got, expected)
+		}
+	}
+}
+
+func TestTicketFormatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		position string
+		input    string
+		expected string
+	}{
+		{"trailer", "trailer", "feat: add login", "feat: add login\n\nRefs: PROJ-123"},
+		{"default position", "", "feat: add login\n\nBody.", "feat: add login\n\nBody.\n\nRefs: PROJ-123"},
+		{"scope", "scope", "feat(auth): add login", "feat(PROJ-123): add login"},
+		{"scope without type", "scope", "Add login", "Add login\n\nRefs: PROJ-123"},
+		{"gitmoji scope", "scope", "✨ feat(auth): add login", "✨ feat(PROJ-123): add login"},
+		{"already referenced", "trailer", "feat: add login\n\nRefs: PROJ-123", "feat: add login\n\nRefs: PROJ-123"},
+	}
+
+	for _, tt := range tests {
+		f := ticketFormatter{ticket: "PROJ-123", position: tt.position}
+		result, err := f.Format(tt.input)
+		if err != nil || result != tt.expected {
+			t.Errorf("%s:\n  got: %q\n  expected: %q", tt.name, result, tt.expected)
+		}
+	}
+
+	t.Log("✓ Ticket added as trailer or scope")
+}
+
+func TestExtractTicket(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		branch   string
+		expected string
+	}{
+		{`[A-Z]+-[0-9]+`, "feature/PROJ-123-login", "PROJ-123"},
+		{`^[a-z]+/([0-9]+)`, "fix/42-nil", "42"},
+		{`[A-Z]+-[0-9]+`, "main", ""},
+	}
+
+	for _, tt := range tests {
+		if got := extractTicket(tt.branch, regexp.MustCompile(tt.pattern)); got != tt.expected {
+			t.Errorf("extractTicket(%q, %q): got %q, expected %q", tt.branch, tt.pattern, got, tt.expected)
+		}
+	}
+}
+
+func TestTicketPatternValidation(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := *config.Get()
+
+	cfg.Generation.TicketPattern = "[A-Z+-("
+	if _, err := NewGenerator(&cfg, nil).ticketPattern(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for an invalid regex, got %v", err)
+	}
+
+	cfg.Generation.TicketPattern = "[A-Z]+-[0-9]+"
+	cfg.Generation.TicketPosition = "subject"
+	if _, err := NewGenerator(&cfg, nil).ticketPattern(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for an unknown position, got %v", err)
+	}
+
+	cfg.Generation.TicketPosition = "scope"
+	if re, err := NewGenerator(&cfg, nil).ticketPattern(); err != nil || re == nil {
+		t.Errorf("Expected a compiled pattern, got %v, %v", re, err)
+	}
+
+	t.Log("✓ Invalid ticket settings rejected")
+}
+
+func TestMergeTemplateTrailers(t *testing.T) {
+	template := "\n# Why is this change needed?\nExplain the change here.\n\nRefs:\nReviewed-by: Team Lead <lead@example.com>\n"
+
+	got := MergeTemplateTrailers("feat: add login\n\nBody text.", template, "#")
+	want := "feat: add login\n\nBody text.\n\nRefs:\nReviewed-by: Team Lead <lead@example.com>"
+	if got != want {
+		t.Errorf("MergeTemplateTrailers = %q, want %q", got, want)
+	}
+
+	again := MergeTemplateTrailers(got, template, "#")
+	if again != got {
+		t.Errorf("Trailers already present should not be repeated, got %q", again)
+	}
+
+	if got := MergeTemplateTrailers("fix: typo", "", "#"); got != "fix: typo" {
+		t.Errorf("An empty template should leave the message unchanged, got %q", got)
+	}
+}
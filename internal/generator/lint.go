@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/avgt93/commit-gen/internal/config"
+)
+
+// conventionalTypes are the commit types the built-in style guides allow.
+var conventionalTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "chore"}
+
+// lintExemptPrefixes start subjects that git or its tools write, which are
+// not expected to follow a style.
+var lintExemptPrefixes = []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "}
+
+/**
+ * LintMessage checks a commit message against the configured style: the
+ * subject format ("type(scope): description" for the conventional, detailed,
+ * and gitmoji styles), known types, generation.max_subject_length, and a
+ * blank line between the subject and the body. Custom styles only get the
+ * length and blank line checks, and merge, revert, and fixup subjects are
+ * not checked at all.
+ *
+ * @param message - The commit message without git comment lines
+ * @param cfg - Supplies generation.style, max_subject_length, type_map, and custom_styles
+ * @returns One description per violation; empty if the message is valid
+ */
+func LintMessage(message string, cfg *config.Config) []string {
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	subject := strings.TrimSpace(lines[0])
+	if subject == "" {
+		return []string{"message is empty"}
+	}
+	for _, prefix := range lintExemptPrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return nil
+		}
+	}
+
+	var violations []string
+	if limit := cfg.Generation.MaxSubjectLength; limit > 0 {
+		if length := utf8.RuneCountInString(subject); length > limit {
+			violations = append(violations, fmt.Sprintf("subject is %d characters long; the limit is %d", length, limit))
+		}
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		violations = append(violations, "line 2 must be blank to separate the subject from the body")
+	}
+
+	style := cfg.Generation.Style
+	if isCustomStyle(style, cfg.Generation.CustomStyles) {
+		return violations
+	}
+	switch style {
+	case "imperative":
+		violations = append(violations, lintImperativeSubject(subject)...)
+	case "gitmoji":
+		emoji, rest, _ := strings.Cut(subject, " ")
+		if first, _ := utf8.DecodeRuneInString(emoji); first < utf8.RuneSelf {
+			violations = append(violations, `subject must start with a gitmoji, e.g. "✨ feat(auth): add login"`)
+			rest = subject
+		}
+		violations = append(violations, lintConventionalSubject(rest, cfg.Generation.TypeMap)...)
+	default:
+		violations = append(violations, lintConventionalSubject(subject, cfg.Generation.TypeMap)...)
+	}
+	return violations
+}
+
+// isCustomStyle reports whether style is defined in generation.custom_styles.
+func isCustomStyle(style string, custom map[string]string) bool {
+	for _, name := range []string{style, strings.ToLower(style)} {
+		if guide, ok := custom[name]; ok && strings.TrimSpace(guide) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * lintConventionalSubject checks a "type(scope): description" subject. The
+ * type must be one of the style guide's types or a generation.type_map value.
+ */
+func lintConventionalSubject(subject string, typeMap map[string]string) []string {
+	match := typePrefixPattern.FindStringSubmatch(subject)
+	if match == nil || !strings.HasPrefix(subject, match[1]) {
+		return []string{fmt.Sprintf("subject %q does not match \"type(scope): description\"", subject)}
+	}
+
+	var violations []string
+	allowed := knownTypes(typeMap)
+	if commitType := match[1]; !containsString(allowed, commitType) {
+		violations = append(violations, fmt.Sprintf("unknown type %q; expected one of: %s", commitType, strings.Join(allowed, ", ")))
+	}
+	if match[2] == "()" {
+		violations = append(violations, "scope is empty; remove the parentheses or name a scope")
+	}
+	description := subject[len(match[0]):]
+	if !strings.HasPrefix(description, " ") || strings.TrimSpace(description) == "" {
+		violations = append(violations, "a description must follow the colon and a space")
+	}
+	return violations
+}
+
+// lintImperativeSubject checks that a subject starts with a capitalized verb
+// rather than a conventional type.
+func lintImperativeSubject(subject string) []string {
+	if typePrefixPattern.MatchString(subject) {
+		return []string{`imperative subjects have no type prefix; start with a verb, e.g. "Add login page"`}
+	}
+	if first, _ := utf8.DecodeRuneInString(subject); !unicode.IsUpper(first) {
+		return []string{`subject must start with a capitalized verb, e.g. "Add login page"`}
+	}
+	return nil
+}
+
+// knownTypes returns the style guide's types plus any generation.type_map targets.
+func knownTypes(typeMap map[string]string) []string {
+	types := append([]string{}, conventionalTypes...)
+	var mapped []string
+	for _, target := range typeMap {
+		if target != "" && !containsString(types, target) && !containsString(mapped, target) {
+			mapped = append(mapped, target)
+		}
+	}
+	sort.Strings(mapped)
+	return append(types, mapped...)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
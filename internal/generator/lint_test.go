@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/avgt93/commit-gen/internal/config"
+)
+
+func TestLintMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		style   string
+		message string
+		want    []string // fragments of the expected violations, in order
+	}{
+		{name: "conventional", message: "feat(auth): add login page"},
+		{name: "conventional with body", message: "fix: handle empty response\n\nThe API returns 204 for missing users."},
+		{name: "breaking change", message: "refactor(api)!: drop the v1 endpoints"},
+		{name: "merge subjects are exempt", message: "Merge branch 'main' into feature"},
+		{name: "missing type", message: "add login page", want: []string{"does not match"}},
+		{name: "unknown type", message: "feature: add login page", want: []string{`unknown type "feature"`}},
+		{name: "empty scope", message: "feat(): add login page", want: []string{"scope is empty"}},
+		{name: "missing description", message: "feat:", want: []string{"description must follow"}},
+		{
+			name:    "long subject and no blank line",
+			message: "feat: " + strings.Repeat("x", 80) + "\nbody starts here",
+			want:    []string{"86 characters long; the limit is 72", "line 2 must be blank"},
+		},
+		{name: "empty", message: "\n\n", want: []string{"message is empty"}},
+		{name: "gitmoji", style: "gitmoji", message: "✨ feat(auth): add login page"},
+		{name: "gitmoji without emoji", style: "gitmoji", message: "feat: add login page", want: []string{"must start with a gitmoji"}},
+		{name: "conventional with emoji", message: "✨ feat: add login page", want: []string{"does not match"}},
+		{name: "imperative", style: "imperative", message: "Add login page"},
+		{name: "imperative with type", style: "imperative", message: "feat: add login page", want: []string{"no type prefix"}},
+		{name: "imperative lowercase", style: "imperative", message: "add login page", want: []string{"capitalized verb"}},
+		{name: "custom style skips format", style: "team", message: "anything goes here"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = config.Initialize("")
+			cfg := *config.Get()
+			cfg.Generation.Style = "conventional"
+			if tt.style != "" {
+				cfg.Generation.Style = tt.style
+			}
+			cfg.Generation.MaxSubjectLength = 72
+			cfg.Generation.CustomStyles = map[string]string{"team": "Write whatever you like"}
+
+			got := LintMessage(tt.message, &cfg)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d violation(s), got %q", len(tt.want), got)
+			}
+			for i, fragment := range tt.want {
+				if !strings.Contains(got[i], fragment) {
+					t.Errorf("Violation %d = %q, want it to mention %q", i, got[i], fragment)
+				}
+			}
+		})
+	}
+}
+
+func TestLintMessageAcceptsMappedTypes(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := *config.Get()
+	cfg.Generation.Style = "conventional"
+	cfg.Generation.TypeMap = map[string]string{"feat": "feature"}
+
+	if got := LintMessage("feature: add login page", &cfg); len(got) != 0 {
+		t.Errorf("Expected a generation.type_map target to be a known type, got %q", got)
+	}
+}
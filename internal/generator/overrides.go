@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"path"
+	"strings"
+
+	"github.com/avgt93/commit-gen/internal/config"
+)
+
+/**
+ * matchOverride returns the first override whose glob matches more than half
+ * of the changed files.
+ *
+ * @param overrides - The configured override rules, in priority order
+ * @param files - The staged file paths
+ * @returns The matching rule, or nil to keep the global settings
+ */
+func matchOverride(overrides []config.Override, files []string) *config.Override {
+	if len(files) == 0 {
+		return nil
+	}
+
+	for i, rule := range overrides {
+		matched := 0
+		for _, f := range files {
+			if matchGlob(rule.PathGlob, f) {
+				matched++
+			}
+		}
+		if matched*2 > len(files) {
+			return &overrides[i]
+		}
+	}
+	return nil
+}
+
+/**
+ * matchGlob matches a slash-separated path against a glob. A trailing "/**"
+ * matches everything below that directory; other patterns use path.Match.
+ */
+func matchGlob(pattern, file string) bool {
+	if pattern == "" {
+		return false
+	}
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		if matched, _ := path.Match(dir, file); matched {
+			return true
+		}
+		for d := path.Dir(file); d != "." && d != "/"; d = path.Dir(d) {
+			if matched, _ := path.Match(dir, d); matched {
+				return true
+			}
+		}
+		return false
+	}
+	matched, _ := path.Match(pattern, file)
+	return matched
+}
+
+/**
+ * withOverride returns a copy of cfg with the rule's style and model applied.
+ */
+func withOverride(cfg *config.Config, rule *config.Override) *config.Config {
+	overridden := *cfg
+	if rule.Style != "" {
+		overridden.Generation.Style = rule.Style
+	}
+	if rule.Model.Provider != "" {
+		overridden.Generation.Model.Provider = rule.Model.Provider
+	}
+	if rule.Model.ModelID != "" {
+		overridden.Generation.Model.ModelID = rule.Model.ModelID
+	}
+	return &overridden
+}
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prTemplatePaths lists where GitHub looks for a pull request template, relative to the repo root.
+var prTemplatePaths = []string{
+	".github/PULL_REQUEST_TEMPLATE.md",
+	".github/pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+}
+
+/**
+ * prTemplateHeadings returns the markdown section headings of the first pull
+ * request template found under root.
+ *
+ * @param root - The repository root
+ * @returns The heading texts in order, or nil if no template exists
+ */
+func prTemplateHeadings(root string) []string {
+	for _, rel := range prTemplatePaths {
+		data, err := os.ReadFile(filepath.Join(root, rel))
+		if err != nil {
+			continue
+		}
+
+		var headings []string
+		for _, line := range strings.Split(string(data), "\n") {
+			trimmed := strings.TrimSpace(line)
+			if !strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			if heading := strings.TrimSpace(strings.TrimLeft(trimmed, "#")); heading != "" {
+				headings = append(headings, heading)
+			}
+		}
+		return headings
+	}
+	return nil
+}
+
+/**
+ * prTemplateNote suggests the PR template's sections as the commit body structure.
+ *
+ * @param headings - The PR template headings
+ * @returns The prompt note, or empty string if there are no headings
+ */
+func prTemplateNote(headings []string) string {
+	if len(headings) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\nStructure the commit body using these sections from the repository's pull request template, skipping any that do not apply:\n")
+	for _, h := range headings {
+		sb.WriteString("- " + h + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"strings"
+)
+
+/**
+ * releaseSection describes a heading in the release notes and the commit types it collects.
+ */
+type releaseSection struct {
+	title string
+	types []string
+}
+
+var releaseSections = []releaseSection{
+	{title: "Features", types: []string{"feat", "feature"}},
+	{title: "Fixes", types: []string{"fix", "bugfix"}},
+}
+
+/**
+ * BuildReleaseNotes groups commit subjects by conventional type and renders
+ * them as a markdown release-notes block with Features, Fixes, and Other sections.
+ * Empty sections are omitted.
+ *
+ * @param subjects - Commit subjects, newest first as printed by git log
+ * @returns The markdown release notes
+ */
+func BuildReleaseNotes(subjects []string) string {
+	grouped := make(map[string][]string)
+	var other []string
+
+	for _, subject := range subjects {
+		subject = strings.TrimSpace(subject)
+		if subject == "" {
+			continue
+		}
+
+		section, entry := classifySubject(subject)
+		if section == "" {
+			other = append(other, entry)
+			continue
+		}
+		grouped[section] = append(grouped[section], entry)
+	}
+
+	var sb strings.Builder
+	writeSection := func(title string, entries []string) {
+		if len(entries) == 0 {
+			return
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("### " + title + "\n\n")
+		for _, entry := range entries {
+			sb.WriteString("- " + entry + "\n")
+		}
+	}
+
+	for _, section := range releaseSections {
+		writeSection(section.title, grouped[section.title])
+	}
+	writeSection("Other", other)
+
+	return sb.String()
+}
+
+/**
+ * classifySubject returns the release section for a subject and the entry to list under it.
+ * Subjects that don't belong to a named section return an empty section and are listed verbatim.
+ */
+func classifySubject(subject string) (string, string) {
+	match := typePrefixPattern.FindStringSubmatch(subject)
+	if match == nil {
+		return "", subject
+	}
+
+	commitType := strings.ToLower(match[1])
+	for _, section := range releaseSections {
+		for _, t := range section.types {
+			if commitType != t {
+				continue
+			}
+			description := strings.TrimSpace(subject[len(match[0]):])
+			if scope := strings.Trim(match[2], "()"); scope != "" {
+				description = "**" + scope + ":** " + description
+			}
+			return section.title, description
+		}
+	}
+
+	return "", subject
+}
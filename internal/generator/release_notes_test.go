@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"testing"
+)
+
+func TestBuildReleaseNotesGroupsByType(t *testing.T) {
+	subjects := []string{
+		"feat(auth): add login page",
+		"fix: handle nil pointer in parser",
+		"docs: update README",
+		"feat: support dark mode",
+		"Merge branch 'main'",
+		"fix(api)!: reject invalid tokens",
+		"✨ feat: add gitmoji support",
+	}
+
+	expected := `### Features
+
+- **auth:** add login page
+- support dark mode
+- add gitmoji support
+
+### Fixes
+
+- handle nil pointer in parser
+- **api:** reject invalid tokens
+
+### Other
+
+- docs: update README
+- Merge branch 'main'
+`
+
+	notes := BuildReleaseNotes(subjects)
+	if notes != expected {
+		t.Errorf("Release notes mismatch:\n--- got ---\n%s\n--- expected ---\n%s", notes, expected)
+	} else {
+		t.Log("✓ Release notes grouped by conventional type")
+	}
+}
+
+func TestBuildReleaseNotesOmitsEmptySections(t *testing.T) {
+	notes := BuildReleaseNotes([]string{"fix: correct typo", ""})
+
+	expected := "### Fixes\n\n- correct typo\n"
+	if notes != expected {
+		t.Errorf("Release notes mismatch: got %q, expected %q", notes, expected)
+	}
+
+	if BuildReleaseNotes(nil) != "" {
+		t.Error("Expected empty release notes for no commits")
+	}
+
+	t.Log("✓ Empty sections omitted")
+}
@@ -0,0 +1,210 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/avgt93/commit-gen/internal/git"
+)
+
+// maxSplitFiles is the most files generation.on_summarized "split" describes
+// one by one; larger changes fall back to the summarized diff.
+const maxSplitFiles = 20
+
+/**
+ * fileSummary is the model's one-line description of one file's changes.
+ */
+type fileSummary struct {
+	Path    string
+	Summary string
+}
+
+/**
+ * filePrompt is the prompt describing one file's changes.
+ */
+type filePrompt struct {
+	Path   string
+	Prompt string
+}
+
+// splitSummaryEstimate is the length assumed for each per-file summary when
+// estimating the synthesis prompt before the summaries exist.
+const splitSummaryEstimate = 100
+
+/**
+ * fileSummaryPrompts builds the per-file prompts, skipping files whose diff
+ * is empty.
+ *
+ * @param files - The changed files, in diff order
+ * @param fileDiff - Returns the diff of a single file
+ * @returns One prompt per file with a non-empty diff
+ */
+func fileSummaryPrompts(files []string, fileDiff func(path string) (string, error)) ([]filePrompt, error) {
+	var prompts []filePrompt
+	for _, path := range files {
+		diff, err := fileDiff(path)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(diff) == "" {
+			continue
+		}
+		prompts = append(prompts, filePrompt{Path: path, Prompt: fileSummaryPrompt(path, diff)})
+	}
+	return prompts, nil
+}
+
+/**
+ * summarizeFiles asks for a one-line summary of each file in turn.
+ *
+ * @param prompts - The per-file prompts (see fileSummaryPrompts)
+ * @param send - Sends a prompt and returns the model response
+ * @returns One summary per prompt
+ */
+func summarizeFiles(prompts []filePrompt, send func(prompt string) (string, error)) ([]fileSummary, error) {
+	var summaries []fileSummary
+	for _, prompt := range prompts {
+		response, err := send(prompt.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize %s: %w", prompt.Path, err)
+		}
+		summaries = append(summaries, fileSummary{Path: prompt.Path, Summary: firstLine(response)})
+	}
+	return summaries, nil
+}
+
+/**
+ * fileSummaryPrompt asks for a one-line description of a single file's diff.
+ */
+func fileSummaryPrompt(path, diff string) string {
+	return fmt.Sprintf(`Describe the following change to %s in one short line (under 100 characters), focusing on what changed and why. Output only that line.
+
+%s`, path, diff)
+}
+
+/**
+ * synthesisInput lists the per-file summaries in place of the diff, for the
+ * final call that combines them into one commit message.
+ */
+func synthesisInput(summaries []fileSummary) string {
+	var sb strings.Builder
+	sb.WriteString("NOTE: The diff was too large to send at once, so each file was described separately. Write a single commit message that covers all of these changes:\n\n")
+	for _, summary := range summaries {
+		fmt.Fprintf(&sb, "- %s: %s\n", summary.Path, summary.Summary)
+	}
+	return sb.String()
+}
+
+/**
+ * firstLine returns the first non-empty line of a response without list
+ * markers, backticks, or surrounding quotes.
+ */
+func firstLine(response string) string {
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*"))
+		if line != "" && !strings.HasPrefix(line, "```") {
+			return unwrapCandidate(line)
+		}
+	}
+	return ""
+}
+
+/**
+ * splitPrompt describes each changed file with its own model call and builds
+ * the prompt that synthesizes them into one message. It applies only to a
+ * summarized diff of 2 to maxSplitFiles files; otherwise ok is false and the
+ * summarized prompt should be used.
+ *
+ * @param diffResult - The summarized diff
+ * @returns The synthesis prompt, the number of files described, and whether splitting applied
+ */
+func (g *Generator) splitPrompt(diffResult *git.DiffResult) (string, int, bool, error) {
+	if !diffResult.IsSummarized || len(diffResult.Files) < 2 || len(diffResult.Files) > maxSplitFiles {
+		return "", 0, false, nil
+	}
+
+	fileDiff, err := g.fileDiffSource()
+	if err != nil {
+		return "", 0, false, err
+	}
+
+	prompts, err := fileSummaryPrompts(diffResult.Files, fileDiff)
+	if err != nil {
+		return "", 0, false, err
+	}
+	if len(prompts) == 0 || !g.splitWithinBudget(prompts) {
+		return "", 0, false, nil
+	}
+
+	summaries, err := summarizeFiles(prompts, g.send)
+	if err != nil {
+		return "", 0, false, err
+	}
+	return g.buildPrompt(synthesisInput(summaries), false), len(summaries), true, nil
+}
+
+/**
+ * splitWithinBudget checks the per-file prompts plus an estimate of the
+ * synthesis prompt against generation.max_cost_tokens, so splitting cannot
+ * bypass the budget the summarized prompt was checked against.
+ *
+ * @param prompts - The per-file prompts
+ * @returns false if the split calls would exceed the budget without --force
+ */
+func (g *Generator) splitWithinBudget(prompts []filePrompt) bool {
+	var sb strings.Builder
+	placeholders := make([]fileSummary, len(prompts))
+	for i, prompt := range prompts {
+		sb.WriteString(prompt.Prompt)
+		placeholders[i] = fileSummary{Path: prompt.Path, Summary: strings.Repeat("x", splitSummaryEstimate)}
+	}
+	sb.WriteString(g.buildPrompt(synthesisInput(placeholders), false))
+
+	if err := g.checkBudget(sb.String()); err != nil {
+		fmt.Fprintf(os.Stderr, "Describing files one by one would exceed generation.max_cost_tokens; using the summarized diff\n")
+		return false
+	}
+	return true
+}
+
+/**
+ * fileDiffSource returns a function producing one file's full diff: a
+ * section of the diff passed to SetDiff, or a fresh git diff of that path.
+ */
+func (g *Generator) fileDiffSource() (func(path string) (string, error), error) {
+	if g.providedDiff != nil {
+		sections := map[string]string{}
+		for _, file := range git.GroupDiffByFile(*g.providedDiff) {
+			sections[file.Path] = file.Body
+		}
+		return func(path string) (string, error) {
+			result, err := git.LimitProvidedDiff(sections[path], g.maxDiffSize(), 0)
+			if err != nil {
+				return "", err
+			}
+			return result.Diff, nil
+		}, nil
+	}
+
+	opts, err := g.resolvedDiffOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts.CollapseThreshold = 0
+
+	return func(path string) (string, error) {
+		fileOpts := opts
+		// Diff paths are relative to the repository root unless --relative is set.
+		if opts.Relative {
+			fileOpts.Paths = []string{path}
+		} else {
+			fileOpts.Paths = []string{":/" + path}
+		}
+		result, err := git.GetStagedDiffWithOptions(g.maxDiffSize(), fileOpts)
+		if err != nil {
+			return "", err
+		}
+		return result.Diff, nil
+	}, nil
+}
@@ -0,0 +1,163 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avgt93/commit-gen/internal/cache"
+	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/avgt93/commit-gen/internal/git/gittest"
+	"github.com/avgt93/commit-gen/internal/llm/openai/openaitest"
+)
+
+func TestSummarizeFiles(t *testing.T) {
+	diffs := map[string]string{
+		"api/handler.go": "diff --git a/api/handler.go b/api/handler.go\n+func Handle() {}\n",
+		"docs/empty.md":  "",
+		"web/app.ts":     "diff --git a/web/app.ts b/web/app.ts\n+export const app = 1\n",
+	}
+	var prompts []string
+	send := func(prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return fmt.Sprintf("\n- `summary %d`\n\nextra prose", len(prompts)), nil
+	}
+
+	filePrompts, err := fileSummaryPrompts([]string{"api/handler.go", "docs/empty.md", "web/app.ts"}, func(path string) (string, error) {
+		return diffs[path], nil
+	})
+	if err != nil {
+		t.Fatalf("fileSummaryPrompts failed: %v", err)
+	}
+	summaries, err := summarizeFiles(filePrompts, send)
+	if err != nil {
+		t.Fatalf("summarizeFiles failed: %v", err)
+	}
+
+	expected := []fileSummary{{"api/handler.go", "summary 1"}, {"web/app.ts", "summary 2"}}
+	if len(summaries) != len(expected) {
+		t.Fatalf("Expected %d summaries, got %+v", len(expected), summaries)
+	}
+	for i := range expected {
+		if summaries[i] != expected[i] {
+			t.Errorf("Summary %d: got %+v, expected %+v", i, summaries[i], expected[i])
+		}
+	}
+	if len(prompts) != 2 || !strings.Contains(prompts[0], "api/handler.go") || !strings.Contains(prompts[0], "+func Handle() {}") {
+		t.Errorf("Unexpected per-file prompts: %q", prompts)
+	}
+
+	input := synthesisInput(summaries)
+	if !strings.Contains(input, "- api/handler.go: summary 1\n- web/app.ts: summary 2\n") {
+		t.Errorf("Synthesis input missing summaries: %q", input)
+	}
+}
+
+func TestSummarizeFilesStopsOnError(t *testing.T) {
+	calls := 0
+	filePrompts := []filePrompt{{"a.go", "diff a.go"}, {"b.go", "diff b.go"}}
+	_, err := summarizeFiles(filePrompts, func(prompt string) (string, error) {
+		calls++
+		return "", errors.New("backend down")
+	})
+	if err == nil || !strings.Contains(err.Error(), "a.go") || calls != 1 {
+		t.Errorf("Expected to stop at the first failure naming the file, got %v after %d calls", err, calls)
+	}
+}
+
+// stubSplitServer answers per-file prompts with "update <path>" and any
+// other prompt with reply, recording every prompt.
+func stubSplitServer(t *testing.T, reply string, prompts *[]string) config.Config {
+	cfg := openaitest.Config(t, func(req openaitest.Request) openaitest.Response {
+		*prompts = append(*prompts, req.Prompt)
+		if rest, ok := strings.CutPrefix(req.Prompt, "Describe the following change to "); ok {
+			path, _, _ := strings.Cut(rest, " in one short line")
+			return openaitest.Response{Content: "update " + path}
+		}
+		return openaitest.Response{Content: reply}
+	})
+	cfg.Git.MaxDiffSize = 64
+	cfg.Git.CollapseLargeFiles = false
+	cfg.Generation.OnSummarized = "split"
+	return cfg
+}
+
+func TestGenerateSplitsSummarizedDiff(t *testing.T) {
+	var prompts []string
+	cfg := stubSplitServer(t, "feat: add app packages", &prompts)
+	gittest.StagedRepo(t, "cmd/app/main.go", "internal/store/store.go", "web/index.go")
+
+	result, err := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir())).GenerateResult()
+	if err != nil {
+		t.Fatalf("GenerateResult failed: %v", err)
+	}
+
+	if result.Message != "feat: add app packages" {
+		t.Errorf("Message mismatch: got %q", result.Message)
+	}
+	if result.SplitFiles != 3 || result.IsSummarized {
+		t.Errorf("Expected 3 split files and no summary, got %d, summarized %v", result.SplitFiles, result.IsSummarized)
+	}
+	if len(prompts) != 4 {
+		t.Fatalf("Expected 3 per-file calls and 1 synthesis call, got %d", len(prompts))
+	}
+	final := prompts[3]
+	for _, line := range []string{"- cmd/app/main.go: update cmd/app/main.go", "- web/index.go: update web/index.go"} {
+		if !strings.Contains(final, line) {
+			t.Errorf("Synthesis prompt missing %q", line)
+		}
+	}
+	if strings.Contains(final, "DIFF SUMMARY") {
+		t.Error("Synthesis prompt should not include the summarized diff")
+	}
+}
+
+func TestGenerateSplitFallsBackToSummaryForManyFiles(t *testing.T) {
+	var prompts []string
+	cfg := stubSplitServer(t, "chore: add generated files", &prompts)
+	files := make([]string, maxSplitFiles+1)
+	for i := range files {
+		files[i] = fmt.Sprintf("gen/file%02d.go", i)
+	}
+	gittest.StagedRepo(t, files...)
+
+	result, err := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir())).GenerateResult()
+	if err != nil {
+		t.Fatalf("GenerateResult failed: %v", err)
+	}
+
+	if result.SplitFiles != 0 || !result.IsSummarized {
+		t.Errorf("Expected the summary fallback, got %d split files, summarized %v", result.SplitFiles, result.IsSummarized)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "DIFF SUMMARY") {
+		t.Errorf("Expected one summarized prompt, got %d", len(prompts))
+	}
+}
+
+func TestGenerateSplitFallsBackToSummaryOverBudget(t *testing.T) {
+	var prompts []string
+	cfg := stubSplitServer(t, "feat: add app packages", &prompts)
+	gittest.StagedRepo(t, "cmd/app/main.go", "internal/store/store.go", "web/index.go")
+
+	sessionCache := cache.GetCache(24*time.Hour, t.TempDir())
+	report, err := NewGenerator(&cfg, sessionCache).TokenReport()
+	if err != nil {
+		t.Fatalf("TokenReport failed: %v", err)
+	}
+	// The summarized prompt fits, the per-file and synthesis calls together do not
+	cfg.Generation.MaxCostTokens = report.PromptTokens
+
+	result, err := NewGenerator(&cfg, sessionCache).GenerateResult()
+	if err != nil {
+		t.Fatalf("GenerateResult failed: %v", err)
+	}
+
+	if result.SplitFiles != 0 || !result.IsSummarized {
+		t.Errorf("Expected the summary fallback, got %d split files, summarized %v", result.SplitFiles, result.IsSummarized)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "DIFF SUMMARY") {
+		t.Errorf("Expected one summarized prompt, got %d", len(prompts))
+	}
+}
@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"unicode/utf8"
+)
+
+// charsPerToken is the rough average number of characters per token for English text and code.
+const charsPerToken = 4
+
+/**
+ * EstimateTokens returns an approximate token count for text, using the
+ * common heuristic of about four characters per token.
+ *
+ * @param text - The text to estimate
+ * @returns The estimated number of tokens
+ */
+func EstimateTokens(text string) int {
+	chars := utf8.RuneCountInString(text)
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+/**
+ * TokenReport describes the estimated size of the staged diff and the prompt
+ * that would be sent for it.
+ */
+type TokenReport struct {
+	DiffBytes    int  `json:"diff_bytes"`
+	DiffTokens   int  `json:"diff_tokens"`
+	PromptBytes  int  `json:"prompt_bytes"`
+	PromptTokens int  `json:"prompt_tokens"`
+	MaxDiffSize  int  `json:"max_diff_size"`
+	Summarized   bool `json:"summarized"`
+}
+
+/**
+ * TokenReport assembles the prompt for the staged changes without sending it
+ * and estimates its token count.
+ *
+ * @returns The token report
+ * @returns An error if the staged diff cannot be read or is empty
+ */
+func (g *Generator) TokenReport() (*TokenReport, error) {
+	defer g.applyOverrides()()
+
+	diffResult, prompt, err := g.preparePrompt()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenReport{
+		DiffBytes:    diffResult.OriginalSize,
+		DiffTokens:   (diffResult.OriginalSize + charsPerToken - 1) / charsPerToken,
+		PromptBytes:  len(prompt),
+		PromptTokens: EstimateTokens(prompt),
+		MaxDiffSize:  g.maxDiffSize(),
+		Summarized:   diffResult.IsSummarized,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avgt93/commit-gen/internal/cache"
+	"github.com/avgt93/commit-gen/internal/config"
+	"github.com/avgt93/commit-gen/internal/git/gittest"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{strings.Repeat("x", 4000), 1000},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.input); got != tt.expected {
+			t.Errorf("EstimateTokens(%d chars): got %d, expected %d", len(tt.input), got, tt.expected)
+		}
+	}
+
+	t.Log("✓ Token estimates match heuristic")
+}
+
+func TestTokenReportForKnownDiff(t *testing.T) {
+	_ = config.Initialize("")
+	cfg := *config.Get()
+	gittest.StagedRepo(t)
+
+	gen := NewGenerator(&cfg, cache.GetCache(24*time.Hour, t.TempDir()))
+	report, err := gen.TokenReport()
+	if err != nil {
+		t.Fatalf("TokenReport failed: %v", err)
+	}
+
+	// The staged diff adds main.go containing "package main": ~100 bytes of headers and content.
+	if report.DiffBytes < 60 || report.DiffBytes > 200 {
+		t.Errorf("Unexpected diff size: %d bytes", report.DiffBytes)
+	}
+	if report.DiffTokens != EstimateTokens(strings.Repeat("x", report.DiffBytes)) {
+		t.Errorf("Diff tokens %d do not match %d bytes", report.DiffTokens, report.DiffBytes)
+	}
+	if report.PromptTokens <= report.DiffTokens || report.PromptTokens > report.DiffTokens+500 {
+		t.Errorf("Prompt tokens out of bounds: prompt=%d diff=%d", report.PromptTokens, report.DiffTokens)
+	}
+	if report.Summarized {
+		t.Error("Small diff should not be summarized")
+	}
+
+	t.Logf("✓ Token report: diff=%d prompt=%d", report.DiffTokens, report.PromptTokens)
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/avgt93/commit-gen/internal/config"
+)
+
+/**
+ * Usage is the token count the backend reported for a generation, summed
+ * over every request it took (reprompts and context-length retries included).
+ */
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+/**
+ * usageTally sums the usage of each request in one generation. A single
+ * request without usage makes the total unknown.
+ */
+type usageTally struct {
+	total    Usage
+	requests int
+	missing  bool
+}
+
+func (t *usageTally) add(usage *Usage) {
+	t.requests++
+	if usage == nil {
+		t.missing = true
+		return
+	}
+	t.total.PromptTokens += usage.PromptTokens
+	t.total.CompletionTokens += usage.CompletionTokens
+}
+
+/**
+ * result returns the summed usage.
+ *
+ * @returns The usage, or nil if no request was made or any request lacked usage
+ */
+func (t *usageTally) result() *Usage {
+	if t.requests == 0 || t.missing {
+		return nil
+	}
+	total := t.total
+	return &total
+}
+
+/**
+ * lastUsage returns the usage reported for the backend's last reply. Run
+ * mode never reports usage.
+ */
+func (g *Generator) lastUsage() *Usage {
+	switch {
+	case g.client != nil:
+		if usage := g.client.LastUsage(); usage != nil {
+			return &Usage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens}
+		}
+	case g.chat != nil:
+		if usage := g.chat.LastUsage(); usage != nil {
+			return &Usage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens}
+		}
+	}
+	return nil
+}
+
+/**
+ * EstimateCost prices usage with the generation.pricing entry for model.
+ *
+ * @param usage - The reported token usage
+ * @param model - The model as "provider/model"
+ * @param pricing - The configured per-million-token rates
+ * @returns The estimated cost, and false if usage is nil or the model has no rate
+ */
+func EstimateCost(usage *Usage, model string, pricing []config.ModelPricing) (float64, bool) {
+	if usage == nil {
+		return 0, false
+	}
+	_, modelID, _ := strings.Cut(model, "/")
+	for _, price := range pricing {
+		if price.Model == model || (modelID != "" && price.Model == modelID) {
+			cost := float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output
+			return cost / 1e6, true
+		}
+	}
+	return 0, false
+}
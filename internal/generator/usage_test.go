@@ -0,0 +1,54 @@
+package generator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/avgt93/commit-gen/internal/config"
+)
+
+func TestUsageTally(t *testing.T) {
+	var tally usageTally
+	if tally.result() != nil {
+		t.Error("Expected nil usage before any request")
+	}
+
+	tally.add(&Usage{PromptTokens: 1000, CompletionTokens: 40})
+	tally.add(&Usage{PromptTokens: 1100, CompletionTokens: 10})
+	if usage := tally.result(); usage == nil || *usage != (Usage{PromptTokens: 2100, CompletionTokens: 50}) {
+		t.Errorf("Expected summed usage, got %+v", usage)
+	}
+
+	tally.add(nil)
+	if usage := tally.result(); usage != nil {
+		t.Errorf("Expected nil usage when a request lacked it, got %+v", usage)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	pricing := []config.ModelPricing{
+		{Model: "openai/gpt-4o", Input: 2.5, Output: 10},
+		{Model: "gpt-4o-mini", Input: 0.15, Output: 0.6},
+	}
+	usage := &Usage{PromptTokens: 2000, CompletionTokens: 100}
+
+	tests := []struct {
+		model    string
+		expected float64
+		ok       bool
+	}{
+		{"openai/gpt-4o", 0.006, true},
+		{"opencode/gpt-4o-mini", 0.00036, true},
+		{"opencode/gpt-5-nano", 0, false},
+	}
+	for _, tt := range tests {
+		cost, ok := EstimateCost(usage, tt.model, pricing)
+		if ok != tt.ok || math.Abs(cost-tt.expected) > 1e-12 {
+			t.Errorf("%s: got %v, %v; expected %v, %v", tt.model, cost, ok, tt.expected, tt.ok)
+		}
+	}
+
+	if _, ok := EstimateCost(nil, "openai/gpt-4o", pricing); ok {
+		t.Error("Expected no estimate without usage")
+	}
+}
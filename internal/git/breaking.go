@@ -0,0 +1,98 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// goExportedDeclPattern matches an exported Go func, method, or type declaration
+// and captures the method receiver and the name.
+var goExportedDeclPattern = regexp.MustCompile(`^(func|type)\s+(?:\(\s*(?:\w+\s+)?\*?\s*([\w.\[\], ]+?)\s*\)\s*)?([A-Z]\w*)`)
+
+/**
+ * DetectBreakingChanges scans a unified diff for changes that break a
+ * package's public API. Only Go is supported so far: exported functions,
+ * methods, and types that are removed, or whose declaration line changes
+ * (e.g. a new function signature), are reported. Test files are ignored, and
+ * a declaration moved unchanged within the same file is not a change.
+ *
+ * @param diff - The unified diff to scan
+ * @returns One description per breaking change, in diff order, e.g.
+ * "removed func Parse (config/parse.go)"
+ */
+func DetectBreakingChanges(diff string) []string {
+	var changes []string
+	for _, section := range splitDiffByFile(diff) {
+		header, _, _ := strings.Cut(section, "\n")
+		if !strings.HasPrefix(header, "diff --git ") {
+			continue
+		}
+		path := diffSectionPath(header)
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		changes = append(changes, goBreakingChanges(path, section)...)
+	}
+	return changes
+}
+
+/**
+ * goBreakingChanges compares the exported declarations removed from and
+ * added to one Go file's diff section.
+ */
+func goBreakingChanges(path, section string) []string {
+	type decl struct {
+		kind, name, line string
+	}
+	var removed []decl
+	added := map[string]string{}
+
+	for _, line := range strings.Split(section, "\n") {
+		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") || line == "" {
+			continue
+		}
+		sign, code := line[0], strings.TrimSpace(line[1:])
+		if sign != '-' && sign != '+' {
+			continue
+		}
+		match := goExportedDeclPattern.FindStringSubmatch(code)
+		if match == nil {
+			continue
+		}
+		name := match[3]
+		if receiver := strings.TrimSpace(match[2]); receiver != "" {
+			name = receiver + "." + name
+		}
+		key := match[1] + " " + name
+		if sign == '-' {
+			removed = append(removed, decl{kind: match[1], name: name, line: normalizeDecl(code)})
+		} else {
+			added[key] = normalizeDecl(code)
+		}
+	}
+
+	var changes []string
+	for _, d := range removed {
+		now, ok := added[d.kind+" "+d.name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("removed %s %s (%s)", d.kind, d.name, path))
+		case now != d.line:
+			changes = append(changes, fmt.Sprintf("changed %s %s (%s)", d.kind, d.name, path))
+		}
+	}
+	return changes
+}
+
+/**
+ * normalizeDecl reduces a declaration line to its signature: the opening
+ * brace and trailing comment are dropped and whitespace is collapsed.
+ */
+func normalizeDecl(line string) string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSuffix(strings.TrimSpace(line), "{")
+	return strings.Join(strings.Fields(line), " ")
+}
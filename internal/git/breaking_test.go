@@ -0,0 +1,88 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectBreakingChanges(t *testing.T) {
+	diff := `diff --git a/api/client.go b/api/client.go
+--- a/api/client.go
++++ b/api/client.go
+@@ -10,20 +10,18 @@
+-// Fetch returns the resource.
+-func Fetch(id string) (*Resource, error) {
++// Fetch returns the resource.
++func Fetch(ctx context.Context, id string) (*Resource, error) {
+ 	return nil, nil
+ }
+ 
+-func (c *Client) Close() error {
+-	return nil
+-}
+-
+-type Options struct {
++type Options struct { // unchanged apart from the comment
+ 	Timeout int
+ }
+ 
+-func helper() {}
+-func Ping() error {
++func Ping()   error {
+diff --git a/api/client_test.go b/api/client_test.go
+--- a/api/client_test.go
++++ b/api/client_test.go
+@@ -1,3 +1,1 @@
+-func TestFetch(t *testing.T) {}
+diff --git a/lib/util.py b/lib/util.py
+--- a/lib/util.py
++++ b/lib/util.py
+@@ -1,2 +1,1 @@
+-def Public(): pass
+`
+
+	expected := []string{
+		"changed func Fetch (api/client.go)",
+		"removed func Client.Close (api/client.go)",
+	}
+	if got := DetectBreakingChanges(diff); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Breaking changes mismatch:\n  got: %q\n  expected: %q", got, expected)
+	}
+}
+
+func TestDetectBreakingChangesRemovedFile(t *testing.T) {
+	diff := `diff --git a/store/cache.go b/store/cache.go
+deleted file mode 100644
+--- a/store/cache.go
++++ /dev/null
+@@ -1,5 +0,0 @@
+-package store
+-
+-type Cache[K comparable] struct{}
+-
+-func (c Cache[K]) Get(key K) {}
+`
+
+	expected := []string{
+		"removed type Cache (store/cache.go)",
+		"removed func Cache[K].Get (store/cache.go)",
+	}
+	if got := DetectBreakingChanges(diff); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Breaking changes mismatch:\n  got: %q\n  expected: %q", got, expected)
+	}
+}
+
+func TestDetectBreakingChangesIgnoresAdditions(t *testing.T) {
+	diff := `diff --git a/api/new.go b/api/new.go
+new file mode 100644
+--- /dev/null
++++ b/api/new.go
+@@ -0,0 +1,2 @@
++func Serve() {}
++type Server struct{}
+`
+
+	if got := DetectBreakingChanges(diff); len(got) != 0 {
+		t.Errorf("Expected no breaking changes for additions, got %q", got)
+	}
+}
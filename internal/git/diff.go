@@ -1,15 +1,34 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 )
 
 const DefaultMaxDiffSize = 32 * 1024
 
+// runGit runs git with the given arguments and returns its stdout.
+// Tests replace it to count or stub subprocess calls.
+var runGit = func(args ...string) ([]byte, error) {
+	return exec.Command("git", args...).Output()
+}
+
+// runGitWithEnv runs git with extra environment variables; it defers to runGit when env is empty.
+var runGitWithEnv = func(env []string, args ...string) ([]byte, error) {
+	if len(env) == 0 {
+		return runGit(args...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Output()
+}
+
 /**
  * DiffResult contains the diff and metadata about whether it was summarized.
  */
@@ -17,6 +36,145 @@ type DiffResult struct {
 	Diff         string
 	IsSummarized bool
 	OriginalSize int
+	// CollapsedFiles lists files whose diff body was replaced by a size note.
+	CollapsedFiles []string
+	// BinaryFiles lists binary files, whose diff is a one-line size note.
+	BinaryFiles []string
+	// BinaryOnly is true when every changed file is binary.
+	BinaryOnly bool
+	// Files lists the changed paths in diff order.
+	Files []string
+}
+
+/**
+ * DiffOptions controls how the staged diff is produced.
+ */
+type DiffOptions struct {
+	// Relative makes paths relative to the current directory (git diff --relative).
+	Relative bool
+	// Base compares the index against this commit instead of HEAD, e.g. when amending.
+	Base string
+	// Algorithm selects the diff algorithm (myers, minimal, patience, histogram); empty uses git's default.
+	Algorithm string
+	// CollapseThreshold is the per-file diff size in bytes above which a file's
+	// diff body is replaced by a short note when the whole diff is too large.
+	// Zero disables collapsing.
+	CollapseThreshold int
+	// Paths limits the diff to these staged paths; empty includes every staged file.
+	Paths []string
+	// Working diffs the working tree (staged and unstaged tracked changes)
+	// against Base instead of the index; see GetWorkingBase.
+	Working bool
+	// ContextLines sets the lines of context around each change (git diff -U);
+	// nil uses git's default of 3, and zero sends only the changed lines.
+	ContextLines *int
+}
+
+// DefaultExcludePaths are lock files left out of diffs by default; they are
+// large and say nothing about the intent of a change.
+var DefaultExcludePaths = []string{
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"go.sum",
+	"Cargo.lock",
+	"poetry.lock",
+	"composer.lock",
+	"Gemfile.lock",
+}
+
+// excludePaths are the patterns set by SetExcludePaths.
+var excludePaths []string
+
+/**
+ * SetExcludePaths sets glob patterns for files left out of every diff and
+ * file list. Patterns without a slash match the file name in any directory;
+ * others are matched from the repository root.
+ *
+ * @param patterns - The patterns to exclude; empty excludes nothing
+ */
+func SetExcludePaths(patterns []string) {
+	excludePaths = patterns
+}
+
+/**
+ * excludePathspecs turns the configured patterns into git exclude pathspecs.
+ */
+func excludePathspecs() []string {
+	var specs []string
+	for _, pattern := range excludePaths {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if strings.Contains(pattern, "/") {
+			specs = append(specs, ":(top,exclude,glob)"+strings.TrimPrefix(pattern, "/"))
+		} else {
+			specs = append(specs, ":(top,exclude,glob)**/"+pattern)
+		}
+	}
+	return specs
+}
+
+// DiffAlgorithms lists the values accepted for DiffOptions.Algorithm.
+var DiffAlgorithms = []string{"myers", "minimal", "patience", "histogram"}
+
+/**
+ * validate checks that the options are supported.
+ *
+ * @returns An error if the diff algorithm is unknown or the context is negative
+ */
+func (o DiffOptions) validate() error {
+	if o.ContextLines != nil && *o.ContextLines < 0 {
+		return fmt.Errorf("context lines must not be negative, got %d", *o.ContextLines)
+	}
+	if o.Algorithm == "" {
+		return nil
+	}
+	for _, a := range DiffAlgorithms {
+		if o.Algorithm == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown diff algorithm %q (expected one of: %s)", o.Algorithm, strings.Join(DiffAlgorithms, ", "))
+}
+
+/**
+ * stagedArgs builds the git arguments for a staged diff with these options.
+ *
+ * @param extra - Additional arguments such as --stat or --name-only
+ * @returns The full argument list for runGit
+ */
+func (o DiffOptions) stagedArgs(extra ...string) []string {
+	args := []string{"diff", "--staged"}
+	if o.Working {
+		args = []string{"diff"}
+	}
+	if o.Relative {
+		args = append(args, "--relative")
+	}
+	if o.Algorithm != "" {
+		args = append(args, "--diff-algorithm="+o.Algorithm)
+	}
+	if o.ContextLines != nil {
+		args = append(args, fmt.Sprintf("-U%d", *o.ContextLines))
+	}
+	args = append(args, extra...)
+	if o.Base != "" {
+		args = append(args, o.Base)
+	}
+	excludes := excludePathspecs()
+	if len(o.Paths) > 0 || len(excludes) > 0 {
+		args = append(args, "--")
+		if len(o.Paths) > 0 {
+			args = append(args, o.Paths...)
+		} else {
+			// Exclusions need a positive pathspec; keep the whole repository.
+			args = append(args, ":/")
+		}
+		args = append(args, excludes...)
+	}
+	return args
 }
 
 /**
@@ -26,14 +184,52 @@ type DiffResult struct {
  * @returns An error if the git command fails
  */
 func GetStagedDiff() (string, error) {
-	cmd := exec.Command("git", "diff", "--staged")
-	output, err := cmd.Output()
+	return getStagedDiff(DiffOptions{})
+}
+
+func getStagedDiff(opts DiffOptions) (string, error) {
+	output, err := runGit(opts.stagedArgs()...)
 	if err != nil {
 		return "", fmt.Errorf("failed to get git diff: %w", err)
 	}
 	return string(output), nil
 }
 
+/**
+ * GetStagedDiffForPaths returns the staged diff limited to the given paths.
+ *
+ * @param paths - Staged paths to include
+ * @returns The staged diff output for those paths
+ * @returns An error if the git command fails
+ */
+func GetStagedDiffForPaths(paths []string) (string, error) {
+	return getStagedDiff(DiffOptions{Paths: paths})
+}
+
+/**
+ * GetWorkingDiff returns staged and unstaged changes to tracked files,
+ * compared with HEAD (or the empty tree in a repository without commits).
+ *
+ * @returns The working tree diff
+ * @returns An error if the git command fails
+ */
+func GetWorkingDiff() (string, error) {
+	return getStagedDiff(DiffOptions{Working: true, Base: GetWorkingBase()})
+}
+
+/**
+ * GetWorkingBase returns the revision working tree diffs compare against:
+ * HEAD, or the empty tree before the first commit.
+ *
+ * @returns The base revision
+ */
+func GetWorkingBase() string {
+	if _, err := runGit("rev-parse", "--verify", "HEAD"); err != nil {
+		return emptyTreeHash
+	}
+	return "HEAD"
+}
+
 /**
  * GetStagedDiffStat returns the diff stat showing file change statistics.
  *
@@ -41,8 +237,11 @@ func GetStagedDiff() (string, error) {
  * @returns An error if the git command fails
  */
 func GetStagedDiffStat() (string, error) {
-	cmd := exec.Command("git", "diff", "--staged", "--stat")
-	output, err := cmd.Output()
+	return getStagedDiffStat(DiffOptions{})
+}
+
+func getStagedDiffStat(opts DiffOptions) (string, error) {
+	output, err := runGit(opts.stagedArgs("--stat")...)
 	if err != nil {
 		return "", fmt.Errorf("failed to get git diff stat: %w", err)
 	}
@@ -58,44 +257,118 @@ func GetStagedDiffStat() (string, error) {
  * @returns An error if the git command fails
  */
 func GetStagedDiffWithLimit(maxSize int) (*DiffResult, error) {
+	return GetStagedDiffWithOptions(maxSize, DiffOptions{})
+}
+
+/**
+ * GetStagedDiffWithOptions returns the staged diff produced with the given
+ * options, automatically summarizing if it exceeds the specified maximum size.
+ *
+ * @param maxSize - Maximum size in bytes before summarizing (0 uses default)
+ * @param opts - Options controlling the git diff invocation
+ * @returns A DiffResult containing the diff and metadata about summarization
+ * @returns An error if the git command fails
+ */
+func GetStagedDiffWithOptions(maxSize int, opts DiffOptions) (*DiffResult, error) {
 	if maxSize <= 0 {
 		maxSize = DefaultMaxDiffSize
 	}
 
-	diff, err := GetStagedDiff()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	diff, err := getStagedDiff(opts)
 	if err != nil {
 		return nil, err
 	}
 
+	return limitDiff(diff, maxSize, opts.CollapseThreshold, diffSource{
+		binaryNote: func(path string, deleted bool) string {
+			return binarySizeNote(path, deleted, opts)
+		},
+		stat: func() (string, error) {
+			return getStagedDiffStat(opts)
+		},
+		files: func() ([]string, error) {
+			return getChangedFiles(opts)
+		},
+	}), nil
+}
+
+/**
+ * diffSource supplies the details limitDiff cannot read from the diff text
+ * itself: binary file sizes, and the stat and file list for a summary.
+ */
+type diffSource struct {
+	binaryNote func(path string, deleted bool) string
+	stat       func() (string, error)
+	files      func() ([]string, error)
+}
+
+/**
+ * limitDiff describes binary files and, if the diff exceeds maxSize,
+ * collapses large files or summarizes it.
+ *
+ * @param diff - The unified diff
+ * @param maxSize - Maximum size in bytes before summarizing
+ * @param collapseThreshold - Per-file size above which to collapse; zero disables collapsing
+ * @param source - Supplies binary sizes and the summary stat and file list
+ * @returns A DiffResult containing the diff and metadata about summarization
+ */
+func limitDiff(diff string, maxSize, collapseThreshold int, source diffSource) *DiffResult {
 	originalSize := len(diff)
+	files := diffFiles(diff)
 
-	if originalSize <= maxSize {
+	var binaries []string
+	binaryOnly := false
+	if strings.Contains(diff, "\nBinary files ") || strings.Contains(diff, "\nGIT binary patch") {
+		diff, binaries, binaryOnly = describeBinaryFiles(diff, source.binaryNote)
+	}
+
+	if len(diff) <= maxSize {
 		return &DiffResult{
 			Diff:         diff,
 			IsSummarized: false,
 			OriginalSize: originalSize,
-		}, nil
+			BinaryFiles:  binaries,
+			BinaryOnly:   binaryOnly,
+			Files:        files,
+		}
 	}
 
-	summarized, err := summarizeDiff(diff, maxSize)
-	if err != nil {
-		return nil, err
+	if collapseThreshold > 0 {
+		collapsed, collapsedFiles := collapseLargeFiles(diff, collapseThreshold)
+		if len(collapsedFiles) > 0 && len(collapsed) <= maxSize {
+			return &DiffResult{
+				Diff:           collapsed,
+				IsSummarized:   false,
+				OriginalSize:   originalSize,
+				CollapsedFiles: collapsedFiles,
+				BinaryFiles:    binaries,
+				BinaryOnly:     binaryOnly,
+				Files:          files,
+			}
+		}
 	}
 
 	return &DiffResult{
-		Diff:         summarized,
+		Diff:         summarizeDiff(diff, maxSize, source),
 		IsSummarized: true,
 		OriginalSize: originalSize,
-	}, nil
+		BinaryFiles:  binaries,
+		BinaryOnly:   binaryOnly,
+		Files:        files,
+	}
 }
 
-func summarizeDiff(diff string, maxSize int) (string, error) {
-	stat, err := GetStagedDiffStat()
+func summarizeDiff(diff string, maxSize int, source diffSource) string {
+	stat, err := source.stat()
 	if err != nil {
 		stat = "(unable to get diff stat)"
 	}
 
-	files, err := GetChangedFiles()
+	files, err := source.files()
 	if err != nil {
 		files = []string{"(unable to get file list)"}
 	}
@@ -105,8 +378,20 @@ func summarizeDiff(diff string, maxSize int) (string, error) {
 	sb.WriteString(fmt.Sprintf("Original diff size: %d bytes\n", len(diff)))
 	sb.WriteString(fmt.Sprintf("Files changed: %d\n\n", len(files)))
 
+	binaryNotes := map[string]string{}
+	for _, line := range strings.Split(diff, "\n") {
+		if rest, ok := strings.CutPrefix(line, "binary: "); ok {
+			if idx := strings.LastIndex(rest, " ("); idx >= 0 {
+				binaryNotes[rest[:idx]] = line
+			}
+		}
+	}
+
 	sb.WriteString("=== CHANGED FILES ===\n")
 	for _, f := range files {
+		if note, ok := binaryNotes[f]; ok {
+			f = note
+		}
 		sb.WriteString(fmt.Sprintf("  - %s\n", f))
 	}
 	sb.WriteString("\n")
@@ -125,7 +410,254 @@ func summarizeDiff(diff string, maxSize int) (string, error) {
 		sb.WriteString("\n\n... [truncated] ...\n")
 	}
 
-	return sb.String(), nil
+	return sb.String()
+}
+
+/**
+ * collapseLargeFiles replaces the diff body of every file whose section exceeds
+ * threshold bytes with a "[large file: name, +X/-Y]" note, keeping smaller
+ * files intact.
+ *
+ * @param diff - The full staged diff
+ * @param threshold - Per-file section size in bytes above which to collapse
+ * @returns The rewritten diff and the paths of collapsed files
+ */
+func collapseLargeFiles(diff string, threshold int) (string, []string) {
+	var sb strings.Builder
+	var collapsed []string
+
+	for _, section := range splitDiffByFile(diff) {
+		if len(section) <= threshold || !strings.HasPrefix(section, "diff --git ") {
+			sb.WriteString(section)
+			continue
+		}
+
+		header, _, _ := strings.Cut(section, "\n")
+		name := diffSectionPath(header)
+		added, removed := countChangedLines(section)
+
+		sb.WriteString(header)
+		sb.WriteString(fmt.Sprintf("\n[large file: %s, +%d/-%d]\n", name, added, removed))
+		collapsed = append(collapsed, name)
+	}
+
+	return sb.String(), collapsed
+}
+
+/**
+ * describeBinaryFiles replaces the body of each binary file's diff section
+ * with a one-line "binary: path (N bytes)" note, so binary content never
+ * reaches the prompt or counts toward the size budget.
+ *
+ * @param diff - The unified diff
+ * @param note - Returns the parenthesised note for a binary file
+ * @returns The rewritten diff, the binary file paths, and whether every
+ * file in the diff is binary
+ */
+func describeBinaryFiles(diff string, note func(path string, deleted bool) string) (string, []string, bool) {
+	var sb strings.Builder
+	var binaries []string
+	sections := 0
+
+	for _, section := range splitDiffByFile(diff) {
+		if !strings.HasPrefix(section, "diff --git ") {
+			sb.WriteString(section)
+			continue
+		}
+		sections++
+		if !isBinarySection(section) {
+			sb.WriteString(section)
+			continue
+		}
+
+		header, _, _ := strings.Cut(section, "\n")
+		name := diffSectionPath(header)
+		deleted := strings.Contains(section, "\ndeleted file mode ")
+
+		sb.WriteString(header)
+		sb.WriteString(fmt.Sprintf("\nbinary: %s %s\n", name, note(name, deleted)))
+		binaries = append(binaries, name)
+	}
+
+	return sb.String(), binaries, sections > 0 && len(binaries) == sections
+}
+
+/**
+ * isBinarySection reports whether a single file's diff section is for a
+ * binary file, either as a "Binary files ... differ" line or a binary patch.
+ */
+func isBinarySection(section string) bool {
+	for _, line := range strings.Split(section, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			return false
+		}
+		if line == "GIT binary patch" || (strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ")) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * binarySizeNote describes a binary file's new size, read from the index for
+ * staged diffs and from the working tree otherwise.
+ *
+ * @param path - The path as printed in the diff
+ * @param deleted - Whether the file was deleted
+ * @param opts - The options the diff was produced with
+ * @returns "(N bytes)", "(deleted)", or "(size unknown)"
+ */
+func binarySizeNote(path string, deleted bool, opts DiffOptions) string {
+	if deleted {
+		return "(deleted)"
+	}
+
+	if opts.Working {
+		if !opts.Relative {
+			if root, err := GetRepositoryRoot(); err == nil {
+				path = filepath.Join(root, path)
+			}
+		}
+		if info, err := os.Stat(path); err == nil {
+			return fmt.Sprintf("(%d bytes)", info.Size())
+		}
+		return "(size unknown)"
+	}
+
+	object := ":" + path
+	if opts.Relative {
+		object = ":./" + path
+	}
+	output, err := runGit("cat-file", "-s", object)
+	if err != nil {
+		return "(size unknown)"
+	}
+	return fmt.Sprintf("(%s bytes)", strings.TrimSpace(string(output)))
+}
+
+/**
+ * splitDiffByFile splits a unified diff into per-file sections, each starting
+ * with its "diff --git" header. Any preamble is returned as its own section.
+ */
+func splitDiffByFile(diff string) []string {
+	var sections []string
+	for diff != "" {
+		next := strings.Index(diff[1:], "\ndiff --git ")
+		if next < 0 {
+			sections = append(sections, diff)
+			break
+		}
+		sections = append(sections, diff[:next+2])
+		diff = diff[next+2:]
+	}
+	return sections
+}
+
+/**
+ * FileStatus is a staged file and its single-letter git status (A, M, D, R, ...).
+ */
+type FileStatus struct {
+	Status string
+	Path   string
+}
+
+/**
+ * GetChangedFilesWithStatus returns the staged files with their change status.
+ *
+ * @returns The staged files in git's order
+ * @returns An error if the git command fails
+ */
+func GetChangedFilesWithStatus() ([]FileStatus, error) {
+	output, err := runGit(DiffOptions{}.stagedArgs("--name-status")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changed files: %w", err)
+	}
+	return parseNameStatus(string(output)), nil
+}
+
+// parseNameStatus parses "git diff --name-status" output; renames report the new path.
+func parseNameStatus(output string) []FileStatus {
+	var files []FileStatus
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		files = append(files, FileStatus{
+			Status: fields[0][:1],
+			Path:   fields[len(fields)-1],
+		})
+	}
+	return files
+}
+
+/**
+ * FileDiff is the part of a unified diff belonging to one file.
+ */
+type FileDiff struct {
+	Path    string
+	Added   int
+	Removed int
+	// Body is the file's diff section, starting with its "diff --git" header.
+	Body string
+}
+
+/**
+ * GroupDiffByFile splits a unified diff into per-file sections with line counts.
+ *
+ * @param diff - The unified diff
+ * @returns One entry per file, in diff order
+ */
+func GroupDiffByFile(diff string) []FileDiff {
+	var files []FileDiff
+	for _, section := range splitDiffByFile(diff) {
+		if !strings.HasPrefix(section, "diff --git ") {
+			continue
+		}
+		header, _, _ := strings.Cut(section, "\n")
+		added, removed := countChangedLines(section)
+		files = append(files, FileDiff{
+			Path:    diffSectionPath(header),
+			Added:   added,
+			Removed: removed,
+			Body:    section,
+		})
+	}
+	return files
+}
+
+// diffFiles returns the new-side path of each file section in a unified diff.
+func diffFiles(diff string) []string {
+	var files []string
+	for _, section := range splitDiffByFile(diff) {
+		if header, _, _ := strings.Cut(section, "\n"); strings.HasPrefix(header, "diff --git ") {
+			files = append(files, diffSectionPath(header))
+		}
+	}
+	return files
+}
+
+// diffSectionPath returns the new-side path from a "diff --git a/x b/x" header.
+func diffSectionPath(header string) string {
+	if idx := strings.LastIndex(header, " b/"); idx >= 0 {
+		return header[idx+3:]
+	}
+	return strings.TrimPrefix(header, "diff --git ")
+}
+
+// countChangedLines counts added and removed lines in a diff section, ignoring file headers.
+func countChangedLines(section string) (int, int) {
+	added, removed := 0, 0
+	for _, line := range strings.Split(section, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
 }
 
 func truncateDiffSmart(diff string, maxLen int) string {
@@ -158,14 +690,115 @@ func truncateDiffSmart(diff string, maxLen int) string {
  * @returns An error if not in a git repository
  */
 func GetRepositoryRoot() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	output, err := cmd.Output()
+	output, err := runGit("rev-parse", "--show-toplevel")
 	if err != nil {
 		return "", fmt.Errorf("not a git repository or failed to get root: %w", err)
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
+/**
+ * GetGitCommonDir returns the absolute path of the git directory shared by all
+ * worktrees. In a linked worktree this is the main repository's .git directory,
+ * where hooks live, rather than the worktree's private git dir.
+ *
+ * @returns The absolute path to the common git directory
+ * @returns An error if not in a git repository
+ */
+func GetGitCommonDir() (string, error) {
+	output, err := runGit("rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("not a git repository or failed to get git dir: %w", err)
+	}
+
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+		dir = filepath.Join(cwd, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+/**
+ * GetHooksDir returns the directory git runs hooks from: core.hooksPath,
+ * resolved against the repository root when relative, or the hooks directory
+ * of the common git dir. Husky v9 points core.hooksPath at .husky/_, whose
+ * generated stubs run the scripts in .husky, so .husky is returned instead.
+ *
+ * @returns The absolute path to the hooks directory
+ * @returns An error if not in a git repository
+ */
+func GetHooksDir() (string, error) {
+	output, err := runGit("config", "--type=path", "--get", "core.hooksPath")
+	hooksPath := strings.TrimSpace(string(output))
+	if err != nil || hooksPath == "" {
+		gitDir, err := GetGitCommonDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(gitDir, "hooks"), nil
+	}
+
+	if !filepath.IsAbs(hooksPath) {
+		root, err := GetRepositoryRoot()
+		if err != nil {
+			return "", err
+		}
+		hooksPath = filepath.Join(root, hooksPath)
+	}
+	hooksPath = filepath.Clean(hooksPath)
+
+	if filepath.Base(hooksPath) == "_" && filepath.Base(filepath.Dir(hooksPath)) == ".husky" {
+		hooksPath = filepath.Dir(hooksPath)
+	}
+	return hooksPath, nil
+}
+
+/**
+ * GetGlobalConfigPath reads a path-valued setting from the global git config,
+ * expanding a leading ~.
+ *
+ * @param key - The config key, e.g. init.templateDir
+ * @returns The value, or empty string if unset
+ */
+func GetGlobalConfigPath(key string) string {
+	output, err := runGit("config", "--global", "--type=path", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+/**
+ * SetGlobalConfig writes a setting to the global git config.
+ *
+ * @param key - The config key
+ * @param value - The value to set
+ * @returns An error if git config fails
+ */
+func SetGlobalConfig(key, value string) error {
+	if _, err := runGit("config", "--global", key, value); err != nil {
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+	return nil
+}
+
+/**
+ * UnsetGlobalConfig removes a setting from the global git config.
+ *
+ * @param key - The config key
+ * @returns An error if git config fails
+ */
+func UnsetGlobalConfig(key string) error {
+	if _, err := runGit("config", "--global", "--unset", key); err != nil {
+		return fmt.Errorf("failed to unset %s: %w", key, err)
+	}
+	return nil
+}
+
 /**
  * GetRepositoryName returns the name of the current repository (directory name).
  *
@@ -180,6 +813,48 @@ func GetRepositoryName() (string, error) {
 	return filepath.Base(root), nil
 }
 
+/**
+ * GetUserIdentity returns the committer identity from git config, as used
+ * in a Signed-off-by trailer.
+ *
+ * @returns The identity in "Name <email>" form
+ * @returns An error if user.name or user.email is not set
+ */
+func GetUserIdentity() (string, error) {
+	var parts []string
+	for _, key := range []string{"user.name", "user.email"} {
+		output, err := runGit("config", "--get", key)
+		value := strings.TrimSpace(string(output))
+		if err != nil || value == "" {
+			return "", fmt.Errorf("git %s is not set; run: git config --global %s <value>", key, key)
+		}
+		parts = append(parts, value)
+	}
+	return fmt.Sprintf("%s <%s>", parts[0], parts[1]), nil
+}
+
+/**
+ * GetBranchName returns the name of the currently checked out branch.
+ *
+ * @returns The branch name, or empty string on a detached HEAD
+ * @returns An error if the git command fails
+ */
+func GetBranchName() (string, error) {
+	output, err := runGit("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		output, err = runGit("symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return "", fmt.Errorf("failed to get branch name: %w", err)
+		}
+	}
+
+	branch := strings.TrimSpace(string(output))
+	if branch == "HEAD" {
+		return "", nil
+	}
+	return branch, nil
+}
+
 /**
  * GetStatus returns the current git status in porcelain format.
  *
@@ -187,8 +862,7 @@ func GetRepositoryName() (string, error) {
  * @returns An error if the git command fails
  */
 func GetStatus() (string, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
-	output, err := cmd.Output()
+	output, err := runGit("status", "--porcelain")
 	if err != nil {
 		return "", fmt.Errorf("failed to get git status: %w", err)
 	}
@@ -216,8 +890,23 @@ func HasStagedChanges() (bool, error) {
  * @returns An error if the git command fails
  */
 func GetChangedFiles() ([]string, error) {
-	cmd := exec.Command("git", "diff", "--staged", "--name-only")
-	output, err := cmd.Output()
+	return getChangedFiles(DiffOptions{})
+}
+
+/**
+ * GetChangedFilesWithOptions returns the files in the diff produced with the
+ * given options, e.g. the working tree with --all or HEAD when amending.
+ *
+ * @param opts - Options controlling the git diff invocation
+ * @returns A slice of changed file paths
+ * @returns An error if the git command fails
+ */
+func GetChangedFilesWithOptions(opts DiffOptions) ([]string, error) {
+	return getChangedFiles(opts)
+}
+
+func getChangedFiles(opts DiffOptions) ([]string, error) {
+	output, err := runGit(opts.stagedArgs("--name-only")...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get changed files: %w", err)
 	}
@@ -232,6 +921,118 @@ func GetChangedFiles() ([]string, error) {
 	return result, nil
 }
 
+/**
+ * UnstageFiles removes the given paths from the index, keeping their working
+ * tree changes.
+ *
+ * @param paths - Paths to unstage
+ * @returns An error if the git command fails
+ */
+func UnstageFiles(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	args := append([]string{"reset", "-q", "--"}, paths...)
+	if _, err := runGit(args...); err != nil {
+		return fmt.Errorf("failed to unstage files: %w", err)
+	}
+	return nil
+}
+
+/**
+ * GetPartiallyStagedFiles returns staged files that also have unstaged
+ * changes in the working tree, e.g. after staging only some hunks with git add -p.
+ *
+ * @returns A slice of partially staged file paths
+ * @returns An error if a git command fails
+ */
+func GetPartiallyStagedFiles() ([]string, error) {
+	staged, err := GetChangedFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(staged) == 0 {
+		return nil, nil
+	}
+
+	output, err := runGit("diff", "--name-only")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unstaged files: %w", err)
+	}
+
+	unstaged := make(map[string]bool)
+	for _, f := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if f != "" {
+			unstaged[f] = true
+		}
+	}
+
+	var result []string
+	for _, f := range staged {
+		if unstaged[f] {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// emptyTreeHash is git's well-known hash of the empty tree, used as the parent of a root commit.
+const emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+/**
+ * GetAmendBase returns the revision an amended HEAD commit will be compared
+ * against: the parent of HEAD, or the empty tree when HEAD is a root commit.
+ *
+ * @returns The base revision for an amend diff
+ * @returns An error if HEAD does not exist
+ */
+func GetAmendBase() (string, error) {
+	if _, err := runGit("rev-parse", "--verify", "HEAD"); err != nil {
+		return "", fmt.Errorf("no commit to amend: %w", err)
+	}
+	if _, err := runGit("rev-parse", "--verify", "HEAD~1"); err != nil {
+		return emptyTreeHash, nil
+	}
+	return "HEAD~1", nil
+}
+
+/**
+ * GetLastCommitMessage returns the full message of the HEAD commit.
+ *
+ * @returns The commit message including body and trailers
+ * @returns An error if the git command fails
+ */
+func GetLastCommitMessage() (string, error) {
+	output, err := runGit("log", "-1", "--pretty=%B")
+	if err != nil {
+		return "", fmt.Errorf("failed to get last commit message: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+/**
+ * GetCommitSubjectsSince returns the subjects of commits reachable from HEAD
+ * but not from the given ref, newest first.
+ *
+ * @param ref - The tag or revision to start from (exclusive)
+ * @returns A slice of commit subjects
+ * @returns An error if the git command fails
+ */
+func GetCommitSubjectsSince(ref string) ([]string, error) {
+	output, err := runGit("log", ref+"..HEAD", "--pretty=%s")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits since %s: %w", ref, err)
+	}
+
+	var subjects []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects, nil
+}
+
 /**
  * IsGitRepository checks if the current directory is inside a git repository.
  *
@@ -272,6 +1073,258 @@ func WriteCommitMessage(message string) error {
 	return os.WriteFile(msgFile, []byte(message), 0o644)
 }
 
+/**
+ * NormalizeBlankLines removes leading blank lines, so the first line is the
+ * subject, and trims trailing blank lines so the message ends with exactly
+ * one newline.
+ *
+ * @param message - The commit message to normalize
+ * @returns The normalized message, or empty string if it is blank
+ */
+func NormalizeBlankLines(message string) string {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+
+	start := 0
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	end := len(lines)
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	if start == end {
+		return ""
+	}
+
+	return strings.Join(lines[start:end], "\n") + "\n"
+}
+
+// scissorsCut marks the line above the diff in verbose commit messages
+// (git commit -v); git ignores everything from that line on.
+const scissorsCut = "------------------------ >8 ------------------------"
+
+/**
+ * GetCommentChar returns the prefix git uses for comment lines in commit
+ * messages, from core.commentString or core.commentChar. Falls back to "#"
+ * when the setting is unset or "auto".
+ *
+ * @returns The comment prefix
+ */
+func GetCommentChar() string {
+	for _, key := range []string{"core.commentString", "core.commentChar"} {
+		output, err := runGit("config", "--get", key)
+		if err != nil {
+			continue
+		}
+		commentChar := strings.TrimSpace(string(output))
+		if commentChar != "" && commentChar != "auto" {
+			return commentChar
+		}
+	}
+	return "#"
+}
+
+/**
+ * StripCommitComments removes what git itself strips from a commit message
+ * file: everything from the scissors line on, lines starting with the
+ * comment prefix, and leading and trailing blank lines.
+ *
+ * @param content - The commit message file contents
+ * @param commentChar - The comment prefix (see GetCommentChar)
+ * @returns The message as git would record it
+ */
+func StripCommitComments(content, commentChar string) string {
+	var kept []string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if strings.Contains(line, scissorsCut) {
+			break
+		}
+		if strings.HasPrefix(line, commentChar) {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t"))
+	}
+	return strings.Trim(strings.Join(kept, "\n"), "\n")
+}
+
+/**
+ * GetCommitTemplate returns the contents of the file named by git's
+ * commit.template setting. A relative path is resolved from the repository
+ * root, which is where git runs commit hooks.
+ *
+ * @returns The template, or empty string if commit.template is unset
+ * @returns An error if the configured file cannot be read
+ */
+func GetCommitTemplate() (string, error) {
+	output, err := runGit("config", "--path", "--get", "commit.template")
+	if err != nil {
+		return "", nil
+	}
+	path := strings.TrimSpace(string(output))
+	if path == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(path) {
+		if root, err := GetRepositoryRoot(); err == nil {
+			path = filepath.Join(root, path)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit.template: %w", err)
+	}
+	return string(content), nil
+}
+
+/**
+ * IsCommitMessageEmpty reports whether a commit message file holds no
+ * message of its own: nothing is left once comment lines, the verbose diff,
+ * and lines copied unchanged from the commit template are removed.
+ *
+ * @param content - The commit message file contents
+ * @param template - The commit template (see GetCommitTemplate); may be empty
+ * @param commentChar - The comment prefix (see GetCommentChar)
+ * @returns true if the user has not written a message
+ */
+func IsCommitMessageEmpty(content, template, commentChar string) bool {
+	templateLines := map[string]bool{}
+	for _, line := range strings.Split(StripCommitComments(template, commentChar), "\n") {
+		templateLines[strings.TrimSpace(line)] = true
+	}
+
+	for _, line := range strings.Split(StripCommitComments(content, commentChar), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !templateLines[line] {
+			return false
+		}
+	}
+	return true
+}
+
+var authorPattern = regexp.MustCompile(`^[^<>\n]*[^<>\s] <[^<>\s@]+@[^<>\s@]+>$`)
+
+/**
+ * CommitOptions controls how Commit invokes git commit.
+ */
+type CommitOptions struct {
+	// Author overrides the commit author, in "Name <email>" form.
+	Author string
+	// Date overrides the author date, in RFC 2822 or ISO 8601 form.
+	Date string
+	// CommitterDate also sets the committer date to Date.
+	CommitterDate bool
+	// Amend replaces the HEAD commit instead of creating a new one.
+	Amend bool
+}
+
+// commitDateLayouts lists the RFC 2822 and ISO 8601 layouts accepted for CommitOptions.Date.
+var commitDateLayouts = []string{
+	time.RFC1123Z,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+/**
+ * ValidateDate checks that date is an RFC 2822 or ISO 8601 timestamp git accepts.
+ *
+ * @param date - The date string to validate
+ * @returns An error describing the accepted formats if date is malformed
+ */
+func ValidateDate(date string) error {
+	date = strings.TrimSpace(date)
+	for _, layout := range commitDateLayouts {
+		if _, err := time.Parse(layout, date); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid date %q: expected RFC 2822 (\"Mon, 02 Jan 2006 15:04:05 -0700\") or ISO 8601 (\"2006-01-02T15:04:05Z07:00\")", date)
+}
+
+/**
+ * ValidateAuthor checks that author has the "Name <email>" form git expects.
+ *
+ * @param author - The author string to validate
+ * @returns An error describing the problem if the format is invalid
+ */
+func ValidateAuthor(author string) error {
+	if !authorPattern.MatchString(strings.TrimSpace(author)) {
+		return fmt.Errorf("invalid author %q: expected \"Name <email@example.com>\"", author)
+	}
+	return nil
+}
+
+/**
+ * commitArgs builds the git commit arguments for a message file and options.
+ */
+func commitArgs(messageFile string, opts CommitOptions) []string {
+	args := []string{"commit", "-F", messageFile}
+	if opts.Author != "" {
+		args = append(args, "--author="+strings.TrimSpace(opts.Author))
+	}
+	if opts.Date != "" {
+		args = append(args, "--date="+strings.TrimSpace(opts.Date))
+	}
+	if opts.Amend {
+		args = append(args, "--amend")
+	}
+	return args
+}
+
+/**
+ * Commit records the staged changes with the given message. The message is
+ * passed to git commit -F through a temporary file. When git commit fails,
+ * e.g. because a pre-commit hook rejects the change, git's stderr is
+ * returned in the error.
+ *
+ * @param message - The commit message
+ * @param opts - Additional commit options such as an author or date override
+ * @returns An error if the options are invalid or git commit fails
+ */
+func Commit(message string, opts CommitOptions) error {
+	if opts.Author != "" {
+		if err := ValidateAuthor(opts.Author); err != nil {
+			return err
+		}
+	}
+
+	var env []string
+	if opts.Date != "" {
+		if err := ValidateDate(opts.Date); err != nil {
+			return err
+		}
+		if opts.CommitterDate {
+			env = append(env, "GIT_COMMITTER_DATE="+strings.TrimSpace(opts.Date))
+		}
+	}
+
+	file, err := os.CreateTemp("", "commit-gen-msg-*")
+	if err != nil {
+		return fmt.Errorf("failed to create message file: %w", err)
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	_, err = file.WriteString(message)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write message file: %w", err)
+	}
+
+	if _, err := runGitWithEnv(env, commitArgs(file.Name(), opts)...); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("git commit failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return fmt.Errorf("git commit failed: %w", err)
+	}
+	return nil
+}
+
 /**
  * ChangeEditor sets the git core.editor configuration.
  *
@@ -289,6 +1342,7 @@ func ChangeEditor(editor string) error {
 
 /**
  * ReadCommitMessage reads the current commit message from the git commit message file.
+ * Comment lines and the verbose diff below the scissors line are stripped.
  *
  * @returns The commit message content, or empty string if file doesn't exist
  * @returns An error if reading fails
@@ -308,5 +1362,5 @@ func ReadCommitMessage() (string, error) {
 		return "", fmt.Errorf("failed to read commit message file: %w", err)
 	}
 
-	return strings.TrimSpace(string(content)), nil
+	return strings.TrimSpace(StripCommitComments(string(content), GetCommentChar())), nil
 }
@@ -2,9 +2,11 @@
 package git
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -169,3 +171,446 @@ func TestGitCommandExecution(t *testing.T) {
 
 	t.Logf("Git directory: %s", string(output))
 }
+
+// countGitCalls replaces runGit with a stub returning diff for every call
+// and reports how many git subprocesses would have been spawned.
+func countGitCalls(t testing.TB, diff string) *int {
+	calls := 0
+	original := runGit
+	runGit = func(args ...string) ([]byte, error) {
+		calls++
+		return []byte(diff), nil
+	}
+	t.Cleanup(func() { runGit = original })
+	return &calls
+}
+
+func TestSmallDiffMakesSingleGitCall(t *testing.T) {
+	calls := countGitCalls(t, "diff --git a/a.go b/a.go\n+small change\n")
+
+	result, err := GetStagedDiffWithLimit(DefaultMaxDiffSize)
+	if err != nil {
+		t.Fatalf("GetStagedDiffWithLimit failed: %v", err)
+	}
+
+	if result.IsSummarized {
+		t.Error("Small diff should not be summarized")
+	}
+
+	// The non-summarized path must spawn exactly one git subprocess;
+	// stat and file lists are only fetched when summarizing.
+	if *calls != 1 {
+		t.Errorf("Expected 1 git call for a small diff, got %d", *calls)
+	}
+
+	t.Log("✓ Small diff fetched with a single git call")
+}
+
+func TestLargeDiffFetchesStatAndFiles(t *testing.T) {
+	calls := countGitCalls(t, strings.Repeat("+line\n", 1000))
+
+	result, err := GetStagedDiffWithLimit(1024)
+	if err != nil {
+		t.Fatalf("GetStagedDiffWithLimit failed: %v", err)
+	}
+
+	if !result.IsSummarized {
+		t.Error("Large diff should be summarized")
+	}
+
+	if *calls != 3 {
+		t.Errorf("Expected 3 git calls for a summarized diff, got %d", *calls)
+	}
+
+	t.Log("✓ Summarized diff fetches stat and file list")
+}
+
+// BenchmarkGetStagedDiffSmall measures only the diff fetch for a small diff,
+// with git stubbed out, so neither generation nor subprocess cost is
+// included. The one-git-call rule it guards covers the diff fetch alone.
+func BenchmarkGetStagedDiffSmall(b *testing.B) {
+	countGitCalls(b, "diff --git a/a.go b/a.go\n+small change\n")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := GetStagedDiffWithLimit(DefaultMaxDiffSize); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetCommentCharHonorsConfig(t *testing.T) {
+	original := runGit
+	defer func() { runGit = original }()
+
+	for value, expected := range map[string]string{";\n": ";", "auto\n": "#", "": "#"} {
+		runGit = func(args ...string) ([]byte, error) {
+			if args[len(args)-1] == "core.commentChar" && value != "" {
+				return []byte(value), nil
+			}
+			return nil, exec.ErrNotFound
+		}
+		if got := GetCommentChar(); got != expected {
+			t.Errorf("core.commentChar %q: got %q, expected %q", value, got, expected)
+		}
+	}
+}
+
+func TestDiffAlgorithmArgApplied(t *testing.T) {
+	var calls [][]string
+	original := runGit
+	runGit = func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return []byte("diff --git a/a.go b/a.go\n"), nil
+	}
+	defer func() { runGit = original }()
+
+	if _, err := GetStagedDiffWithOptions(0, DiffOptions{Algorithm: "histogram"}); err != nil {
+		t.Fatalf("GetStagedDiffWithOptions failed: %v", err)
+	}
+
+	if len(calls) != 1 || !strings.Contains(strings.Join(calls[0], " "), "--diff-algorithm=histogram") {
+		t.Errorf("Expected --diff-algorithm=histogram in git args, got %v", calls)
+	}
+
+	calls = nil
+	if _, err := GetStagedDiffWithOptions(0, DiffOptions{}); err != nil {
+		t.Fatalf("GetStagedDiffWithOptions failed: %v", err)
+	}
+	if strings.Contains(strings.Join(calls[0], " "), "--diff-algorithm") {
+		t.Errorf("Unexpected --diff-algorithm without configuration: %v", calls[0])
+	}
+
+	if _, err := GetStagedDiffWithOptions(0, DiffOptions{Algorithm: "fancy"}); err == nil {
+		t.Error("Expected error for unknown diff algorithm")
+	}
+
+	t.Log("✓ Diff algorithm passed to git when configured")
+}
+
+func TestContextLinesArg(t *testing.T) {
+	zero, five := 0, 5
+	tests := []struct {
+		opts     DiffOptions
+		expected string
+	}{
+		{DiffOptions{}, ""},
+		{DiffOptions{ContextLines: &zero}, "-U0"},
+		{DiffOptions{ContextLines: &five}, "-U5"},
+	}
+
+	for _, tt := range tests {
+		args := tt.opts.stagedArgs()
+		var got string
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "-U") {
+				got = arg
+			}
+		}
+		if got != tt.expected {
+			t.Errorf("Expected context arg %q, got %v", tt.expected, args)
+		}
+	}
+
+	negative := -1
+	if _, err := GetStagedDiffWithOptions(0, DiffOptions{ContextLines: &negative}); err == nil {
+		t.Error("Expected error for negative context lines")
+	}
+}
+
+func TestCollapseLargeFiles(t *testing.T) {
+	small := "diff --git a/small.go b/small.go\n--- a/small.go\n+++ b/small.go\n@@ -1 +1 @@\n-old\n+new\n"
+	huge := "diff --git a/snap.json b/snap.json\n--- a/snap.json\n+++ b/snap.json\n@@ -1,200 +1,300 @@\n" +
+		strings.Repeat("-removed line\n", 200) + strings.Repeat("+added line\n", 300)
+	other := "diff --git a/other.go b/other.go\n--- a/other.go\n+++ b/other.go\n@@ -1 +1,2 @@\n context\n+added\n"
+
+	countGitCalls(t, small+huge+other)
+
+	result, err := GetStagedDiffWithOptions(2048, DiffOptions{CollapseThreshold: 1024})
+	if err != nil {
+		t.Fatalf("GetStagedDiffWithOptions failed: %v", err)
+	}
+
+	if result.IsSummarized {
+		t.Error("Collapsed diff should not fall back to full summarization")
+	}
+	if len(result.CollapsedFiles) != 1 || result.CollapsedFiles[0] != "snap.json" {
+		t.Errorf("Expected only snap.json collapsed, got %v", result.CollapsedFiles)
+	}
+	if !strings.Contains(result.Diff, "[large file: snap.json, +300/-200]") {
+		t.Errorf("Missing large file note:\n%s", result.Diff)
+	}
+	if !strings.Contains(result.Diff, small) || !strings.Contains(result.Diff, other) {
+		t.Errorf("Small file diffs should be kept intact:\n%s", result.Diff)
+	}
+	if strings.Contains(result.Diff, "added line") {
+		t.Error("Large file body should be removed")
+	}
+
+	t.Log("✓ Only the oversized file diff was collapsed")
+}
+
+func TestGetUserIdentity(t *testing.T) {
+	values := map[string]string{"user.name": "Jane Doe\n", "user.email": "jane@example.com\n"}
+	original := runGit
+	runGit = func(args ...string) ([]byte, error) {
+		if value, ok := values[args[len(args)-1]]; ok {
+			return []byte(value), nil
+		}
+		return nil, errors.New("exit status 1")
+	}
+	defer func() { runGit = original }()
+
+	identity, err := GetUserIdentity()
+	if err != nil || identity != "Jane Doe <jane@example.com>" {
+		t.Errorf("GetUserIdentity: got %q, %v", identity, err)
+	}
+
+	delete(values, "user.email")
+	if _, err := GetUserIdentity(); err == nil || !strings.Contains(err.Error(), "user.email") {
+		t.Errorf("Expected a missing user.email error, got %v", err)
+	}
+}
+
+func TestCommitRejectsMalformedAuthor(t *testing.T) {
+	calls := countGitCalls(t, "")
+
+	for _, author := range []string{"Jane Doe", "<jane@example.com>", "Jane <jane>", "Jane <jane@example.com", "Jane <a@b> <c@d>"} {
+		if err := Commit("feat: x", CommitOptions{Author: author}); err == nil {
+			t.Errorf("Expected malformed author %q to be rejected", author)
+		}
+	}
+
+	if *calls != 0 {
+		t.Errorf("git should not run for a malformed author, got %d calls", *calls)
+	}
+}
+
+func TestCommitForwardsAuthor(t *testing.T) {
+	var got []string
+	var message string
+	original := runGit
+	runGit = func(args ...string) ([]byte, error) {
+		got = args
+		if len(args) > 2 {
+			content, _ := os.ReadFile(args[2])
+			message = string(content)
+		}
+		return nil, nil
+	}
+	defer func() { runGit = original }()
+
+	if err := Commit("feat: x", CommitOptions{Author: "Jane Doe <jane@example.com>"}); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if len(got) != 4 || got[0] != "commit" || got[1] != "-F" || got[3] != "--author=Jane Doe <jane@example.com>" {
+		t.Errorf("Unexpected git args: %q", got)
+	}
+	if message != "feat: x" {
+		t.Errorf("Message file content: got %q", message)
+	}
+
+	t.Log("✓ Valid author forwarded to git commit")
+}
+
+func TestNormalizeBlankLines(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"\n\n  \nfeat: add x\n\nBody line\n\n\n", "feat: add x\n\nBody line\n"},
+		{"fix: y", "fix: y\n"},
+		{"\r\n\r\nfix: y\r\n", "fix: y\n"},
+		{"\n \n", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeBlankLines(tt.input); got != tt.expected {
+			t.Errorf("NormalizeBlankLines(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+
+	t.Log("✓ Leading and trailing blank lines normalized")
+}
+
+func TestStripCommitComments(t *testing.T) {
+	tests := []struct {
+		input       string
+		commentChar string
+		expected    string
+	}{
+		{"feat: add x\n\nBody\n# Please enter the commit message\n#\n", "#", "feat: add x\n\nBody"},
+		{"\n; comment\nfix: y   \r\n", ";", "fix: y"},
+		{"fix: y\n" + "# " + scissorsCut + "\ndiff --git a/x b/x\n", "#", "fix: y"},
+		{"# only comments\n", "#", ""},
+	}
+
+	for _, tt := range tests {
+		if got := StripCommitComments(tt.input, tt.commentChar); got != tt.expected {
+			t.Errorf("StripCommitComments(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+
+	t.Log("✓ Comment lines and the verbose diff stripped")
+}
+
+func TestIsCommitMessageEmpty(t *testing.T) {
+	template := "Refs: \n\n# Why is this change needed?\nReviewed-by:\n"
+	verbose := "# " + scissorsCut + "\n# Do not modify or remove the line above.\n" +
+		"diff --git a/config.go b/config.go\n+\tif cfg == nil {\n+\t\treturn nil\n+\t}\n"
+
+	tests := []struct {
+		name     string
+		content  string
+		template string
+		expected bool
+	}{
+		{"only comments", "\n# Please enter the commit message\n", "", true},
+		{"template seeded", "Refs:\n\n# Why is this change needed?\nReviewed-by:\n# Please enter the commit message\n", template, true},
+		{"template plus subject", "fix: handle nil config\nRefs:\nReviewed-by:\n", template, false},
+		{"message without template", "fix: handle nil config\n", "", false},
+		{"template text without template configured", "Refs:\n", "", false},
+		{"verbose commit", "\n# Please enter the commit message\n" + verbose, "", true},
+		{"verbose commit with subject", "fix: handle nil config\n" + verbose, "", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsCommitMessageEmpty(tt.content, tt.template, "#"); got != tt.expected {
+			t.Errorf("%s: IsCommitMessageEmpty = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+
+	t.Log("✓ Template and comment lines do not count as a message")
+}
+
+func TestGetCommitTemplate(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(templatePath, []byte("Refs:\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	configured := ""
+	original := runGit
+	runGit = func(args ...string) ([]byte, error) {
+		if args[len(args)-1] == "commit.template" && configured != "" {
+			return []byte(configured + "\n"), nil
+		}
+		return nil, exec.ErrNotFound
+	}
+	defer func() { runGit = original }()
+
+	if template, err := GetCommitTemplate(); template != "" || err != nil {
+		t.Errorf("Expected no template when commit.template is unset, got %q, %v", template, err)
+	}
+
+	configured = templatePath
+	if template, err := GetCommitTemplate(); template != "Refs:\n" || err != nil {
+		t.Errorf("Expected the template contents, got %q, %v", template, err)
+	}
+
+	configured = filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := GetCommitTemplate(); err == nil {
+		t.Error("Expected an error for an unreadable commit.template")
+	}
+}
+
+func TestCommitRejectsMalformedDate(t *testing.T) {
+	calls := countGitCalls(t, "")
+
+	for _, date := range []string{"yesterday", "2024-13-01", "01/02/2024", "Mon, 02 Jan 2006"} {
+		if err := Commit("feat: x", CommitOptions{Date: date}); err == nil {
+			t.Errorf("Expected malformed date %q to be rejected", date)
+		}
+	}
+
+	if *calls != 0 {
+		t.Errorf("git should not run for a malformed date, got %d calls", *calls)
+	}
+}
+
+func TestCommitForwardsDate(t *testing.T) {
+	var gotArgs, gotEnv []string
+	original := runGitWithEnv
+	runGitWithEnv = func(env []string, args ...string) ([]byte, error) {
+		gotEnv, gotArgs = env, args
+		return nil, nil
+	}
+	defer func() { runGitWithEnv = original }()
+
+	date := "2024-03-01T12:00:00+01:00"
+	if err := Commit("feat: x", CommitOptions{Date: date, CommitterDate: true}); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if len(gotArgs) != 4 || gotArgs[1] != "-F" || gotArgs[3] != "--date="+date {
+		t.Errorf("Unexpected git args: %q", gotArgs)
+	}
+	if len(gotEnv) != 1 || gotEnv[0] != "GIT_COMMITTER_DATE="+date {
+		t.Errorf("Unexpected env: %q", gotEnv)
+	}
+
+	if err := Commit("feat: x", CommitOptions{Date: "Fri, 01 Mar 2024 12:00:00 +0100"}); err != nil {
+		t.Errorf("RFC 2822 date rejected: %v", err)
+	}
+
+	t.Log("✓ Valid date forwarded to git commit")
+}
+
+func TestGroupDiffByFile(t *testing.T) {
+	diff := `diff --git a/app.go b/app.go
+index 1111111..2222222 100644
+--- a/app.go
++++ b/app.go
+@@ -1,2 +1,3 @@
+ package app
+-var x = 1
++var x = 2
++var y = 3
+diff --git a/README.md b/README.md
+new file mode 100644
+--- /dev/null
++++ b/README.md
+@@ -0,0 +1 @@
++# App
+`
+
+	files := GroupDiffByFile(diff)
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 file groups, got %d", len(files))
+	}
+
+	expected := []FileDiff{
+		{Path: "app.go", Added: 2, Removed: 1},
+		{Path: "README.md", Added: 1, Removed: 0},
+	}
+	for i, want := range expected {
+		got := files[i]
+		if got.Path != want.Path || got.Added != want.Added || got.Removed != want.Removed {
+			t.Errorf("Group %d: got %s +%d/-%d, expected %s +%d/-%d", i, got.Path, got.Added, got.Removed, want.Path, want.Added, want.Removed)
+		}
+		if !strings.HasPrefix(got.Body, "diff --git a/"+want.Path) {
+			t.Errorf("Group %d body should start with its header, got %q", i, got.Body)
+		}
+	}
+
+	if got := diffFiles(diff); strings.Join(got, ",") != "app.go,README.md" {
+		t.Errorf("diffFiles mismatch: %v", got)
+	}
+
+	t.Log("✓ Two-file diff grouped with headers and line counts")
+}
+
+func TestParseNameStatus(t *testing.T) {
+	files := parseNameStatus("M\tapp.go\nA\tREADME.md\nR100\told.go\tnew.go\n")
+
+	expected := []FileStatus{{"M", "app.go"}, {"A", "README.md"}, {"R", "new.go"}}
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %d files, got %v", len(expected), files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Errorf("Entry %d: got %v, expected %v", i, files[i], expected[i])
+		}
+	}
+}
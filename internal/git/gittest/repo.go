@@ -0,0 +1,56 @@
+// Package gittest creates throwaway git repositories for tests.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ * StagedRepo creates a repository with a commit identity, writes and stages
+ * files (main.go if none are given), and changes into it until the test ends.
+ *
+ * @param t - The test owning the repository
+ * @param files - Paths to create, relative to the repository root
+ * @returns The repository directory
+ */
+func StagedRepo(t testing.TB, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if len(files) == 0 {
+		files = []string{"main.go"}
+	}
+
+	run := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	run("config", "user.email", "test@example.com")
+	run("config", "user.name", "Test User")
+
+	for _, name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	run(append([]string{"add", "--"}, files...)...)
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(oldCwd) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	return dir
+}
@@ -2,9 +2,11 @@
 package git_test
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/avgt93/commit-gen/internal/git"
@@ -420,3 +422,519 @@ func BenchmarkGetStagedDiff(b *testing.B) {
 		_, _ = git.GetStagedDiff()
 	}
 }
+
+func TestIntegrationGetPartiallyStagedFiles(t *testing.T) {
+	tmpDir := setupTestRepo(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	err = os.Chdir(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	original := strings.Join(lines, "\n") + "\n"
+
+	if err := os.WriteFile("partial.txt", []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile("full.txt", []byte("full\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	for _, args := range [][]string{{"add", "."}, {"commit", "-m", "Initial commit"}} {
+		if err := exec.Command("git", args...).Run(); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+
+	lines[0] = "line 1 changed"
+	lines[29] = "line 30 changed"
+	if err := os.WriteFile("partial.txt", []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+	if err := os.WriteFile("full.txt", []byte("full changed\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+
+	patch, err := exec.Command("git", "diff", "partial.txt").Output()
+	if err != nil {
+		t.Fatalf("Failed to get diff: %v", err)
+	}
+	secondHunk := strings.LastIndex(string(patch), "\n@@")
+	if secondHunk < 0 || strings.Count(string(patch), "\n@@") != 2 {
+		t.Fatalf("Expected a two-hunk diff, got:\n%s", patch)
+	}
+	firstHunkOnly := string(patch)[:secondHunk+1]
+
+	apply := exec.Command("git", "apply", "--cached")
+	apply.Stdin = strings.NewReader(firstHunkOnly)
+	if out, err := apply.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to stage first hunk: %v\n%s", err, out)
+	}
+	if err := exec.Command("git", "add", "full.txt").Run(); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+
+	partial, err := git.GetPartiallyStagedFiles()
+	if err != nil {
+		t.Fatalf("GetPartiallyStagedFiles failed: %v", err)
+	}
+
+	if len(partial) != 1 || partial[0] != "partial.txt" {
+		t.Errorf("✗ Expected [partial.txt], got %v", partial)
+	} else {
+		t.Logf("✓ Partially staged files: %v", partial)
+	}
+}
+
+func TestIntegrationStagedDiffForPaths(t *testing.T) {
+	tmpDir := setupTestRepo(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	for _, name := range []string{"picked.txt", "other.txt"} {
+		if err := os.WriteFile(name, []byte(name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	if err := exec.Command("git", "add", ".").Run(); err != nil {
+		t.Fatalf("Failed to stage files: %v", err)
+	}
+
+	diff, err := git.GetStagedDiffForPaths([]string{"picked.txt"})
+	if err != nil {
+		t.Fatalf("GetStagedDiffForPaths failed: %v", err)
+	}
+	if !strings.Contains(diff, "picked.txt") || strings.Contains(diff, "other.txt") {
+		t.Errorf("✗ Expected diff of picked.txt only, got:\n%s", diff)
+	}
+
+	if err := git.UnstageFiles([]string{"other.txt"}); err != nil {
+		t.Fatalf("UnstageFiles failed: %v", err)
+	}
+	staged, err := git.GetChangedFiles()
+	if err != nil {
+		t.Fatalf("GetChangedFiles failed: %v", err)
+	}
+	if len(staged) != 1 || staged[0] != "picked.txt" {
+		t.Errorf("✗ Expected only picked.txt staged, got %v", staged)
+	} else {
+		t.Log("✓ Diff scoped to selected paths and the rest unstaged")
+	}
+}
+
+func TestIntegrationRelativeDiffFromSubdirectory(t *testing.T) {
+	tmpDir := setupTestRepo(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	subDir := filepath.Join(tmpDir, "services", "billing")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(subDir, "invoice.go"), []byte("package billing\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	cmd := exec.Command("git", "add", ".")
+	cmd.Dir = tmpDir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+
+	if err := os.Chdir(subDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	absolute, err := git.GetStagedDiffWithOptions(0, git.DiffOptions{})
+	if err != nil {
+		t.Fatalf("GetStagedDiffWithOptions failed: %v", err)
+	}
+	if !strings.Contains(absolute.Diff, "b/services/billing/invoice.go") {
+		t.Errorf("✗ Expected repository-relative path in default diff:\n%s", absolute.Diff)
+	}
+
+	relative, err := git.GetStagedDiffWithOptions(0, git.DiffOptions{Relative: true})
+	if err != nil {
+		t.Fatalf("GetStagedDiffWithOptions failed: %v", err)
+	}
+	if !strings.Contains(relative.Diff, "b/invoice.go") || strings.Contains(relative.Diff, "services/billing") {
+		t.Errorf("✗ Expected paths relative to subdirectory:\n%s", relative.Diff)
+	} else {
+		t.Log("✓ Diff paths relativized to current directory")
+	}
+}
+
+func TestIntegrationGetWorkingDiff(t *testing.T) {
+	tmpDir := setupTestRepo(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	// Before the first commit the working diff compares against the empty tree.
+	if err := os.WriteFile("tracked.txt", []byte("first\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := exec.Command("git", "add", "tracked.txt").Run(); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+	if err := os.WriteFile("tracked.txt", []byte("first\nunstaged\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+
+	diff, err := git.GetWorkingDiff()
+	if err != nil {
+		t.Fatalf("GetWorkingDiff without HEAD failed: %v", err)
+	}
+	if !strings.Contains(diff, "+unstaged") {
+		t.Errorf("✗ Expected unstaged line in diff without HEAD, got:\n%s", diff)
+	}
+
+	if err := exec.Command("git", "commit", "-qam", "initial").Run(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	if err := os.WriteFile("tracked.txt", []byte("first\nunstaged\nchanged\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+
+	staged, err := git.GetStagedDiff()
+	if err != nil {
+		t.Fatalf("GetStagedDiff failed: %v", err)
+	}
+	if staged != "" {
+		t.Errorf("✗ Expected no staged diff, got:\n%s", staged)
+	}
+
+	diff, err = git.GetWorkingDiff()
+	if err != nil {
+		t.Fatalf("GetWorkingDiff failed: %v", err)
+	}
+	if !strings.Contains(diff, "+changed") {
+		t.Errorf("✗ Expected unstaged change in working diff, got:\n%s", diff)
+	} else {
+		t.Log("✓ Working diff includes unstaged changes with and without HEAD")
+	}
+}
+
+func TestIntegrationGetBranchName(t *testing.T) {
+	tmpDir := setupTestRepo(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"checkout", "-q", "-b", "feature/PROJ-123"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if err := exec.Command("git", args...).Run(); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+
+	branch, err := git.GetBranchName()
+	if err != nil {
+		t.Fatalf("GetBranchName failed: %v", err)
+	}
+	if branch != "feature/PROJ-123" {
+		t.Errorf("✗ Expected feature/PROJ-123, got %q", branch)
+	}
+
+	if err := exec.Command("git", "checkout", "-q", "--detach").Run(); err != nil {
+		t.Fatalf("Failed to detach HEAD: %v", err)
+	}
+
+	branch, err = git.GetBranchName()
+	if err != nil {
+		t.Fatalf("GetBranchName on detached HEAD failed: %v", err)
+	}
+	if branch != "" {
+		t.Errorf("✗ Expected empty branch on detached HEAD, got %q", branch)
+	} else {
+		t.Log("✓ Branch name returned, empty on detached HEAD")
+	}
+}
+
+func TestIntegrationExcludePaths(t *testing.T) {
+	tmpDir := setupTestRepo(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	if err := os.MkdirAll("web", 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	for _, name := range []string{"main.go", "go.sum", "web/package-lock.json"} {
+		if err := os.WriteFile(name, []byte(name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	if err := exec.Command("git", "add", ".").Run(); err != nil {
+		t.Fatalf("Failed to stage files: %v", err)
+	}
+
+	git.SetExcludePaths(git.DefaultExcludePaths)
+	defer git.SetExcludePaths(nil)
+
+	diff, err := git.GetStagedDiff()
+	if err != nil {
+		t.Fatalf("GetStagedDiff failed: %v", err)
+	}
+	if !strings.Contains(diff, "main.go") || strings.Contains(diff, "go.sum") || strings.Contains(diff, "package-lock.json") {
+		t.Errorf("✗ Lock files not excluded from diff:\n%s", diff)
+	}
+
+	files, err := git.GetChangedFiles()
+	if err != nil {
+		t.Fatalf("GetChangedFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "main.go" {
+		t.Errorf("✗ Expected only main.go in the file list, got %v", files)
+	} else {
+		t.Log("✓ Lock files excluded from diff and file list")
+	}
+}
+
+func TestIntegrationBinaryFiles(t *testing.T) {
+	tmpDir := setupTestRepo(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	blob := append([]byte("\x89PNG\r\n\x1a\n\x00\x00"), make([]byte, 2038)...)
+	if err := os.WriteFile("logo.png", blob, 0644); err != nil {
+		t.Fatalf("Failed to create binary file: %v", err)
+	}
+	if err := exec.Command("git", "add", "logo.png").Run(); err != nil {
+		t.Fatalf("Failed to stage binary file: %v", err)
+	}
+
+	result, err := git.GetStagedDiffWithOptions(0, git.DiffOptions{})
+	if err != nil {
+		t.Fatalf("GetStagedDiffWithOptions failed: %v", err)
+	}
+
+	if !strings.Contains(result.Diff, "binary: logo.png (2048 bytes)") {
+		t.Errorf("✗ Missing binary note:\n%s", result.Diff)
+	}
+	if strings.Contains(result.Diff, "Binary files") {
+		t.Errorf("✗ Raw binary diff line should be replaced:\n%s", result.Diff)
+	}
+	if !result.BinaryOnly || len(result.BinaryFiles) != 1 || result.BinaryFiles[0] != "logo.png" {
+		t.Errorf("✗ Expected logo.png as the only binary file, got %v (binary only: %v)", result.BinaryFiles, result.BinaryOnly)
+	}
+	if sig := git.ClassifyChangeSignificance(result.Diff); sig != git.SignificanceLogic {
+		t.Errorf("✗ Binary change classified as %q", sig)
+	}
+
+	if err := os.WriteFile("README.md", []byte("# Assets\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := exec.Command("git", "add", "README.md").Run(); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+
+	result, err = git.GetStagedDiffWithOptions(0, git.DiffOptions{})
+	if err != nil {
+		t.Fatalf("GetStagedDiffWithOptions failed: %v", err)
+	}
+	if result.BinaryOnly || !strings.Contains(result.Diff, "+# Assets") {
+		t.Errorf("✗ Mixed diff should keep text changes and not be binary only:\n%s", result.Diff)
+	} else {
+		t.Log("✓ Binary files are described by size instead of raw diff")
+	}
+}
+
+func TestIntegrationAmendDiff(t *testing.T) {
+	tmpDir := setupTestRepo(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	commitFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := exec.Command("git", "add", name).Run(); err != nil {
+			t.Fatalf("Failed to stage %s: %v", name, err)
+		}
+		if err := exec.Command("git", "commit", "-m", "add "+name).Run(); err != nil {
+			t.Fatalf("Failed to commit %s: %v", name, err)
+		}
+	}
+	amendDiff := func() string {
+		t.Helper()
+		base, err := git.GetAmendBase()
+		if err != nil {
+			t.Fatalf("GetAmendBase failed: %v", err)
+		}
+		result, err := git.GetStagedDiffWithOptions(0, git.DiffOptions{Base: base})
+		if err != nil {
+			t.Fatalf("GetStagedDiffWithOptions failed: %v", err)
+		}
+		return result.Diff
+	}
+
+	if _, err := git.GetAmendBase(); err == nil {
+		t.Error("✗ Expected an error with no commit to amend")
+	}
+
+	commitFile("root.txt", "root\n")
+	if err := os.WriteFile("staged.txt", []byte("staged\n"), 0644); err != nil {
+		t.Fatalf("Failed to write staged.txt: %v", err)
+	}
+	if err := exec.Command("git", "add", "staged.txt").Run(); err != nil {
+		t.Fatalf("Failed to stage staged.txt: %v", err)
+	}
+
+	diff := amendDiff()
+	if !strings.Contains(diff, "+root") || !strings.Contains(diff, "+staged") {
+		t.Errorf("✗ Root commit amend diff should include the commit and staged changes:\n%s", diff)
+	}
+
+	commitFile("second.txt", "second\n")
+	if err := os.WriteFile("staged.txt", []byte("staged again\n"), 0644); err != nil {
+		t.Fatalf("Failed to write staged.txt: %v", err)
+	}
+	if err := exec.Command("git", "add", "staged.txt").Run(); err != nil {
+		t.Fatalf("Failed to stage staged.txt: %v", err)
+	}
+
+	diff = amendDiff()
+	if !strings.Contains(diff, "+second") || !strings.Contains(diff, "+staged again") {
+		t.Errorf("✗ Amend diff should include HEAD and staged changes:\n%s", diff)
+	}
+	if strings.Contains(diff, "root.txt") {
+		t.Errorf("✗ Amend diff should not include earlier commits:\n%s", diff)
+	} else {
+		t.Log("✓ Amend diff combines the last commit with staged changes")
+	}
+}
+
+func TestIntegrationCommit(t *testing.T) {
+	tmpDir := setupTestRepo(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	stage := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := exec.Command("git", "add", name).Run(); err != nil {
+			t.Fatalf("Failed to stage %s: %v", name, err)
+		}
+	}
+	lastMessage := func() string {
+		t.Helper()
+		message, err := git.GetLastCommitMessage()
+		if err != nil {
+			t.Fatalf("GetLastCommitMessage failed: %v", err)
+		}
+		return message
+	}
+
+	stage("app.go", "package app\n")
+	if err := git.Commit("feat: add app\n\nFirst version.\n", git.CommitOptions{}); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if got := lastMessage(); got != "feat: add app\n\nFirst version." {
+		t.Errorf("✗ Unexpected commit message: %q", got)
+	}
+
+	stage("app.go", "package app\n\nfunc Run() {}\n")
+	if err := git.Commit("feat: add app with Run\n", git.CommitOptions{Amend: true}); err != nil {
+		t.Fatalf("Amend commit failed: %v", err)
+	}
+	if got := lastMessage(); got != "feat: add app with Run" {
+		t.Errorf("✗ Amended commit has unexpected message: %q", got)
+	}
+	if out, _ := exec.Command("git", "rev-list", "--count", "HEAD").Output(); strings.TrimSpace(string(out)) != "1" {
+		t.Errorf("✗ Amend should replace HEAD, got %s commits", strings.TrimSpace(string(out)))
+	}
+
+	hook := filepath.Join(tmpDir, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\necho 'lint failed: app.go' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write pre-commit hook: %v", err)
+	}
+	stage("other.go", "package app\n")
+	err = git.Commit("feat: add other\n", git.CommitOptions{})
+	if err == nil || !strings.Contains(err.Error(), "lint failed: app.go") {
+		t.Errorf("✗ Expected the pre-commit hook's stderr in the error, got %v", err)
+	} else {
+		t.Log("✓ Commit, amend with sign-off, and hook rejection handled")
+	}
+}
@@ -0,0 +1,81 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrEmptyDiff is returned when a provided diff has no file changes.
+var ErrEmptyDiff = errors.New("the provided diff contains no file changes")
+
+/**
+ * LimitProvidedDiff applies the same binary, collapse, and summarization
+ * handling as GetStagedDiffWithOptions to a diff read from a file or stdin
+ * instead of git. The stat and file list of a summary come from the diff
+ * text, and binary file sizes are reported as unknown.
+ *
+ * @param diff - A unified diff with "diff --git" file headers
+ * @param maxSize - Maximum size in bytes before summarizing (0 uses default)
+ * @param collapseThreshold - Per-file size above which to collapse; zero disables collapsing
+ * @returns A DiffResult containing the diff and metadata about summarization
+ * @returns ErrEmptyDiff if the diff has no file sections
+ */
+func LimitProvidedDiff(diff string, maxSize, collapseThreshold int) (*DiffResult, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDiffSize
+	}
+
+	diff = strings.ReplaceAll(diff, "\r\n", "\n")
+	if len(diffFiles(diff)) == 0 {
+		return nil, ErrEmptyDiff
+	}
+
+	return limitDiff(diff, maxSize, collapseThreshold, diffSource{
+		binaryNote: func(path string, deleted bool) string {
+			if deleted {
+				return "(deleted)"
+			}
+			return "(size unknown)"
+		},
+		stat: func() (string, error) {
+			return diffTextStat(diff), nil
+		},
+		files: func() ([]string, error) {
+			return diffFiles(diff), nil
+		},
+	}), nil
+}
+
+/**
+ * ProvidedDiffFiles lists the files changed by a diff read from a file or
+ * stdin, in diff order.
+ *
+ * @param diff - A unified diff with "diff --git" file headers
+ * @returns The new-side path of each file
+ */
+func ProvidedDiffFiles(diff string) []string {
+	return diffFiles(strings.ReplaceAll(diff, "\r\n", "\n"))
+}
+
+/**
+ * diffTextStat builds a stat similar to `git diff --stat` from the diff
+ * text: one "path | +A -R" line per file and a totals line.
+ */
+func diffTextStat(diff string) string {
+	var sb strings.Builder
+	files, totalAdded, totalRemoved := 0, 0, 0
+	for _, section := range splitDiffByFile(diff) {
+		header, _, _ := strings.Cut(section, "\n")
+		if !strings.HasPrefix(header, "diff --git ") {
+			continue
+		}
+		added, removed := countChangedLines(section)
+		sb.WriteString(fmt.Sprintf(" %s | +%d -%d\n", diffSectionPath(header), added, removed))
+		files++
+		totalAdded += added
+		totalRemoved += removed
+	}
+	sb.WriteString(fmt.Sprintf(" %d files changed, %d insertions(+), %d deletions(-)\n", files, totalAdded, totalRemoved))
+	return sb.String()
+}
@@ -0,0 +1,47 @@
+package git
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLimitProvidedDiff(t *testing.T) {
+	diff := "diff --git a/pkg/api.go b/pkg/api.go\r\n" +
+		"--- a/pkg/api.go\r\n" +
+		"+++ b/pkg/api.go\r\n" +
+		"@@ -1 +1,2 @@\r\n" +
+		" package api\r\n" +
+		"+func Provided() {}\r\n" +
+		"diff --git a/logo.png b/logo.png\r\n" +
+		"Binary files a/logo.png and b/logo.png differ\r\n"
+
+	result, err := LimitProvidedDiff(diff, 0, 0)
+	if err != nil {
+		t.Fatalf("LimitProvidedDiff failed: %v", err)
+	}
+	if result.IsSummarized || strings.Contains(result.Diff, "\r") {
+		t.Errorf("Expected an unsummarized diff with normalized line endings, got %q", result.Diff)
+	}
+	if !reflect.DeepEqual(result.Files, []string{"pkg/api.go", "logo.png"}) {
+		t.Errorf("Unexpected files: %v", result.Files)
+	}
+	if !strings.Contains(result.Diff, "binary: logo.png (size unknown)") {
+		t.Errorf("Expected a binary note without a size, got %q", result.Diff)
+	}
+
+	summarized, err := LimitProvidedDiff(diff, 64, 0)
+	if err != nil {
+		t.Fatalf("LimitProvidedDiff failed: %v", err)
+	}
+	if !summarized.IsSummarized ||
+		!strings.Contains(summarized.Diff, " pkg/api.go | +1 -0") ||
+		!strings.Contains(summarized.Diff, "2 files changed, 1 insertions(+), 0 deletions(-)") {
+		t.Errorf("Expected a summary with a stat built from the diff, got %q", summarized.Diff)
+	}
+
+	if _, err := LimitProvidedDiff("not a diff\n", 0, 0); !errors.Is(err, ErrEmptyDiff) {
+		t.Errorf("Expected ErrEmptyDiff, got %v", err)
+	}
+}
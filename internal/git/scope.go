@@ -0,0 +1,154 @@
+package git
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var goPackagePattern = regexp.MustCompile(`^package\s+([A-Za-z_][A-Za-z0-9_]*)`)
+
+/**
+ * InferScopeFromMetadata derives a conventional commit scope for the changed
+ * files. Go files use their package clause and files in a Node package use the
+ * nearest package.json name; the scope is used only if every file with such
+ * metadata shares it, so changes spanning several packages get no scope. When
+ * no file has such metadata, the deepest directory shared by all files is used
+ * (see CommonDirectoryScope).
+ *
+ * @param files - Changed file paths relative to the repository root
+ * @returns The inferred scope, or empty string if none could be derived
+ */
+func InferScopeFromMetadata(files []string) string {
+	root, err := GetRepositoryRoot()
+	if err != nil {
+		root = "."
+	}
+
+	shared := ""
+	for _, f := range files {
+		scope := metadataScope(root, f)
+		if scope == "" {
+			continue
+		}
+		if shared != "" && scope != shared {
+			return ""
+		}
+		shared = scope
+	}
+
+	if shared == "" {
+		return CommonDirectoryScope(files)
+	}
+	return shared
+}
+
+/**
+ * CommonDirectoryScope derives a scope from the directory layout alone: the
+ * name of the deepest directory containing every file, e.g. "git" when all
+ * files are under internal/git.
+ *
+ * @param files - Changed file paths relative to the repository root
+ * @returns The scope, or empty string if the files share no directory (a root
+ * file, or files under unrelated top-level directories)
+ */
+func CommonDirectoryScope(files []string) string {
+	var common []string
+	for i, f := range files {
+		dir := path.Dir(filepath.ToSlash(f))
+		if dir == "." || dir == "/" {
+			return ""
+		}
+		parts := strings.Split(strings.Trim(dir, "/"), "/")
+		if i == 0 {
+			common = parts
+			continue
+		}
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+		if len(common) == 0 {
+			return ""
+		}
+	}
+	if len(common) == 0 {
+		return ""
+	}
+	return common[len(common)-1]
+}
+
+// metadataScope returns the Go package or Node package name owning file.
+func metadataScope(root, file string) string {
+	path := filepath.Join(root, filepath.FromSlash(file))
+	if filepath.Ext(file) == ".go" {
+		if name := goPackageName(path); name != "" && name != "main" {
+			return strings.TrimSuffix(name, "_test")
+		}
+	}
+	return nodePackageName(root, filepath.Dir(path))
+}
+
+// goPackageName reads the package clause of a Go file, or of a sibling if the file is gone.
+func goPackageName(path string) string {
+	if name := readGoPackage(path); name != "" {
+		return name
+	}
+	siblings, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.go"))
+	for _, sibling := range siblings {
+		if name := readGoPackage(sibling); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func readGoPackage(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if match := goPackagePattern.FindStringSubmatch(strings.TrimSpace(scanner.Text())); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// nodePackageName walks up from dir to root looking for a package.json name.
+func nodePackageName(root, dir string) string {
+	root = filepath.Clean(root)
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+		if err == nil {
+			var pkg struct {
+				Name string `json:"name"`
+			}
+			if json.Unmarshal(data, &pkg) == nil && pkg.Name != "" {
+				name := pkg.Name
+				if idx := strings.LastIndex(name, "/"); idx >= 0 {
+					name = name[idx+1:]
+				}
+				return name
+			}
+		}
+
+		if filepath.Clean(dir) == root {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeScopeFixture writes files under a temporary directory and changes into it.
+func writeScopeFixture(t *testing.T, files map[string]string) {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(oldCwd) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+}
+
+func TestInferScopeFromGoPackage(t *testing.T) {
+	writeScopeFixture(t, map[string]string{
+		"internal/storage/sql/db.go":      "// Package sqlstore persists records.\npackage sqlstore\n",
+		"internal/storage/sql/db_test.go": "package sqlstore_test\n",
+	})
+
+	scope := InferScopeFromMetadata([]string{"internal/storage/sql/db.go", "internal/storage/sql/db_test.go"})
+	if scope != "sqlstore" {
+		t.Errorf("Expected scope sqlstore, got %q", scope)
+	}
+
+	t.Log("✓ Scope inferred from Go package clause")
+}
+
+func TestInferScopeSkipsSeveralPackages(t *testing.T) {
+	writeScopeFixture(t, map[string]string{
+		"internal/git/diff.go":       "package git\n",
+		"internal/git/scope.go":      "package git\n",
+		"internal/config/config.go":  "package config\n",
+		"internal/config/default.go": "package config\n",
+	})
+
+	if scope := InferScopeFromMetadata([]string{"internal/git/diff.go", "internal/config/config.go"}); scope != "" {
+		t.Errorf("Expected no scope for two packages, got %q", scope)
+	}
+	if scope := InferScopeFromMetadata([]string{"internal/git/diff.go", "internal/git/scope.go", "internal/config/config.go"}); scope != "" {
+		t.Errorf("Expected no scope when most files share a package, got %q", scope)
+	}
+	if scope := InferScopeFromMetadata([]string{"internal/git/diff.go", "README.md"}); scope != "git" {
+		t.Errorf("Expected the only package scope git, got %q", scope)
+	}
+}
+
+func TestInferScopeFromNodeSubpackage(t *testing.T) {
+	writeScopeFixture(t, map[string]string{
+		"package.json":                  `{"name": "monorepo"}`,
+		"packages/ui/package.json":      `{"name": "@acme/ui-kit"}`,
+		"packages/ui/src/button/btn.ts": "export const x = 1\n",
+	})
+
+	scope := InferScopeFromMetadata([]string{"packages/ui/src/button/btn.ts"})
+	if scope != "ui-kit" {
+		t.Errorf("Expected scope ui-kit, got %q", scope)
+	}
+
+	t.Log("✓ Scope inferred from nearest package.json")
+}
+
+func TestInferScopeFallsBackToDirectory(t *testing.T) {
+	writeScopeFixture(t, map[string]string{
+		"docs/guide.md": "# Guide\n",
+	})
+
+	if scope := InferScopeFromMetadata([]string{"docs/guide.md"}); scope != "docs" {
+		t.Errorf("Expected directory scope docs, got %q", scope)
+	}
+	if scope := InferScopeFromMetadata([]string{"README.md"}); scope != "" {
+		t.Errorf("Expected no scope for a root file, got %q", scope)
+	}
+}
+
+func TestCommonDirectoryScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		expected string
+	}{
+		{"single package", []string{"internal/git/diff.go", "internal/git/scope.go"}, "git"},
+		{"nested directories", []string{"internal/git/diff.go", "internal/git/testdata/a.diff"}, "git"},
+		{"shared parent", []string{"internal/git/diff.go", "internal/config/config.go"}, "internal"},
+		{"unrelated directories", []string{"docs/guide.md", "scripts/release.sh"}, ""},
+		{"root file", []string{"docs/guide.md", "README.md"}, ""},
+		{"prefix is not a directory", []string{"web/app.ts", "webhooks/handler.ts"}, ""},
+		{"no files", nil, ""},
+	}
+
+	for _, tt := range tests {
+		if scope := CommonDirectoryScope(tt.files); scope != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, scope)
+		}
+	}
+}
+
+func TestInferScopeSkipsUnrelatedDirectories(t *testing.T) {
+	writeScopeFixture(t, map[string]string{
+		"docs/guide.md":      "# Guide\n",
+		"docs/api/index.md":  "# API\n",
+		"scripts/release.sh": "#!/bin/sh\n",
+	})
+
+	if scope := InferScopeFromMetadata([]string{"docs/guide.md", "docs/api/index.md"}); scope != "docs" {
+		t.Errorf("Expected common directory scope docs, got %q", scope)
+	}
+	if scope := InferScopeFromMetadata([]string{"docs/guide.md", "docs/api/index.md", "scripts/release.sh"}); scope != "" {
+		t.Errorf("Expected no scope for unrelated directories, got %q", scope)
+	}
+}
@@ -0,0 +1,116 @@
+package git
+
+import (
+	"strings"
+)
+
+/**
+ * ChangeSignificance estimates whether a diff changes behavior or only
+ * touches comments and formatting.
+ */
+type ChangeSignificance string
+
+const (
+	// SignificanceLogic means at least one code line changed.
+	SignificanceLogic ChangeSignificance = "logic"
+	// SignificanceComments means only comments (and possibly whitespace) changed.
+	SignificanceComments ChangeSignificance = "comments"
+	// SignificanceWhitespace means only blank lines or indentation changed.
+	SignificanceWhitespace ChangeSignificance = "whitespace"
+)
+
+/**
+ * ClassifyChangeSignificance inspects the added and removed lines of a
+ * unified diff and estimates whether the change is comment/whitespace-only.
+ * The heuristics are language-agnostic: lines starting with "//" or "#" and
+ * lines inside "/* ... *\/" blocks count as comments, and code lines that
+ * only differ in whitespace count as formatting.
+ *
+ * @param diff - The unified diff to classify
+ * @returns The estimated significance of the change
+ */
+func ClassifyChangeSignificance(diff string) ChangeSignificance {
+	added := map[string]int{}
+	removed := map[string]int{}
+	comments := false
+	inBlock := false
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "diff --git ") {
+			inBlock = false
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---") {
+			continue
+		}
+		// A binary file changed, so assume behavior or content changed too.
+		if strings.HasPrefix(line, "binary: ") || strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch" {
+			return SignificanceLogic
+		}
+
+		marker := line[0]
+		if marker != '+' && marker != '-' && marker != ' ' {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line[1:])
+		isComment := inBlock || isCommentLine(trimmed)
+		inBlock = updateBlockComment(trimmed, inBlock)
+
+		if marker == ' ' || trimmed == "" {
+			continue
+		}
+		if isComment {
+			comments = true
+			continue
+		}
+
+		normalized := strings.Join(strings.Fields(trimmed), "")
+		if marker == '+' {
+			added[normalized]++
+		} else {
+			removed[normalized]++
+		}
+	}
+
+	if !sameCounts(added, removed) {
+		return SignificanceLogic
+	}
+	if comments {
+		return SignificanceComments
+	}
+	return SignificanceWhitespace
+}
+
+// isCommentLine reports whether a trimmed line starts a line or block comment.
+func isCommentLine(trimmed string) bool {
+	for _, prefix := range []string{"//", "#", "/*"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateBlockComment returns whether a "/* ... */" block is still open after trimmed.
+func updateBlockComment(trimmed string, inBlock bool) bool {
+	if inBlock {
+		return !strings.Contains(trimmed, "*/")
+	}
+	if rest, ok := strings.CutPrefix(trimmed, "/*"); ok {
+		return !strings.Contains(rest, "*/")
+	}
+	return false
+}
+
+func sameCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package git
+
+import "testing"
+
+func TestClassifyCommentOnlyDiff(t *testing.T) {
+	diff := `diff --git a/app.go b/app.go
+--- a/app.go
++++ b/app.go
+@@ -1,6 +1,9 @@
+ package app
+ 
+-// Run starts the app.
++// Run starts the app and blocks until it exits.
++/*
++ * Callers must configure logging first.
++ */
+ func Run() {
+diff --git a/setup.py b/setup.py
+--- a/setup.py
++++ b/setup.py
+@@ -1,2 +1,3 @@
++# Build configuration
+ import setuptools
+`
+
+	if got := ClassifyChangeSignificance(diff); got != SignificanceComments {
+		t.Errorf("Expected %q, got %q", SignificanceComments, got)
+	}
+
+	t.Log("✓ Comment-only diff classified as comments")
+}
+
+func TestClassifyWhitespaceOnlyDiff(t *testing.T) {
+	diff := `diff --git a/app.go b/app.go
+--- a/app.go
++++ b/app.go
+@@ -1,3 +1,4 @@
+ func Run() {
+-return  start()
++	return start()
++
+ }
+`
+
+	if got := ClassifyChangeSignificance(diff); got != SignificanceWhitespace {
+		t.Errorf("Expected %q, got %q", SignificanceWhitespace, got)
+	}
+}
+
+func TestClassifyLogicChangeDiff(t *testing.T) {
+	diff := `diff --git a/app.go b/app.go
+--- a/app.go
++++ b/app.go
+@@ -1,3 +1,4 @@
+ func Run() {
+-	return start()
++	// Start with retries.
++	return startWithRetry(3)
+ }
+`
+
+	if got := ClassifyChangeSignificance(diff); got != SignificanceLogic {
+		t.Errorf("Expected %q, got %q", SignificanceLogic, got)
+	}
+
+	t.Log("✓ Logic change classified as logic")
+}
@@ -2,9 +2,11 @@
 package hook
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/avgt93/commit-gen/internal/git"
@@ -12,7 +14,39 @@ import (
 
 const hookName = "prepare-commit-msg"
 
-const hookScriptFmt = `#!/bin/bash
+// lintHookName is the hook install --lint writes to check messages.
+const lintHookName = "commit-msg"
+
+// hookMarkerPrefix starts the line that marks a hook as written by
+// commit-gen; the script version follows it.
+const hookMarkerPrefix = "# commit-gen-managed-hook v"
+
+// hookVersion is the version of the hook scripts. Bump it whenever a script
+// changes, so install rewrites hooks written by older releases.
+const hookVersion = 4
+
+// hookMarker is the marker line embedded in every hook script.
+const hookMarker = hookMarkerPrefix + "4"
+
+// legacyHookHeaders identify hooks written before the marker line existed;
+// they count as version 0.
+var legacyHookHeaders = []string{"# commit-gen git hook", "# commit-gen commit-msg hook"}
+
+// ErrAlreadyInstalled is returned when the commit-gen hook is already present.
+var ErrAlreadyInstalled = errors.New("hook already installed")
+
+// chainSnippetFmt runs the preserved original hook, named by %s, before
+// commit-gen, aborting the commit if it fails.
+const chainSnippetFmt = `
+# Run the original hook first (chained by commit-gen install --chain)
+LOCAL_HOOK="$(dirname "$0")/%s"
+if [ -x "$LOCAL_HOOK" ]; then
+  "$LOCAL_HOOK" "$@" || exit $?
+fi
+`
+
+const hookScriptFmt = `#!/bin/sh
+` + hookMarker + `
 # commit-gen git hook
 # Auto-generates commit messages for empty commit messages
 
@@ -20,16 +54,58 @@ MESSAGE_FILE=$1
 COMMIT_SOURCE=$2
 SHA1=$3
 
-# Only run for normal commits (not for merge commits, etc.)
-if [ "$COMMIT_SOURCE" != "" ]; then
-  exit 0
-fi
+AMEND=""
+case "$COMMIT_SOURCE" in
+  merge|squash)
+    # git prepared the message; leave it alone
+    exit 0
+    ;;
+  commit)
+    # git commit --amend reuses HEAD's message; commit-gen decides from
+    # hook.on_amend whether to regenerate it. -c/-C reuse another commit.
+    if [ "$SHA1" != "$(git rev-parse HEAD 2>/dev/null)" ]; then
+      exit 0
+    fi
+    AMEND="--amend"
+    ;;
+  ""|message|template)
+    # Plain commits, -m/-F (only when the message is empty) and commit.template
+    ;;
+  *)
+    exit 0
+    ;;
+esac
+
+# Comment lines start with core.commentString or core.commentChar, like
+# git itself strips them ("#" when unset or "auto")
+COMMENT_CHAR=$(git config core.commentString 2>/dev/null || git config core.commentChar 2>/dev/null || true)
+case "$COMMENT_CHAR" in
+  ""|auto) COMMENT_CHAR="#" ;;
+esac
 
-# Read the current message and filter out comment lines (starting with #)
-MESSAGE=$(grep -v '^#' "$MESSAGE_FILE" 2>/dev/null | xargs)
+# message_lines prints the message file without comment lines or anything
+# from the scissors line on, where git commit -v puts the diff
+message_lines() {
+  awk -v c="$COMMENT_CHAR" '/------------------------ >8 ------------------------/ { exit } index($0, c) != 1' "$MESSAGE_FILE" 2>/dev/null
+}
+
+# Read the current message without comments, the verbose diff, or whitespace
+MESSAGE=$(message_lines | tr -d '[:space:]')
+
+# Lines copied unchanged from commit.template do not count as a message
+SEEDED=""
+TEMPLATE=$(git config --path commit.template 2>/dev/null || true)
+if [ -n "$MESSAGE" ] && [ -n "$TEMPLATE" ] && [ -f "$TEMPLATE" ]; then
+  OWN=$(message_lines | grep -v -x -F -f "$TEMPLATE" | tr -d '[:space:]')
+  if [ -z "$OWN" ]; then
+    MESSAGE=""
+    SEEDED=1
+  fi
+fi
 
-# Check if message is empty (only whitespace and comments)
-if [ -z "$MESSAGE" ]; then
+# Generate for an empty message, an amend, or above an unedited template;
+# never overwrite a message given with -m or -F
+if [ -z "$MESSAGE" ] || [ -n "$AMEND" ] || [ "$COMMIT_SOURCE" = "template" ]; then
   # Change to git root directory to ensure git commands work
   GIT_ROOT=$(git rev-parse --show-toplevel 2>/dev/null)
   if [ -z "$GIT_ROOT" ]; then
@@ -38,18 +114,23 @@ if [ -z "$MESSAGE" ]; then
   cd "$GIT_ROOT" || exit 0
   
   # Generate commit message
-  GENERATED=$("%s" generate --hook 2>/dev/null)
+  # "|| true" keeps failures harmless when run via "sh -e" (Husky)
+  GENERATED=$("%s" generate --hook $AMEND 2>/dev/null || true)
   
   # Only write if we got output
   if [ -n "$GENERATED" ]; then
-    # Preserve the comment lines from original message file
-    COMMENTS=$(grep '^#' "$MESSAGE_FILE" 2>/dev/null)
+    # Keep the whole template, otherwise only the comment lines and the
+    # verbose diff from the scissors line on
+    if [ "$COMMIT_SOURCE" = "template" ] || [ -n "$SEEDED" ]; then
+      KEEP=$(cat "$MESSAGE_FILE" 2>/dev/null || true)
+    else
+      KEEP=$(awk -v c="$COMMENT_CHAR" '/------------------------ >8 ------------------------/ { cut = 1 } cut || index($0, c) == 1' "$MESSAGE_FILE" 2>/dev/null || true)
+    fi
     
-    # Write generated message followed by comments
-    echo "$GENERATED" > "$MESSAGE_FILE"
-    if [ -n "$COMMENTS" ]; then
-      echo "" >> "$MESSAGE_FILE"
-      echo "$COMMENTS" >> "$MESSAGE_FILE"
+    # Write generated message followed by what was kept
+    printf '%%s\n' "$GENERATED" > "$MESSAGE_FILE"
+    if [ -n "$KEEP" ]; then
+      printf '\n%%s\n' "$KEEP" >> "$MESSAGE_FILE"
     fi
   fi
 fi
@@ -57,10 +138,145 @@ fi
 exit 0
 `
 
-func Install() error {
-	root, err := git.GetRepositoryRoot()
+const lintHookScriptFmt = `#!/bin/sh
+` + hookMarker + `
+# commit-gen commit-msg hook
+# Rejects commit messages that do not follow the configured style
+
+MESSAGE_FILE=$1
+
+exec "%s" lint "$MESSAGE_FILE"
+`
+
+/**
+ * hookSpec is a git hook commit-gen can install.
+ */
+type hookSpec struct {
+	// name is the hook file name, e.g. prepare-commit-msg.
+	name string
+	// scriptFmt is the script, with %s for the commit-gen executable.
+	scriptFmt string
+	// chainAfter is the script line after which a chained original hook runs.
+	chainAfter string
+}
+
+var (
+	generateHook = hookSpec{name: hookName, scriptFmt: hookScriptFmt, chainAfter: "SHA1=$3\n"}
+	lintHook     = hookSpec{name: lintHookName, scriptFmt: lintHookScriptFmt, chainAfter: "MESSAGE_FILE=$1\n"}
+)
+
+// localName is where install --chain moves a pre-existing hook of this name.
+func (h hookSpec) localName() string {
+	return h.name + ".local"
+}
+
+/**
+ * render returns the hook script for the given commit-gen executable. A
+ * chained hook runs the preserved <name>.local hook first.
+ */
+func (h hookSpec) render(exePath string, chain bool) string {
+	script := fmt.Sprintf(h.scriptFmt, exePath)
+	if chain {
+		script = strings.Replace(script, h.chainAfter, h.chainAfter+fmt.Sprintf(chainSnippetFmt, h.localName()), 1)
+	}
+	return script
+}
+
+/**
+ * managedHookVersion reads the commit-gen marker line from a hook script.
+ * Only a whole line counts, so a hook that merely mentions commit-gen is not
+ * mistaken for one commit-gen wrote.
+ *
+ * @param content - The hook script
+ * @returns The script version (0 for hooks from before the marker) and
+ * whether the hook is managed by commit-gen
+ */
+func managedHookVersion(content []byte) (int, bool) {
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, hookMarkerPrefix); ok {
+			if version, err := strconv.Atoi(rest); err == nil {
+				return version, true
+			}
+		}
+		for _, header := range legacyHookHeaders {
+			if line == header {
+				return 0, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// isManagedHook reports whether content is a hook script commit-gen wrote.
+func isManagedHook(content []byte) bool {
+	_, ok := managedHookVersion(content)
+	return ok
+}
+
+/**
+ * getHooksDir returns the directory git actually runs hooks from, honoring
+ * core.hooksPath. Without it, hooks are shared by all worktrees and live in
+ * the common git directory.
+ *
+ * @returns The hooks directory
+ * @returns An error if not in a git repository
+ */
+func getHooksDir() (string, error) {
+	hooksDir, err := git.GetHooksDir()
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return hooksDir, nil
+}
+
+// getHookPath returns the path of the prepare-commit-msg hook (see getHooksDir).
+func getHookPath() (string, error) {
+	hooksDir, err := getHooksDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(hooksDir, hookName), nil
+}
+
+/**
+ * GlobalTemplateDir returns the git template directory install --global
+ * uses: $XDG_CONFIG_HOME/git/template, or ~/.config/git/template.
+ *
+ * @returns The template directory path
+ * @returns An error if the home directory cannot be determined
+ */
+func GlobalTemplateDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return templateDirFor(home, os.Getenv("XDG_CONFIG_HOME")), nil
+}
+
+func templateDirFor(home, xdgConfigHome string) string {
+	if xdgConfigHome == "" {
+		xdgConfigHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(xdgConfigHome, "git", "template")
+}
+
+/**
+ * InstallGlobal writes the hook into the global git template directory and
+ * points init.templateDir at it, so repositories created or cloned afterward
+ * get the hook. Existing repositories are not changed.
+ *
+ * @returns An error if init.templateDir already points elsewhere or a
+ * different hook is in the template
+ */
+func InstallGlobal() error {
+	templateDir, err := GlobalTemplateDir()
 	if err != nil {
-		return fmt.Errorf("not in a git repository: %w", err)
+		return err
+	}
+
+	if current := git.GetGlobalConfigPath("init.templateDir"); current != "" && filepath.Clean(current) != templateDir {
+		return fmt.Errorf("init.templateDir is already set to %s; copy the hook into %s manually or unset it first", current, filepath.Join(current, "hooks"))
 	}
 
 	exe, err := os.Executable()
@@ -72,66 +288,221 @@ func Install() error {
 		return fmt.Errorf("failed to get absolute executable path: %w", err)
 	}
 
-	hookPath := filepath.Join(root, ".git", "hooks", hookName)
+	hookPath := filepath.Join(templateDir, "hooks", hookName)
+	if content, err := os.ReadFile(hookPath); err == nil && !isManagedHook(content) {
+		return fmt.Errorf("hook already exists at %s (not installed by commit-gen)", hookPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(hookPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create template hooks directory: %w", err)
+	}
+	if err := os.WriteFile(hookPath, []byte(generateHook.render(exePath, false)), 0o755); err != nil {
+		return fmt.Errorf("failed to write hook: %w", err)
+	}
+
+	return git.SetGlobalConfig("init.templateDir", templateDir)
+}
+
+/**
+ * UninstallGlobal removes the hook from the global template directory and
+ * unsets init.templateDir if it still points there.
+ *
+ * @returns An error if no commit-gen hook is in the template
+ */
+func UninstallGlobal() error {
+	templateDir, err := GlobalTemplateDir()
+	if err != nil {
+		return err
+	}
+
+	hookPath := filepath.Join(templateDir, "hooks", hookName)
+	content, err := os.ReadFile(hookPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("hook not found at %s", hookPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read hook: %w", err)
+	}
+	if !isManagedHook(content) {
+		return fmt.Errorf("hook at %s is not a commit-gen hook", hookPath)
+	}
+
+	if err := os.Remove(hookPath); err != nil {
+		return fmt.Errorf("failed to remove hook: %w", err)
+	}
+
+	if current := git.GetGlobalConfigPath("init.templateDir"); current != "" && filepath.Clean(current) == templateDir {
+		return git.UnsetGlobalConfig("init.templateDir")
+	}
+	return nil
+}
+
+/**
+ * Install writes the commit-gen hook. It fails if any hook already exists,
+ * except a commit-gen hook from an older release, which is rewritten.
+ *
+ * @returns ErrAlreadyInstalled, or an error if another hook exists or the hook cannot be written
+ */
+func Install() error {
+	return install(generateHook, false)
+}
+
+/**
+ * InstallChained installs the hook like Install, but when another hook is
+ * already present it is renamed to prepare-commit-msg.local and run before
+ * commit-gen. Uninstall restores it.
+ *
+ * @returns An error if both hooks already exist or the hook cannot be written
+ */
+func InstallChained() error {
+	return install(generateHook, true)
+}
+
+/**
+ * InstallLint writes a commit-msg hook that runs `commit-gen lint` on the
+ * message, so commits whose message does not follow the style are rejected.
+ * It coexists with the prepare-commit-msg hook and is removed by Uninstall.
+ *
+ * @param chain - true to keep an existing commit-msg hook as commit-msg.local and run it first
+ * @returns ErrAlreadyInstalled, or an error if another hook exists or the hook cannot be written
+ */
+func InstallLint(chain bool) error {
+	return install(lintHook, chain)
+}
+
+func install(spec hookSpec, chain bool) error {
+	hooksDir, err := getHooksDir()
+	if err != nil {
+		return err
+	}
+	hookPath := filepath.Join(hooksDir, spec.name)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	exePath, err := filepath.Abs(exe)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute executable path: %w", err)
+	}
 
-	hooksDir := filepath.Dir(hookPath)
 	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create hooks directory: %w", err)
 	}
 
+	localPath := filepath.Join(hooksDir, spec.localName())
 	if _, err := os.Stat(hookPath); err == nil {
 		content, err := os.ReadFile(hookPath)
-		if err == nil && strings.Contains(string(content), "commit-gen") {
-			return fmt.Errorf("hook already installed at %s", hookPath)
+		if version, managed := managedHookVersion(content); err == nil && managed {
+			if version >= hookVersion {
+				return fmt.Errorf("%w at %s", ErrAlreadyInstalled, hookPath)
+			}
+			// Rewrite a hook from an older release, keeping its chained hook.
+			_, statErr := os.Stat(localPath)
+			return writeHook(hookPath, spec.render(exePath, statErr == nil))
+		}
+		if !chain {
+			return fmt.Errorf("hook already exists at %s (not installed by commit-gen); use --chain to keep it", hookPath)
+		}
+		if _, err := os.Stat(localPath); err == nil {
+			return fmt.Errorf("cannot chain: both %s and %s already exist; merge or remove one first", hookPath, localPath)
+		}
+		if err := os.Rename(hookPath, localPath); err != nil {
+			return fmt.Errorf("failed to preserve existing hook: %w", err)
 		}
-		return fmt.Errorf("hook already exists at %s (not installed by commit-gen)", hookPath)
 	}
 
-	hookContent := fmt.Sprintf(hookScriptFmt, exePath)
+	if _, err := os.Stat(localPath); err != nil {
+		chain = false
+	}
+
+	return writeHook(hookPath, spec.render(exePath, chain))
+}
 
-	if err := os.WriteFile(hookPath, []byte(hookContent), 0o755); err != nil {
+// writeHook writes an executable hook script.
+func writeHook(hookPath, script string) error {
+	if err := os.WriteFile(hookPath, []byte(script), 0o755); err != nil {
 		return fmt.Errorf("failed to write hook: %w", err)
 	}
-
 	return nil
 }
 
+/**
+ * Uninstall removes the commit-gen prepare-commit-msg and commit-msg hooks
+ * and restores hooks preserved by --chain. A commit-msg hook commit-gen did
+ * not write is left alone.
+ *
+ * @returns An error if no commit-gen hook is installed
+ */
 func Uninstall() error {
-	root, err := git.GetRepositoryRoot()
+	hooksDir, err := getHooksDir()
+	if err != nil {
+		return err
+	}
+
+	removed, err := uninstall(hooksDir, generateHook, true)
 	if err != nil {
-		return fmt.Errorf("not in a git repository: %w", err)
+		return err
+	}
+	removedLint, err := uninstall(hooksDir, lintHook, false)
+	if err != nil {
+		return err
 	}
 
-	hookPath := filepath.Join(root, ".git", "hooks", hookName)
+	if !removed && !removedLint {
+		return fmt.Errorf("hook not found at %s", filepath.Join(hooksDir, hookName))
+	}
+	return nil
+}
 
+/**
+ * uninstall removes one commit-gen hook and restores its chained original.
+ *
+ * @param hooksDir - The hooks directory
+ * @param spec - The hook to remove
+ * @param strict - true to fail when the hook exists but was not written by commit-gen
+ * @returns Whether the hook was removed
+ */
+func uninstall(hooksDir string, spec hookSpec, strict bool) (bool, error) {
+	hookPath := filepath.Join(hooksDir, spec.name)
 	if _, err := os.Stat(hookPath); os.IsNotExist(err) {
-		return fmt.Errorf("hook not found at %s", hookPath)
+		return false, nil
 	}
 
 	content, err := os.ReadFile(hookPath)
 	if err != nil {
-		return fmt.Errorf("failed to read hook: %w", err)
+		return false, fmt.Errorf("failed to read hook: %w", err)
 	}
 
-	if !strings.Contains(string(content), "commit-gen") {
-		return fmt.Errorf("hook at %s is not a commit-gen hook", hookPath)
+	if !isManagedHook(content) {
+		if strict {
+			return false, fmt.Errorf("hook at %s is not a commit-gen hook", hookPath)
+		}
+		return false, nil
 	}
 
 	if err := os.Remove(hookPath); err != nil {
-		return fmt.Errorf("failed to remove hook: %w", err)
+		return false, fmt.Errorf("failed to remove hook: %w", err)
 	}
 
-	return nil
+	if strings.Contains(string(content), spec.localName()) {
+		localPath := filepath.Join(hooksDir, spec.localName())
+		if _, err := os.Stat(localPath); err == nil {
+			if err := os.Rename(localPath, hookPath); err != nil {
+				return true, fmt.Errorf("failed to restore original hook: %w", err)
+			}
+		}
+	}
+
+	return true, nil
 }
 
 func IsInstalled() (bool, error) {
-	root, err := git.GetRepositoryRoot()
+	hookPath, err := getHookPath()
 	if err != nil {
 		return false, err
 	}
 
-	hookPath := filepath.Join(root, ".git", "hooks", hookName)
-
 	content, err := os.ReadFile(hookPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -140,5 +511,57 @@ func IsInstalled() (bool, error) {
 		return false, err
 	}
 
-	return strings.Contains(string(content), "commit-gen"), nil
+	return isManagedHook(content), nil
+}
+
+/**
+ * HookStatus describes one of the hooks commit-gen can install.
+ */
+type HookStatus struct {
+	Name string
+	Path string
+	// Installed is true when a commit-gen hook is present.
+	Installed bool
+	// Foreign is true when a hook commit-gen did not write occupies Path.
+	Foreign bool
+	// Version is the installed script version; 0 for hooks from before versioning.
+	Version int
+	// UpToDate is true when the installed script is the current version.
+	UpToDate bool
+}
+
+/**
+ * Status reports the prepare-commit-msg and commit-msg hooks of the current
+ * repository: whether each is installed, its version, and whether install
+ * would upgrade it.
+ *
+ * @returns One status per hook, generation hook first
+ * @returns An error if not in a git repository or a hook cannot be read
+ */
+func Status() ([]HookStatus, error) {
+	hooksDir, err := getHooksDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []HookStatus
+	for _, spec := range []hookSpec{generateHook, lintHook} {
+		status := HookStatus{Name: spec.name, Path: filepath.Join(hooksDir, spec.name)}
+		content, err := os.ReadFile(status.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read hook: %w", err)
+		}
+		if err == nil {
+			status.Version, status.Installed = managedHookVersion(content)
+			status.Foreign = !status.Installed
+			status.UpToDate = status.Installed && status.Version >= hookVersion
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CurrentVersion returns the version of the hook scripts install writes.
+func CurrentVersion() int {
+	return hookVersion
 }
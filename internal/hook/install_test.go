@@ -2,9 +2,12 @@
 package hook
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -205,8 +208,11 @@ func TestUninstallWithoutInstall(t *testing.T) {
 func TestHookScriptContent(t *testing.T) {
 	hookScript := fmt.Sprintf(hookScriptFmt, "commit-gen")
 
+	if !strings.HasPrefix(hookScript, "#!/bin/sh\n") {
+		t.Errorf("Hook script should use a POSIX sh shebang, got %q", strings.SplitN(hookScript, "\n", 2)[0])
+	}
+
 	expectedKeywords := []string{
-		"bash",
 		"commit-gen",
 		"MESSAGE_FILE",
 		"exit 0",
@@ -221,6 +227,176 @@ func TestHookScriptContent(t *testing.T) {
 	}
 }
 
+func TestHookScriptPOSIXSyntax(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	for _, chain := range []bool{false, true} {
+		hookPath := filepath.Join(t.TempDir(), hookName)
+		if err := os.WriteFile(hookPath, []byte(generateHook.render("/usr/local/bin/commit-gen", chain)), 0o755); err != nil {
+			t.Fatalf("Failed to write hook: %v", err)
+		}
+		if out, err := exec.Command("sh", "-n", hookPath).CombinedOutput(); err != nil {
+			t.Errorf("Hook (chain=%v) is not valid sh: %v\n%s", chain, err, out)
+		}
+	}
+}
+
+func TestHookScriptPassesAmend(t *testing.T) {
+	hookScript := fmt.Sprintf(hookScriptFmt, "commit-gen")
+
+	if !strings.Contains(hookScript, "  commit)\n") || !strings.Contains(hookScript, `AMEND="--amend"`) {
+		t.Error("Hook script should detect amended commits")
+	}
+	if !strings.Contains(hookScript, "generate --hook $AMEND") {
+		t.Error("Hook script should pass --amend to generate")
+	}
+}
+
+func TestHookScriptCommitSources(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git and sh)")
+	}
+
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	template := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(template, []byte("Refs: #\n\n# Describe why\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	cmd = exec.Command("git", "config", "commit.template", template)
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git config failed: %v\n%s", err, out)
+	}
+
+	stub := filepath.Join(t.TempDir(), "commit-gen")
+	if err := os.WriteFile(stub, []byte("#!/bin/sh\necho 'feat: generated'\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+	hookPath := filepath.Join(t.TempDir(), hookName)
+	if err := os.WriteFile(hookPath, []byte(generateHook.render(stub, false)), 0o755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+
+	// git commit -v appends the staged diff below the scissors line
+	verboseTail := "# ------------------------ >8 ------------------------\n" +
+		"# Do not modify or remove the line above.\n" +
+		"diff --git a/main.go b/main.go\n+func main() {}\n"
+
+	tests := []struct {
+		name     string
+		source   string
+		sha      string
+		message  string
+		expected string
+	}{
+		{"plain commit", "", "", "# Please enter the commit message\n", "feat: generated\n\n# Please enter the commit message\n"},
+		{"empty -m", "message", "", "", "feat: generated\n"},
+		{"user -m", "message", "", "fix: typed by hand\n", "fix: typed by hand\n"},
+		{"user -m with quote", "message", "", "fix: don't crash\n", "fix: don't crash\n"},
+		{"template", "template", "", "Refs: \n# Describe why\n", "feat: generated\n\nRefs: \n# Describe why\n"},
+		{"template given with -F", "message", "", "Refs: #\n\n# Describe why\n", "feat: generated\n\nRefs: #\n\n# Describe why\n"},
+		{"-F with own text and template", "message", "", "fix: typed by hand\nRefs: #\n", "fix: typed by hand\nRefs: #\n"},
+		{"verbose commit", "", "", "\n# Please enter the commit message\n" + verboseTail, "feat: generated\n\n# Please enter the commit message\n" + verboseTail},
+		{"verbose commit with -m", "message", "", "fix: typed by hand\n" + verboseTail, "fix: typed by hand\n" + verboseTail},
+		{"merge", "merge", "", "Merge branch 'topic'\n", "Merge branch 'topic'\n"},
+		{"squash", "squash", "", "", ""},
+		{"reuse other commit", "commit", "0123456789abcdef", "chore: reused\n", "chore: reused\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageFile := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+			if err := os.WriteFile(messageFile, []byte(tt.message), 0o644); err != nil {
+				t.Fatalf("Failed to write message file: %v", err)
+			}
+
+			run := exec.Command("sh", hookPath, messageFile, tt.source, tt.sha)
+			run.Dir = repo
+			if out, err := run.CombinedOutput(); err != nil {
+				t.Fatalf("Hook failed: %v\n%s", err, out)
+			}
+
+			content, err := os.ReadFile(messageFile)
+			if err != nil {
+				t.Fatalf("Failed to read message file: %v", err)
+			}
+			if string(content) != tt.expected {
+				t.Errorf("Source %q: got %q, expected %q", tt.source, content, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHookScriptHonorsCommentChar(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git and sh)")
+	}
+
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "-q"}, {"config", "core.commentChar", ";"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	stub := filepath.Join(t.TempDir(), "commit-gen")
+	if err := os.WriteFile(stub, []byte("#!/bin/sh\necho 'feat: generated'\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+	hookPath := filepath.Join(t.TempDir(), hookName)
+	if err := os.WriteFile(hookPath, []byte(generateHook.render(stub, false)), 0o755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+
+	verboseTail := "; ------------------------ >8 ------------------------\n" +
+		"; Do not modify or remove the line above.\n" +
+		"diff --git a/main.go b/main.go\n+func main() {}\n"
+
+	tests := []struct {
+		name     string
+		source   string
+		message  string
+		expected string
+	}{
+		{"plain commit", "", "\n; Please enter the commit message\n", "feat: generated\n\n; Please enter the commit message\n"},
+		{"verbose commit", "", "\n; Please enter the commit message\n" + verboseTail, "feat: generated\n\n; Please enter the commit message\n" + verboseTail},
+		{"-m starting with #", "message", "#42 fix: typed by hand\n", "#42 fix: typed by hand\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageFile := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+			if err := os.WriteFile(messageFile, []byte(tt.message), 0o644); err != nil {
+				t.Fatalf("Failed to write message file: %v", err)
+			}
+
+			run := exec.Command("sh", hookPath, messageFile, tt.source)
+			run.Dir = repo
+			if out, err := run.CombinedOutput(); err != nil {
+				t.Fatalf("Hook failed: %v\n%s", err, out)
+			}
+
+			content, err := os.ReadFile(messageFile)
+			if err != nil {
+				t.Fatalf("Failed to read message file: %v", err)
+			}
+			if string(content) != tt.expected {
+				t.Errorf("got %q, expected %q", content, tt.expected)
+			}
+		})
+	}
+}
+
 func TestHookName(t *testing.T) {
 	if hookName != "prepare-commit-msg" {
 		t.Errorf("Hook name incorrect: got %q, expected %q", hookName, "prepare-commit-msg")
@@ -228,3 +404,555 @@ func TestHookName(t *testing.T) {
 		t.Logf("✓ Hook name correct: %s", hookName)
 	}
 }
+
+func TestInstallFromLinkedWorktree(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	tmpDir := t.TempDir()
+	mainRepo := filepath.Join(tmpDir, "main")
+	worktree := filepath.Join(tmpDir, "wt")
+
+	runGit := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	if err := os.MkdirAll(mainRepo, 0o755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	runGit(mainRepo, "init")
+	runGit(mainRepo, "-c", "user.email=test@example.com", "-c", "user.name=Test", "commit", "--allow-empty", "-m", "init")
+	runGit(mainRepo, "worktree", "add", worktree)
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(worktree); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	if err := Install(); err != nil {
+		t.Fatalf("Install from worktree failed: %v", err)
+	}
+
+	hookPath := filepath.Join(mainRepo, ".git", "hooks", hookName)
+	if _, err := os.Stat(hookPath); err != nil {
+		t.Fatalf("Hook not installed in common git dir: %v", err)
+	}
+
+	installed, err := IsInstalled()
+	if err != nil || !installed {
+		t.Errorf("IsInstalled from worktree: got %v, %v", installed, err)
+	}
+
+	if err := Uninstall(); err != nil {
+		t.Fatalf("Uninstall from worktree failed: %v", err)
+	}
+
+	if _, err := os.Stat(hookPath); !os.IsNotExist(err) {
+		t.Error("Hook still present after uninstall")
+	}
+
+	t.Log("✓ Hook installed into common git dir from linked worktree")
+}
+
+func TestInstallChainedPreservesExistingHook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	hooksDir := filepath.Join(repo, ".git", "hooks")
+	hookPath := filepath.Join(hooksDir, hookName)
+	localPath := filepath.Join(hooksDir, generateHook.localName())
+	original := "#!/bin/sh\necho original\n"
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		t.Fatalf("Failed to create hooks dir: %v", err)
+	}
+	if err := os.WriteFile(hookPath, []byte(original), 0o755); err != nil {
+		t.Fatalf("Failed to write existing hook: %v", err)
+	}
+
+	if err := Install(); err == nil || !strings.Contains(err.Error(), "--chain") {
+		t.Errorf("Install over a foreign hook should suggest --chain, got %v", err)
+	}
+
+	if err := InstallChained(); err != nil {
+		t.Fatalf("InstallChained failed: %v", err)
+	}
+
+	if content, err := os.ReadFile(localPath); err != nil || string(content) != original {
+		t.Errorf("Original hook not preserved at %s: %q, %v", localPath, content, err)
+	}
+	content, err := os.ReadFile(hookPath)
+	if err != nil {
+		t.Fatalf("Failed to read chained hook: %v", err)
+	}
+	script := string(content)
+	if !strings.Contains(script, generateHook.localName()) || !strings.Contains(script, "generate --hook") {
+		t.Errorf("Chained hook should run both the original hook and commit-gen:\n%s", script)
+	}
+	if strings.Index(script, `"$LOCAL_HOOK" "$@"`) > strings.Index(script, "generate --hook") {
+		t.Error("Chained hook should run the original hook first")
+	}
+
+	if err := Uninstall(); err != nil {
+		t.Fatalf("Uninstall failed: %v", err)
+	}
+	if content, err := os.ReadFile(hookPath); err != nil || string(content) != original {
+		t.Errorf("Uninstall should restore the original hook, got %q, %v", content, err)
+	}
+	if _, err := os.Stat(localPath); !os.IsNotExist(err) {
+		t.Error("Saved hook should be moved back on uninstall")
+	}
+
+	t.Log("✓ Existing hook chained and restored")
+}
+
+func TestInstallChainedRefusesWhenBothExist(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	hooksDir := filepath.Join(repo, ".git", "hooks")
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		t.Fatalf("Failed to create hooks dir: %v", err)
+	}
+	for _, name := range []string{hookName, generateHook.localName()} {
+		if err := os.WriteFile(filepath.Join(hooksDir, name), []byte("#!/bin/sh\necho "+name+"\n"), 0o755); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	if err := InstallChained(); err == nil || !strings.Contains(err.Error(), "both") {
+		t.Errorf("Expected an error when both hooks exist, got %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(hooksDir, hookName)); string(content) != "#!/bin/sh\necho "+hookName+"\n" {
+		t.Error("Existing hook should be left untouched")
+	}
+}
+
+func TestInstallHonorsCoreHooksPath(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	tests := []struct {
+		name      string
+		hooksPath string
+		expected  string
+	}{
+		{"custom", "githooks", "githooks"},
+		{"husky", ".husky/_", ".husky"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := filepath.EvalSymlinks(t.TempDir())
+			if err != nil {
+				t.Fatalf("Failed to resolve temp dir: %v", err)
+			}
+			for _, args := range [][]string{{"init"}, {"config", "core.hooksPath", tt.hooksPath}} {
+				cmd := exec.Command("git", args...)
+				cmd.Dir = repo
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("git %v failed: %v\n%s", args, err, out)
+				}
+			}
+			if err := os.MkdirAll(filepath.Join(repo, "src"), 0o755); err != nil {
+				t.Fatalf("Failed to create subdirectory: %v", err)
+			}
+
+			oldCwd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("Failed to get current directory: %v", err)
+			}
+			defer func() { _ = os.Chdir(oldCwd) }()
+
+			// Run from a subdirectory: a relative core.hooksPath is relative to the root.
+			if err := os.Chdir(filepath.Join(repo, "src")); err != nil {
+				t.Fatalf("Failed to change directory: %v", err)
+			}
+
+			if err := Install(); err != nil {
+				t.Fatalf("Install failed: %v", err)
+			}
+
+			hookPath := filepath.Join(repo, tt.expected, hookName)
+			if _, err := os.Stat(hookPath); err != nil {
+				t.Fatalf("Hook not installed at %s: %v", hookPath, err)
+			}
+			if _, err := os.Stat(filepath.Join(repo, ".git", "hooks", hookName)); !os.IsNotExist(err) {
+				t.Error("Hook should not be written to .git/hooks")
+			}
+
+			installed, err := IsInstalled()
+			if err != nil || !installed {
+				t.Errorf("IsInstalled: got %v, %v", installed, err)
+			}
+
+			if err := Uninstall(); err != nil {
+				t.Fatalf("Uninstall failed: %v", err)
+			}
+			if _, err := os.Stat(hookPath); !os.IsNotExist(err) {
+				t.Error("Hook still present after uninstall")
+			}
+		})
+	}
+}
+
+func TestTemplateDirFor(t *testing.T) {
+	if got := templateDirFor("/home/dev", ""); got != filepath.Join("/home/dev", ".config", "git", "template") {
+		t.Errorf("Default template dir: got %q", got)
+	}
+	if got := templateDirFor("/home/dev", "/xdg"); got != filepath.Join("/xdg", "git", "template") {
+		t.Errorf("XDG template dir: got %q", got)
+	}
+}
+
+func TestInstallGlobal(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	templateDir := filepath.Join(home, ".config", "git", "template")
+	readTemplateDir := func() string {
+		out, _ := exec.Command("git", "config", "--global", "--get", "init.templateDir").Output()
+		return strings.TrimSpace(string(out))
+	}
+
+	if err := InstallGlobal(); err != nil {
+		t.Fatalf("InstallGlobal failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(templateDir, "hooks", hookName)); err != nil {
+		t.Errorf("Hook not written to template: %v", err)
+	}
+	if got := readTemplateDir(); got != templateDir {
+		t.Errorf("init.templateDir: got %q, expected %q", got, templateDir)
+	}
+
+	if err := UninstallGlobal(); err != nil {
+		t.Fatalf("UninstallGlobal failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(templateDir, "hooks", hookName)); !os.IsNotExist(err) {
+		t.Error("Hook still present in template after uninstall")
+	}
+	if got := readTemplateDir(); got != "" {
+		t.Errorf("init.templateDir should be unset, got %q", got)
+	}
+
+	custom := filepath.Join(home, "my-template")
+	if err := exec.Command("git", "config", "--global", "init.templateDir", custom).Run(); err != nil {
+		t.Fatalf("Failed to set init.templateDir: %v", err)
+	}
+	if err := InstallGlobal(); err == nil || !strings.Contains(err.Error(), custom) {
+		t.Errorf("Expected refusal to replace an existing templateDir, got %v", err)
+	}
+	if got := readTemplateDir(); got != custom {
+		t.Errorf("Existing init.templateDir was changed to %q", got)
+	}
+
+	t.Log("✓ Global template hook installed and removed")
+}
+
+func TestInstallLintAlongsideGenerationHook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	if err := Install(); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if err := InstallLint(false); err != nil {
+		t.Fatalf("InstallLint failed: %v", err)
+	}
+	if err := InstallLint(false); !errors.Is(err, ErrAlreadyInstalled) {
+		t.Errorf("Expected ErrAlreadyInstalled on a second InstallLint, got %v", err)
+	}
+
+	hooksDir := filepath.Join(repo, ".git", "hooks")
+	generate, err := os.ReadFile(filepath.Join(hooksDir, hookName))
+	if err != nil || !strings.Contains(string(generate), "generate --hook") {
+		t.Errorf("Expected the generation hook to be kept, got %q, %v", generate, err)
+	}
+	lint, err := os.ReadFile(filepath.Join(hooksDir, lintHookName))
+	if err != nil || !strings.Contains(string(lint), `lint "$MESSAGE_FILE"`) {
+		t.Errorf("Expected a commit-msg hook running commit-gen lint, got %q, %v", lint, err)
+	}
+
+	if err := Uninstall(); err != nil {
+		t.Fatalf("Uninstall failed: %v", err)
+	}
+	for _, name := range []string{hookName, lintHookName} {
+		if _, err := os.Stat(filepath.Join(hooksDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed by Uninstall", name)
+		}
+	}
+
+	t.Log("✓ Generation and lint hooks installed together and removed together")
+}
+
+func TestUninstallKeepsForeignCommitMsgHook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	hooksDir := filepath.Join(repo, ".git", "hooks")
+	foreign := "#!/bin/sh\nnpx commitlint --edit \"$1\"\n"
+	if err := os.WriteFile(filepath.Join(hooksDir, lintHookName), []byte(foreign), 0o755); err != nil {
+		t.Fatalf("Failed to write existing hook: %v", err)
+	}
+
+	if err := Install(); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if err := Uninstall(); err != nil {
+		t.Fatalf("Uninstall failed: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(hooksDir, lintHookName)); err != nil || string(content) != foreign {
+		t.Errorf("Uninstall should leave a commit-msg hook commit-gen did not write, got %q, %v", content, err)
+	}
+}
+
+func TestManagedHookVersion(t *testing.T) {
+	if hookMarker != hookMarkerPrefix+strconv.Itoa(hookVersion) {
+		t.Fatalf("hookMarker %q does not match hookVersion %d", hookMarker, hookVersion)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		version int
+		managed bool
+	}{
+		{"generation hook", generateHook.render("/opt/tools/cg", false), hookVersion, true},
+		{"lint hook", lintHook.render("/opt/tools/cg", true), hookVersion, true},
+		{"future version", "#!/bin/sh\n# commit-gen-managed-hook v7\n", 7, true},
+		{"legacy generation hook", "#!/bin/sh\n# commit-gen git hook\nexit 0\n", 0, true},
+		{"mentions commit-gen", "#!/bin/sh\n# TODO: switch to commit-gen\nnpx commitlint --edit \"$1\"\n", 0, false},
+		{"marker inside a line", "#!/bin/sh\necho '# commit-gen-managed-hook v1'\n", 0, false},
+	}
+
+	for _, tt := range tests {
+		version, managed := managedHookVersion([]byte(tt.content))
+		if managed != tt.managed || version != tt.version {
+			t.Errorf("%s: got version %d, managed %v; expected %d, %v", tt.name, version, managed, tt.version, tt.managed)
+		}
+	}
+}
+
+func TestInstallRejectsUnmanagedHookMentioningCommitGen(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	hookPath := filepath.Join(repo, ".git", "hooks", hookName)
+	own := "#!/bin/sh\n# Runs before commit-gen was adopted\necho custom\n"
+	if err := os.WriteFile(hookPath, []byte(own), 0o755); err != nil {
+		t.Fatalf("Failed to write existing hook: %v", err)
+	}
+
+	if installed, _ := IsInstalled(); installed {
+		t.Error("A hook that only mentions commit-gen should not count as installed")
+	}
+	if err := Install(); err == nil || errors.Is(err, ErrAlreadyInstalled) {
+		t.Errorf("Install should refuse to overwrite an unmanaged hook, got %v", err)
+	}
+	if err := Uninstall(); err == nil || !strings.Contains(err.Error(), "not a commit-gen hook") {
+		t.Errorf("Uninstall should refuse to remove an unmanaged hook, got %v", err)
+	}
+	if content, _ := os.ReadFile(hookPath); string(content) != own {
+		t.Errorf("Unmanaged hook was modified: %q", content)
+	}
+}
+
+func TestInstallRewritesOlderHook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	hookPath := filepath.Join(repo, ".git", "hooks", hookName)
+	legacy := "#!/bin/sh\n# commit-gen git hook\nexit 0\n"
+	if err := os.WriteFile(hookPath, []byte(legacy), 0o755); err != nil {
+		t.Fatalf("Failed to write legacy hook: %v", err)
+	}
+
+	if err := Install(); err != nil {
+		t.Fatalf("Install should rewrite a hook from an older release, got %v", err)
+	}
+	content, _ := os.ReadFile(hookPath)
+	if version, managed := managedHookVersion(content); !managed || version != hookVersion {
+		t.Errorf("Expected the hook rewritten at version %d, got %q", hookVersion, content)
+	}
+	if err := Install(); !errors.Is(err, ErrAlreadyInstalled) {
+		t.Errorf("Expected ErrAlreadyInstalled for a current hook, got %v", err)
+	}
+}
+
+func TestStatusReportsOutdatedHook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping hook test in short mode (requires git)")
+	}
+
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	oldCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	if err := os.Chdir(repo); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	hooksDir := filepath.Join(repo, ".git", "hooks")
+	old := "#!/bin/sh\n" + hookMarkerPrefix + strconv.Itoa(hookVersion-1) + "\nexit 0\n"
+	if err := os.WriteFile(filepath.Join(hooksDir, hookName), []byte(old), 0o755); err != nil {
+		t.Fatalf("Failed to write old hook: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(hooksDir, lintHookName), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write foreign hook: %v", err)
+	}
+
+	statuses, err := Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected two hook statuses, got %+v", statuses)
+	}
+	generation, lint := statuses[0], statuses[1]
+	if !generation.Installed || generation.UpToDate || generation.Version != hookVersion-1 {
+		t.Errorf("Expected an outdated generation hook, got %+v", generation)
+	}
+	if lint.Installed || !lint.Foreign {
+		t.Errorf("Expected a foreign commit-msg hook, got %+v", lint)
+	}
+
+	if err := Install(); err != nil {
+		t.Fatalf("Install should upgrade the old hook, got %v", err)
+	}
+	statuses, _ = Status()
+	if generation := statuses[0]; !generation.UpToDate || generation.Version != hookVersion {
+		t.Errorf("Expected the generation hook upgraded to v%d, got %+v", hookVersion, generation)
+	}
+}
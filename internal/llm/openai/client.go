@@ -0,0 +1,212 @@
+// Package openai provides an HTTP client for OpenAI-compatible chat completion APIs.
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the API used when openai.base_url is empty.
+const DefaultBaseURL = "https://api.openai.com"
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	usage      *Usage
+}
+
+/**
+ * Usage is the token count reported for one completion.
+ */
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+/**
+ * StatusError reports a response with an unexpected HTTP status. Body holds
+ * the API's error message when the response carries one, otherwise the raw body.
+ */
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s (status %d)", e.Op, e.Body, e.StatusCode)
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+/**
+ * NewClient creates a client for an OpenAI-compatible API such as OpenAI
+ * itself, a local Ollama or LM Studio server, or a hosted gateway.
+ *
+ * @param baseURL - The API root, with or without a trailing /v1; empty means DefaultBaseURL
+ * @param apiKey - Sent as a bearer token; empty sends no Authorization header
+ * @param timeout - The request timeout in seconds
+ * @returns A new Client
+ */
+func NewClient(baseURL, apiKey string, timeout int) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout: time.Duration(timeout) * time.Second,
+		},
+	}
+}
+
+/**
+ * endpoint returns the URL of an API path such as "/chat/completions". Base
+ * URLs that already end in /v1, as most local servers document theirs, are
+ * not given a second one.
+ */
+func (c *Client) endpoint(path string) string {
+	if strings.HasSuffix(c.baseURL, "/v1") {
+		return c.baseURL + path
+	}
+	return c.baseURL + "/v1" + path
+}
+
+// authorize adds the bearer token, if any, to req.
+func (c *Client) authorize(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+/**
+ * LastUsage returns the token usage reported with the last completion.
+ *
+ * @returns The usage, or nil if the server omitted it or no completion was received
+ */
+func (c *Client) LastUsage() *Usage {
+	return c.usage
+}
+
+/**
+ * Complete sends prompt as a single user message and returns the reply.
+ *
+ * @param ctx - The context for cancellation
+ * @param model - The model name, e.g. "gpt-4o-mini"
+ * @param prompt - The prompt text
+ * @returns The content of the first choice's message
+ */
+func (c *Client) Complete(ctx context.Context, model, prompt string) (string, error) {
+	c.usage = nil
+	bodyBytes, err := json.Marshal(chatRequest{
+		Model:    model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint("/chat/completions"), bytes.NewReader(bodyBytes))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() == nil && strings.Contains(err.Error(), "Client.Timeout exceeded") {
+			return "", fmt.Errorf("chat completion timed out: %w. Try increasing opencode.timeout in your config", err)
+		}
+		return "", fmt.Errorf("failed to send chat completion: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read chat completion: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newStatusError("chat completion failed", resp.StatusCode, data)
+	}
+
+	var completion chatResponse
+	if err := json.Unmarshal(data, &completion); err != nil {
+		return "", fmt.Errorf("failed to parse chat completion: %w", err)
+	}
+	if len(completion.Choices) == 0 || completion.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("no text response received")
+	}
+	c.usage = completion.Usage
+	return completion.Choices[0].Message.Content, nil
+}
+
+/**
+ * Ping checks that the API is reachable and accepts the API key by listing
+ * its models (GET /v1/models), without spending tokens on a completion.
+ *
+ * @param ctx - The context for cancellation
+ * @returns An error if the request fails or the API answers with a non-200
+ * status, such as 401 for a rejected key or 404 for a wrong base URL
+ */
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/models"), nil)
+	if err != nil {
+		return err
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", c.baseURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return newStatusError("failed to list models", resp.StatusCode, data)
+	}
+	return nil
+}
+
+/**
+ * newStatusError builds a *StatusError for op, preferring the message from an
+ * {"error": {"message": ...}} body.
+ */
+func newStatusError(op string, status int, body []byte) *StatusError {
+	var apiErr errorResponse
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+		return &StatusError{Op: op, StatusCode: status, Body: apiErr.Error.Message}
+	}
+	return &StatusError{Op: op, StatusCode: status, Body: strings.TrimSpace(string(body))}
+}
@@ -0,0 +1,147 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer sk-test" {
+			t.Errorf("Authorization header mismatch: %q", auth)
+		}
+
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Model != "gpt-4o-mini" {
+			t.Errorf("Model mismatch: %q", req.Model)
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Role != "user" || req.Messages[0].Content != "Describe this diff" {
+			t.Errorf("Unexpected messages: %+v", req.Messages)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "chatcmpl-123",
+			"object": "chat.completion",
+			"choices": [
+				{"index": 0, "message": {"role": "assistant", "content": "feat: add openai backend"}, "finish_reason": "stop"}
+			],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 6, "total_tokens": 18}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "sk-test", 5)
+	response, err := client.Complete(context.Background(), "gpt-4o-mini", "Describe this diff")
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if response != "feat: add openai backend" {
+		t.Errorf("Response mismatch: got %q", response)
+	}
+	if usage := client.LastUsage(); usage == nil || usage.PromptTokens != 12 || usage.CompletionTokens != 6 {
+		t.Errorf("Usage mismatch: %+v", usage)
+	}
+}
+
+func TestCompleteBaseURLWithVersion(t *testing.T) {
+	var path string
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"fix: local model"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/v1/", "", 5)
+	if _, err := client.Complete(context.Background(), "llama3", "prompt"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if path != "/v1/chat/completions" {
+		t.Errorf("Expected /v1/chat/completions, got %q", path)
+	}
+	if auth != "" {
+		t.Errorf("Expected no Authorization header without a key, got %q", auth)
+	}
+	if usage := client.LastUsage(); usage != nil {
+		t.Errorf("Expected no usage when the server omits it, got %+v", usage)
+	}
+}
+
+func TestCompleteErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{"api error", http.StatusBadRequest, `{"error":{"message":"This model's maximum context length is 8192 tokens","code":"context_length_exceeded"}}`, "maximum context length"},
+		{"plain error", http.StatusBadGateway, "upstream down", "upstream down"},
+		{"no choices", http.StatusOK, `{"choices":[]}`, "no text response"},
+		{"invalid json", http.StatusOK, `not json`, "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewClient(server.URL, "", 5).Complete(context.Background(), "m", "p")
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("Expected error containing %q, got %v", tt.expected, err)
+			}
+
+			var statusErr *StatusError
+			if isStatus := errors.As(err, &statusErr); isStatus != (tt.status != http.StatusOK) {
+				t.Errorf("StatusError mismatch: %v", err)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	var path, auth string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		w.WriteHeader(status)
+		if status == http.StatusUnauthorized {
+			_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided"}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/v1", "sk-test", 5)
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if path != "/v1/models" || auth != "Bearer sk-test" {
+		t.Errorf("Unexpected request: path %q, Authorization %q", path, auth)
+	}
+
+	status = http.StatusUnauthorized
+	var statusErr *StatusError
+	if err := client.Ping(context.Background()); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized || !strings.Contains(err.Error(), "Incorrect API key") {
+		t.Errorf("Expected a 401 StatusError, got %v", err)
+	}
+
+	server.Close()
+	if err := client.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to reach") {
+		t.Errorf("Expected a network error, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Package openaitest provides a stub OpenAI-compatible chat completions server for tests.
+package openaitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/avgt93/commit-gen/internal/config"
+)
+
+/**
+ * Request is the part of a chat completion request handlers look at.
+ */
+type Request struct {
+	Path   string
+	Model  string
+	Prompt string
+}
+
+/**
+ * Response is what the stub server answers. A zero Status answers 200 with
+ * Content as the assistant message; any other status sends Body as is.
+ */
+type Response struct {
+	Content string
+	Status  int
+	Body    string
+}
+
+// Handler decides the response to one chat completion request.
+type Handler func(req Request) Response
+
+// Reply returns a handler answering every request with content.
+func Reply(content string) Handler {
+	return func(Request) Response { return Response{Content: content} }
+}
+
+/**
+ * NewServer starts a chat completions server answering with handler. It is
+ * closed when the test ends.
+ *
+ * @param t - The test owning the server
+ * @param handler - Decides each response
+ * @returns The running server
+ */
+func NewServer(t testing.TB, handler Handler) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		req := Request{Path: r.URL.Path, Model: body.Model}
+		if len(body.Messages) > 0 {
+			req.Prompt = body.Messages[0].Content
+		}
+
+		resp := handler(req)
+		if resp.Status != 0 && resp.Status != http.StatusOK {
+			w.WriteHeader(resp.Status)
+			_, _ = w.Write([]byte(resp.Body))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": resp.Content}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+/**
+ * Config returns a copy of the default configuration in openai mode,
+ * pointed at a stub server answering with handler.
+ *
+ * @param t - The test owning the server
+ * @param handler - Decides each response
+ * @returns The configuration
+ */
+func Config(t testing.TB, handler Handler) config.Config {
+	t.Helper()
+	server := NewServer(t, handler)
+	if err := config.Initialize(""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	cfg := *config.Get()
+	cfg.OpenCode.Mode = "openai"
+	cfg.OpenAI.BaseURL = server.URL
+	return cfg
+}
@@ -0,0 +1,119 @@
+package opencode
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AvailabilityTTL is how long a successful on-disk availability check is trusted.
+const AvailabilityTTL = 10 * time.Minute
+
+const availabilityFile = "availability.json"
+
+// lookPath resolves an executable in PATH; replaced in tests to count lookups.
+var lookPath = exec.LookPath
+
+/**
+ * availabilityCache remembers successful binary lookups, keyed by binary and
+ * PATH so that a PATH change invalidates the entry. Entries live in-process
+ * and, when a directory is set, on disk for AvailabilityTTL.
+ */
+var availabilityCache = struct {
+	mu      sync.Mutex
+	dir     string
+	entries map[string]bool
+}{entries: map[string]bool{}}
+
+/**
+ * SetAvailabilityCacheDir persists successful availability checks under dir
+ * so later invocations can skip the PATH lookup. An empty dir disables the
+ * on-disk cache.
+ *
+ * @param dir - The cache directory
+ */
+func SetAvailabilityCacheDir(dir string) {
+	availabilityCache.mu.Lock()
+	defer availabilityCache.mu.Unlock()
+	availabilityCache.dir = dir
+}
+
+// availabilityKey identifies a lookup of binary under the current PATH.
+func availabilityKey(binary string) string {
+	return binary + "\x00" + os.Getenv("PATH")
+}
+
+/**
+ * cachedLookPath looks up binary, returning early if a previous lookup under
+ * the same PATH succeeded.
+ */
+func cachedLookPath(binary string) error {
+	key := availabilityKey(binary)
+
+	availabilityCache.mu.Lock()
+	defer availabilityCache.mu.Unlock()
+
+	if availabilityCache.entries[key] || loadAvailability(availabilityCache.dir, key) {
+		availabilityCache.entries[key] = true
+		return nil
+	}
+
+	if _, err := lookPath(binary); err != nil {
+		return err
+	}
+
+	availabilityCache.entries[key] = true
+	saveAvailability(availabilityCache.dir, key)
+	return nil
+}
+
+func readAvailability(dir string) map[string]time.Time {
+	entries := map[string]time.Time{}
+	data, err := os.ReadFile(filepath.Join(dir, availabilityFile))
+	if err != nil {
+		return entries
+	}
+	_ = json.Unmarshal(data, &entries)
+	return entries
+}
+
+func loadAvailability(dir, key string) bool {
+	if dir == "" {
+		return false
+	}
+	checked, ok := readAvailability(dir)[key]
+	return ok && time.Since(checked) < AvailabilityTTL
+}
+
+func saveAvailability(dir, key string) {
+	if dir == "" {
+		return
+	}
+
+	entries := readAvailability(dir)
+	for k, checked := range entries {
+		if time.Since(checked) >= AvailabilityTTL {
+			delete(entries, k)
+		}
+	}
+	entries[key] = time.Now()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(dir, availabilityFile), data, 0o644)
+}
+
+// resetAvailabilityCache clears the in-process cache; used by tests.
+func resetAvailabilityCache() {
+	availabilityCache.mu.Lock()
+	defer availabilityCache.mu.Unlock()
+	availabilityCache.entries = map[string]bool{}
+}
@@ -3,18 +3,112 @@ package opencode
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// DefaultRetries is how many times a transient failure (a truncated response,
+// a 429 or 5xx status, or a network error) is retried before giving up.
+const DefaultRetries = 3
+
+// DefaultBackoff is the delay before the first retry; it doubles on each further retry.
+const DefaultBackoff = 500 * time.Millisecond
+
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	timeout    time.Duration
+
+	retries  int
+	backoff  time.Duration
+	partials []string
+
+	apiKey string
+	usage  *Usage
+}
+
+/**
+ * TruncatedResponseError reports a response body that ended before a complete
+ * JSON document was read, e.g. because the connection dropped mid-stream.
+ * It is treated as transient and retried.
+ */
+type TruncatedResponseError struct {
+	Body string
+	Err  error
+}
+
+func (e *TruncatedResponseError) Error() string {
+	return fmt.Sprintf("truncated response (%d bytes received): %v", len(e.Body), e.Err)
+}
+
+func (e *TruncatedResponseError) Unwrap() error {
+	return e.Err
+}
+
+/**
+ * StatusError reports a response with an unexpected HTTP status. 429 and 5xx
+ * statuses are transient and retried; RetryAfter holds the delay requested by
+ * a 429 response's Retry-After header, if any.
+ */
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s (status %d)", e.Op, e.Body, e.StatusCode)
+}
+
+// transient reports whether the status is worth retrying.
+func (e *StatusError) transient() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+/**
+ * newStatusError reads the body of an unexpected response into a *StatusError.
+ */
+func newStatusError(op string, resp *http.Response) *StatusError {
+	body, _ := io.ReadAll(resp.Body)
+	statusErr := &StatusError{Op: op, StatusCode: resp.StatusCode, Body: string(body)}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		statusErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return statusErr
+}
+
+/**
+ * parseRetryAfter parses a Retry-After header given either in seconds or as
+ * an HTTP date.
+ *
+ * @param value - The header value
+ * @param now - The current time, for HTTP dates
+ * @returns The requested delay, or zero if the header is missing or invalid
+ */
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
 }
 
 type Session struct {
@@ -43,26 +137,259 @@ type PromptRequest struct {
 	NoReply bool          `json:"noReply,omitempty"`
 }
 
+/**
+ * Usage is the token count reported for one model reply.
+ */
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+/**
+ * MessageInfo is a reply's metadata. OpenCode reports token counts under "tokens".
+ */
+type MessageInfo struct {
+	ID     string `json:"id"`
+	Tokens *struct {
+		Input  int `json:"input"`
+		Output int `json:"output"`
+	} `json:"tokens,omitempty"`
+}
+
 type Message struct {
-	Info struct {
-		ID string `json:"id"`
-	} `json:"info"`
+	Info  MessageInfo   `json:"info"`
 	Parts []MessagePart `json:"parts"`
+	Usage *Usage        `json:"usage,omitempty"`
+}
+
+/**
+ * TokenUsage returns the reply's token usage, taken from a top-level
+ * "usage" object or OpenCode's "info.tokens".
+ *
+ * @returns The usage, or nil if the server did not report it
+ */
+func (m *Message) TokenUsage() *Usage {
+	if m.Usage != nil {
+		return m.Usage
+	}
+	if m.Info.Tokens != nil {
+		return &Usage{PromptTokens: m.Info.Tokens.Input, CompletionTokens: m.Info.Tokens.Output}
+	}
+	return nil
 }
 
 func NewClient(host string, port int, timeout int) *Client {
-	baseURL := fmt.Sprintf("http://%s:%d", host, port)
+	return NewClientWithBaseURL(BaseURL("http", host, port, ""), timeout)
+}
+
+/**
+ * NewClientWithBaseURL creates a client for a server at baseURL, such as
+ * one behind TLS or a reverse-proxy subpath.
+ *
+ * @param baseURL - The server URL that endpoint paths are joined to
+ * @param timeout - The request timeout in seconds
+ * @returns A new Client
+ */
+func NewClientWithBaseURL(baseURL string, timeout int) *Client {
 	return &Client{
-		baseURL: baseURL,
+		baseURL: strings.TrimRight(baseURL, "/"),
 		httpClient: &http.Client{
 			Timeout: time.Duration(timeout) * time.Second,
 		},
 		timeout: time.Duration(timeout) * time.Second,
+		retries: DefaultRetries,
+		backoff: DefaultBackoff,
+	}
+}
+
+/**
+ * BaseURL builds a server URL from its parts. The base path may be given
+ * with or without slashes; an empty scheme means http.
+ *
+ * @param scheme - "http" or "https"
+ * @param host - The server host
+ * @param port - The server port
+ * @param basePath - A path prefix such as "/opencode", or empty
+ * @returns The URL without a trailing slash
+ */
+func BaseURL(scheme, host string, port int, basePath string) string {
+	if scheme == "" {
+		scheme = "http"
+	}
+	baseURL := fmt.Sprintf("%s://%s:%d", scheme, host, port)
+	if basePath = strings.Trim(basePath, "/"); basePath != "" {
+		baseURL += "/" + basePath
+	}
+	return baseURL
+}
+
+/**
+ * endpoint joins path to the base URL with exactly one slash between them.
+ */
+func (c *Client) endpoint(path string) string {
+	return c.baseURL + "/" + strings.TrimLeft(path, "/")
+}
+
+/**
+ * SetRetries sets how many times a transient failure is retried.
+ *
+ * @param retries - The number of retries; zero disables retrying
+ */
+func (c *Client) SetRetries(retries int) {
+	if retries < 0 {
+		retries = 0
+	}
+	c.retries = retries
+}
+
+/**
+ * SetAPIKey sends key as a bearer token on every request, for OpenCode
+ * servers behind authentication.
+ *
+ * @param key - The API key; empty sends no Authorization header
+ */
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+}
+
+/**
+ * LastUsage returns the token usage reported with the last message reply.
+ *
+ * @returns The usage, or nil if the server omitted it or no reply was received
+ */
+func (c *Client) LastUsage() *Usage {
+	return c.usage
+}
+
+/**
+ * PartialResponses returns the raw bodies of truncated responses that were
+ * retried, for diagnostics.
+ *
+ * @returns The partial bodies in the order they were received
+ */
+func (c *Client) PartialResponses() []string {
+	return c.partials
+}
+
+/**
+ * decodeResponse reads the whole body and decodes it as JSON into v. A body
+ * cut short by the connection or an incomplete JSON document yields a
+ * *TruncatedResponseError.
+ */
+func decodeResponse(body io.Reader, v any) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return &TruncatedResponseError{Body: string(data), Err: err}
+		}
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(data)) {
+			return &TruncatedResponseError{Body: string(data), Err: err}
+		}
+		return err
 	}
+	return nil
+}
+
+/**
+ * withRetry runs attempt until it succeeds or fails with a non-transient
+ * error, retrying up to c.retries times. Retries wait with exponential
+ * backoff starting at c.backoff, or for the delay a 429 response asked for.
+ * Cancelling ctx stops retrying.
+ */
+func (c *Client) withRetry(ctx context.Context, attempt func() error) error {
+	var err error
+	for i := 0; i <= c.retries; i++ {
+		err = attempt()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		delay := c.backoff << i
+		var truncated *TruncatedResponseError
+		var statusErr *StatusError
+		var urlErr *url.Error
+		switch {
+		case errors.As(err, &truncated):
+			c.partials = append(c.partials, truncated.Body)
+		case errors.As(err, &statusErr) && statusErr.transient():
+			if statusErr.RetryAfter > 0 {
+				delay = statusErr.RetryAfter
+			}
+		case errors.As(err, &urlErr) && !urlErr.Timeout():
+		default:
+			return err
+		}
+
+		if i < c.retries {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+	return err
+}
+
+/**
+ * do sends req, adding the Authorization header when an API key is set.
+ */
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return c.httpClient.Do(req)
+}
+
+/**
+ * post sends a JSON body to path under the base URL.
+ */
+func (c *Client) post(ctx context.Context, path string, body []byte, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return c.do(req)
+}
+
+/**
+ * requestError wraps a failed request, pointing at opencode.timeout when the
+ * client timeout was hit rather than the context being cancelled.
+ */
+func requestError(ctx context.Context, op string, err error) error {
+	if ctx.Err() == nil && (strings.Contains(err.Error(), "Client.Timeout exceeded") || strings.Contains(err.Error(), "context deadline exceeded")) {
+		return fmt.Errorf("%s timed out: %w. Try increasing opencode.timeout in your config", op, err)
+	}
+	return fmt.Errorf("failed to %s: %w", op, err)
 }
 
 func (c *Client) CheckHealth() (bool, error) {
-	resp, err := c.httpClient.Get(fmt.Sprintf("%s/global/health", c.baseURL))
+	return c.CheckHealthCtx(context.Background())
+}
+
+/**
+ * CheckHealthCtx is CheckHealth with a context for cancellation.
+ */
+func (c *Client) CheckHealthCtx(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/global/health"), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.do(req)
 	if err != nil {
 		return false, err
 	}
@@ -81,39 +408,52 @@ func (c *Client) CheckHealth() (bool, error) {
 }
 
 func (c *Client) CreateSession(title string) (*Session, error) {
+	return c.CreateSessionCtx(context.Background(), title)
+}
+
+/**
+ * CreateSessionCtx is CreateSession with a context for cancellation.
+ */
+func (c *Client) CreateSessionCtx(ctx context.Context, title string) (*Session, error) {
 	reqBody := map[string]string{"title": title}
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.httpClient.Post(
-		fmt.Sprintf("%s/session", c.baseURL),
-		"application/json",
-		bytes.NewReader(bodyBytes),
-	)
-	if err != nil {
-		if strings.Contains(err.Error(), "Client.Timeout exceeded") || strings.Contains(err.Error(), "context deadline exceeded") {
-			return nil, fmt.Errorf("create session timed out: %w. Try increasing opencode.timeout in your config", err)
+	var session Session
+	err = c.withRetry(ctx, func() error {
+		resp, err := c.post(ctx, "/session", bodyBytes, "")
+		if err != nil {
+			return requestError(ctx, "create session", err)
 		}
-		return nil, fmt.Errorf("failed to create session: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
+		defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to create session: %s (status %d)", string(body), resp.StatusCode)
-	}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+			return newStatusError("failed to create session", resp)
+		}
 
-	var session Session
-	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
-		return nil, fmt.Errorf("failed to parse session response: %w", err)
+		if err := decodeResponse(resp.Body, &session); err != nil {
+			return fmt.Errorf("failed to parse session response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &session, nil
 }
 
 func (c *Client) SendMessage(sessionID string, message string, model *Model) (string, error) {
+	return c.SendMessageCtx(context.Background(), sessionID, message, model)
+}
+
+/**
+ * SendMessageCtx is SendMessage with a context; cancelling it aborts the
+ * request and any pending retries.
+ */
+func (c *Client) SendMessageCtx(ctx context.Context, sessionID string, message string, model *Model) (string, error) {
 	req := PromptRequest{
 		Model: model,
 		Parts: []MessagePart{
@@ -129,29 +469,36 @@ func (c *Client) SendMessage(sessionID string, message string, model *Model) (st
 		return "", err
 	}
 
-	resp, err := c.httpClient.Post(
-		fmt.Sprintf("%s/session/%s/message", c.baseURL, sessionID),
-		"application/json",
-		bytes.NewReader(bodyBytes),
-	)
-	if err != nil {
-		if strings.Contains(err.Error(), "Client.Timeout exceeded") || strings.Contains(err.Error(), "context deadline exceeded") {
-			return "", fmt.Errorf("send message timed out: %w. Try increasing opencode.timeout in your config", err)
+	c.usage = nil
+	var msg Message
+	err = c.withRetry(ctx, func() error {
+		resp, err := c.post(ctx, fmt.Sprintf("/session/%s/message", sessionID), bodyBytes, "")
+		if err != nil {
+			return requestError(ctx, "send message", err)
 		}
-		return "", fmt.Errorf("failed to send message: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
+		defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to send message: %s (status %d)", string(body), resp.StatusCode)
-	}
+		if resp.StatusCode != http.StatusOK {
+			return newStatusError("failed to send message", resp)
+		}
 
-	var msg Message
-	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
-		return "", fmt.Errorf("failed to parse message response: %w", err)
+		if err := decodeResponse(resp.Body, &msg); err != nil {
+			return fmt.Errorf("failed to parse message response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
+	c.usage = msg.TokenUsage()
+	return messageText(&msg)
+}
+
+/**
+ * messageText returns the text of the first text part of msg.
+ */
+func messageText(msg *Message) (string, error) {
 	for _, part := range msg.Parts {
 		if part.Type == "text" {
 			return part.Text, nil
@@ -161,8 +508,44 @@ func (c *Client) SendMessage(sessionID string, message string, model *Model) (st
 	return "", fmt.Errorf("no text response received")
 }
 
+/**
+ * DeleteSession deletes a session on the server. A session the server no
+ * longer knows is not an error.
+ *
+ * @param sessionID - The session to delete
+ * @returns An error if the request fails or the server rejects it
+ */
+func (c *Client) DeleteSession(sessionID string) error {
+	return c.DeleteSessionCtx(context.Background(), sessionID)
+}
+
+/**
+ * DeleteSessionCtx is DeleteSession with a context for cancellation.
+ */
+func (c *Client) DeleteSessionCtx(ctx context.Context, sessionID string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.endpoint("/session/"+url.PathEscape(sessionID)), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return requestError(ctx, "delete session", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	return newStatusError("failed to delete session", resp)
+}
+
 func (c *Client) GetSession(sessionID string) (*Session, error) {
-	resp, err := c.httpClient.Get(fmt.Sprintf("%s/session/%s", c.baseURL, sessionID))
+	req, err := http.NewRequest(http.MethodGet, c.endpoint("/session/"+sessionID), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
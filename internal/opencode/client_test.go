@@ -2,9 +2,12 @@
 package opencode
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -135,9 +138,7 @@ func TestSendMessageSuccess(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(Message{
-			Info: struct {
-				ID string `json:"id"`
-			}{ID: "msg-123"},
+			Info: MessageInfo{ID: "msg-123"},
 			Parts: []MessagePart{
 				{
 					Type: "text",
@@ -172,9 +173,7 @@ func TestSendMessageExtractsFirstTextPart(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(Message{
-			Info: struct {
-				ID string `json:"id"`
-			}{ID: "msg-456"},
+			Info: MessageInfo{ID: "msg-456"},
 			Parts: []MessagePart{
 				{Type: "code", Text: "some code"},
 				{Type: "text", Text: "feat: add feature"},
@@ -224,6 +223,42 @@ func TestGetSessionSuccess(t *testing.T) {
 	t.Logf("✓ Session retrieved: %s", session.ID)
 }
 
+func TestDeleteSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"deleted", http.StatusOK, false},
+		{"no content", http.StatusNoContent, false},
+		{"already gone", http.StatusNotFound, false},
+		{"server error", http.StatusBadRequest, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete {
+					t.Errorf("Wrong method: %s", r.Method)
+				}
+				if r.URL.Path != "/session/session-123" {
+					t.Errorf("Wrong path: %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := NewClient("localhost", 9999, 5)
+			client.baseURL = server.URL
+
+			err := client.DeleteSession("session-123")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeleteSession error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestClientTimeout(t *testing.T) {
 	client := NewClient("localhost", 4096, 15)
 
@@ -267,3 +302,271 @@ func TestModelConfiguration(t *testing.T) {
 
 	t.Logf("✓ Model configured: %s/%s", model.ProviderID, model.ModelID)
 }
+
+func TestSendMessageRetriesTruncatedResponse(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			_, _ = w.Write([]byte(`{"info":{"id":"msg-1"},"parts":[{"type":"te`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Message{
+			Parts: []MessagePart{{Type: "text", Text: "feat: add retry"}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+
+	response, err := client.SendMessage("session-123", "Test message", nil)
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	if response != "feat: add retry" {
+		t.Errorf("Response mismatch: got %q", response)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+	if partials := client.PartialResponses(); len(partials) != 1 || partials[0] != `{"info":{"id":"msg-1"},"parts":[{"type":"te` {
+		t.Errorf("Unexpected partial responses: %q", partials)
+	}
+
+	t.Log("✓ Truncated response retried")
+}
+
+func TestCreateSessionGivesUpAfterRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"id":"ses`))
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+	client.SetRetries(1)
+
+	_, err := client.CreateSession("test")
+	var truncated *TruncatedResponseError
+	if !errors.As(err, &truncated) {
+		t.Fatalf("Expected TruncatedResponseError, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+func TestSendMessageRetriesTransientStatus(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Message{
+			Parts: []MessagePart{{Type: "text", Text: "fix: recover"}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+	client.backoff = time.Millisecond
+
+	response, err := client.SendMessage("session-123", "Test message", nil)
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if response != "fix: recover" {
+		t.Errorf("Response mismatch: got %q", response)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestSendMessageDoesNotRetryClientError(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+	client.backoff = time.Millisecond
+
+	_, err := client.SendMessage("session-123", "Test message", nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 StatusError, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"2", 2 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(5 * time.Second).Format(http.TimeFormat), 5 * time.Second},
+		{now.Add(-5 * time.Second).Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestSendMessageCtxCancelled(t *testing.T) {
+	released := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-released:
+		}
+	}))
+	defer server.Close()
+	defer close(released)
+
+	client := NewClient("localhost", 9999, 30)
+	client.baseURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.SendMessageCtx(ctx, "session-123", "Test message", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Cancellation took too long: %v", elapsed)
+	}
+}
+
+func TestCreateSessionSendsAPIKey(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Session{ID: "session-123"})
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+	client.SetAPIKey("sk-secret")
+
+	if _, err := client.CreateSession("test"); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if auth != "Bearer sk-secret" {
+		t.Errorf("Authorization header mismatch: got %q", auth)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	tests := []struct {
+		scheme   string
+		basePath string
+		expected string
+	}{
+		{"", "", "http://example.com:4096"},
+		{"http", "", "http://example.com:4096"},
+		{"https", "/opencode", "https://example.com:4096/opencode"},
+		{"https", "opencode/", "https://example.com:4096/opencode"},
+		{"https", "/", "https://example.com:4096"},
+		{"http", "/proxy/opencode/", "http://example.com:4096/proxy/opencode"},
+	}
+
+	for _, tt := range tests {
+		if got := BaseURL(tt.scheme, "example.com", 4096, tt.basePath); got != tt.expected {
+			t.Errorf("BaseURL(%q, %q) = %q, expected %q", tt.scheme, tt.basePath, got, tt.expected)
+		}
+	}
+}
+
+func TestClientHTTPSWithBasePath(t *testing.T) {
+	var paths []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/opencode/global/health":
+			_ = json.NewEncoder(w).Encode(HealthResponse{Healthy: true})
+		case "/opencode/session":
+			_ = json.NewEncoder(w).Encode(Session{ID: "session-123"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClientWithBaseURL(server.URL+"/opencode/", 5)
+	client.httpClient = server.Client()
+
+	if healthy, err := client.CheckHealth(); err != nil || !healthy {
+		t.Fatalf("CheckHealth failed: %v, %v", healthy, err)
+	}
+	if _, err := client.CreateSession("test"); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	for _, path := range paths {
+		if strings.Contains(path, "//") {
+			t.Errorf("Double slash in request path %q", path)
+		}
+	}
+}
+
+func TestSendMessageReportsUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected *Usage
+	}{
+		{"usage object", `{"parts":[{"type":"text","text":"feat: x"}],"usage":{"prompt_tokens":1200,"completion_tokens":15}}`, &Usage{PromptTokens: 1200, CompletionTokens: 15}},
+		{"info tokens", `{"info":{"id":"msg-1","tokens":{"input":800,"output":20,"reasoning":0}},"parts":[{"type":"text","text":"feat: x"}]}`, &Usage{PromptTokens: 800, CompletionTokens: 20}},
+		{"omitted", `{"info":{"id":"msg-1"},"parts":[{"type":"text","text":"feat: x"}]}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewClient("localhost", 9999, 5)
+			client.baseURL = server.URL
+
+			if _, err := client.SendMessage("session-123", "Test message", nil); err != nil {
+				t.Fatalf("SendMessage failed: %v", err)
+			}
+
+			usage := client.LastUsage()
+			if (usage == nil) != (tt.expected == nil) || (usage != nil && *usage != *tt.expected) {
+				t.Errorf("Usage mismatch: got %+v, expected %+v", usage, tt.expected)
+			}
+		})
+	}
+}
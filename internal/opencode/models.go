@@ -0,0 +1,147 @@
+package opencode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/**
+ * ModelInfo is a model the backend can use, addressed as "provider/id".
+ */
+type ModelInfo struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+}
+
+// String returns the "provider/id" form used by generation.model.
+func (m ModelInfo) String() string {
+	return m.Provider + "/" + m.ID
+}
+
+// providersResponse is the body of GET /config/providers.
+type providersResponse struct {
+	Providers []struct {
+		ID     string `json:"id"`
+		Models map[string]struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	} `json:"providers"`
+}
+
+// modelLinePattern matches a "provider/model" line printed by `opencode models`.
+var modelLinePattern = regexp.MustCompile(`^([\w.-]+)/(\S+)$`)
+
+/**
+ * ListModels returns the models of every provider configured on the server.
+ *
+ * @returns The models sorted by provider and id
+ */
+func (c *Client) ListModels() ([]ModelInfo, error) {
+	return c.ListModelsCtx(context.Background())
+}
+
+/**
+ * ListModelsCtx is ListModels with a context for cancellation.
+ */
+func (c *Client) ListModelsCtx(ctx context.Context) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/config/providers"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, requestError(ctx, "list models", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError("failed to list models", resp)
+	}
+
+	var body providersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse providers response: %w", err)
+	}
+
+	var models []ModelInfo
+	for _, provider := range body.Providers {
+		for id, model := range provider.Models {
+			models = append(models, ModelInfo{Provider: provider.ID, ID: id, Name: model.Name})
+		}
+	}
+	sortModels(models)
+	return models, nil
+}
+
+/**
+ * ListModels runs `opencode models` and parses the "provider/model" lines it
+ * prints.
+ *
+ * @returns The models sorted by provider and id
+ */
+func (r *Runner) ListModels() ([]ModelInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.binary, "models")
+	cmd.Env = append(os.Environ(), "NO_COLOR=1")
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("opencode models timed out after %v", r.timeout)
+		}
+		return nil, fmt.Errorf("opencode models failed: %w", err)
+	}
+	return parseModelList(string(output)), nil
+}
+
+/**
+ * parseModelList extracts the "provider/model" lines from `opencode models`
+ * output, ignoring color codes, log lines, and headings.
+ */
+func parseModelList(output string) []ModelInfo {
+	cleaned, _ := filterOutput(output)
+
+	var models []ModelInfo
+	for _, line := range strings.Split(cleaned, "\n") {
+		if match := modelLinePattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			models = append(models, ModelInfo{Provider: match[1], ID: match[2]})
+		}
+	}
+	sortModels(models)
+	return models
+}
+
+func sortModels(models []ModelInfo) {
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].Provider != models[j].Provider {
+			return models[i].Provider < models[j].Provider
+		}
+		return models[i].ID < models[j].ID
+	})
+}
+
+/**
+ * HasModel reports whether provider/id is among models.
+ *
+ * @param models - The available models
+ * @param provider - The provider id, e.g. "anthropic"
+ * @param id - The model id
+ * @returns true if the model is listed
+ */
+func HasModel(models []ModelInfo, provider, id string) bool {
+	for _, model := range models {
+		if model.Provider == provider && model.ID == id {
+			return true
+		}
+	}
+	return false
+}
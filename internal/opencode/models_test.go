@@ -0,0 +1,108 @@
+package opencode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/config/providers" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"providers": [
+				{"id": "openai", "name": "OpenAI", "models": {
+					"gpt-4o-mini": {"id": "gpt-4o-mini", "name": "GPT-4o mini"}
+				}},
+				{"id": "anthropic", "name": "Anthropic", "models": {
+					"claude-sonnet-4": {"id": "claude-sonnet-4", "name": "Claude Sonnet 4"},
+					"claude-haiku-4": {"id": "claude-haiku-4", "name": "Claude Haiku 4"}
+				}}
+			],
+			"default": {"anthropic": "claude-sonnet-4"}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+
+	models, err := client.ListModels()
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+
+	expected := []ModelInfo{
+		{Provider: "anthropic", ID: "claude-haiku-4", Name: "Claude Haiku 4"},
+		{Provider: "anthropic", ID: "claude-sonnet-4", Name: "Claude Sonnet 4"},
+		{Provider: "openai", ID: "gpt-4o-mini", Name: "GPT-4o mini"},
+	}
+	if !reflect.DeepEqual(models, expected) {
+		t.Errorf("Models mismatch:\n  got: %+v\n  expected: %+v", models, expected)
+	}
+	if !HasModel(models, "openai", "gpt-4o-mini") || HasModel(models, "google", "gpt-4o-mini") {
+		t.Error("HasModel mismatch")
+	}
+	if models[0].String() != "anthropic/claude-haiku-4" {
+		t.Errorf("String mismatch: %q", models[0].String())
+	}
+}
+
+func TestListModelsStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+
+	if _, err := client.ListModels(); err == nil {
+		t.Fatal("Expected an error for a failing server")
+	}
+}
+
+func TestParseModelList(t *testing.T) {
+	output := "\x1b[1mAvailable models\x1b[0m\n" +
+		"INFO  2025-01-01T00:00:00 loading providers\n" +
+		"opencode/gpt-5-nano\n" +
+		"anthropic/claude-sonnet-4\n" +
+		"openrouter/meta-llama/llama-3.1-8b\n" +
+		"\n"
+
+	expected := []ModelInfo{
+		{Provider: "anthropic", ID: "claude-sonnet-4"},
+		{Provider: "opencode", ID: "gpt-5-nano"},
+		{Provider: "openrouter", ID: "meta-llama/llama-3.1-8b"},
+	}
+	if models := parseModelList(output); !reflect.DeepEqual(models, expected) {
+		t.Errorf("Models mismatch:\n  got: %+v\n  expected: %+v", models, expected)
+	}
+}
+
+func TestRunnerListModels(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the opencode binary")
+	}
+
+	binary := filepath.Join(t.TempDir(), "opencode")
+	script := "#!/bin/sh\n[ \"$1\" = models ] || exit 1\necho opencode/gpt-5-nano\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+
+	models, err := NewRunnerWithBinary(binary, 5).ListModels()
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 1 || models[0].String() != "opencode/gpt-5-nano" {
+		t.Errorf("Unexpected models: %+v", models)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build !windows
+
+package opencode
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setSysProcAttr sets platform-specific process attributes.
+// On Unix systems, this sets Setpgid to detach the process from the parent.
+func setSysProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+}
+
+// processAlive reports whether a process with pid exists.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// processStartTime identifies when pid started, so a recorded PID reused by
+// an unrelated process can be told apart: the start time in clock ticks from
+// /proc on Linux, otherwise the start time reported by ps. It returns an
+// empty string if the process does not exist.
+func processStartTime(pid int) string {
+	if data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat"); err == nil {
+		// The command name may contain spaces, so count fields after its ")".
+		if i := strings.LastIndexByte(string(data), ')'); i >= 0 {
+			if fields := strings.Fields(string(data[i+1:])); len(fields) > 19 {
+				return fields[19]
+			}
+		}
+	}
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// killProcessGroup sends SIGTERM to the process group led by pid.
+func killProcessGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGTERM)
+}
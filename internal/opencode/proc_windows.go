@@ -0,0 +1,40 @@
+//go:build windows
+
+package opencode
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setSysProcAttr sets platform-specific process attributes.
+// On Windows, no special attributes are needed.
+func setSysProcAttr(cmd *exec.Cmd) {
+	// No-op on Windows
+}
+
+// processAlive reports whether a process with pid exists; on Windows
+// FindProcess fails for unknown PIDs.
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
+
+// processStartTime is not available on Windows; recorded PIDs are trusted
+// while the process exists.
+func processStartTime(pid int) string {
+	return ""
+}
+
+// killProcessGroup kills the process; Windows has no process groups here.
+func killProcessGroup(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
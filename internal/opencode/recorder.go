@@ -0,0 +1,203 @@
+package opencode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maskedHeaders lists request headers whose values are never written to a recording.
+var maskedHeaders = []string{"Authorization", "Proxy-Authorization", "X-Api-Key", "Cookie"}
+
+/**
+ * RecordedExchange is a single HTTP request/response pair written to a recording file.
+ */
+type RecordedExchange struct {
+	Time         time.Time         `json:"time"`
+	Method       string            `json:"method"`
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	RequestBody  string            `json:"request_body,omitempty"`
+	Status       int               `json:"status,omitempty"`
+	ResponseBody string            `json:"response_body,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+/**
+ * recordingTransport wraps an http.RoundTripper and appends every exchange
+ * as a JSON line to the recording file.
+ */
+type recordingTransport struct {
+	base http.RoundTripper
+	path string
+	mu   sync.Mutex
+}
+
+/**
+ * RecordTo makes the client append every outgoing request and its response
+ * to the file at path, one JSON object per line. Auth headers are masked.
+ *
+ * @param path - The file to append recorded exchanges to
+ */
+func (c *Client) RecordTo(path string) {
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c.httpClient.Transport = &recordingTransport{base: base, path: path}
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	exchange := RecordedExchange{
+		Time:    time.Now(),
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: maskHeaders(req.Header),
+	}
+
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		exchange.RequestBody = string(body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		exchange.Error = err.Error()
+		t.write(exchange)
+		return nil, err
+	}
+
+	exchange.Status = resp.StatusCode
+
+	// Event streams are recorded as they are read, so the caller sees each
+	// event as it arrives and an open connection does not block the call.
+	if isEventStream(resp) {
+		resp.Body = &recordingBody{body: resp.Body, transport: t, exchange: exchange}
+		return resp, nil
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	exchange.ResponseBody = string(body)
+	if readErr != nil {
+		exchange.Error = readErr.Error()
+	}
+
+	t.write(exchange)
+	return resp, readErr
+}
+
+/**
+ * recordingBody passes a streamed response body through to the caller,
+ * copying what is read, and writes the exchange once the body reaches EOF,
+ * fails, or is closed.
+ */
+type recordingBody struct {
+	body      io.ReadCloser
+	transport *recordingTransport
+	exchange  RecordedExchange
+	buf       bytes.Buffer
+	once      sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.buf.Write(p[:n])
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.body.Close()
+	b.finish(nil)
+	return err
+}
+
+func (b *recordingBody) finish(err error) {
+	b.once.Do(func() {
+		b.exchange.ResponseBody = b.buf.String()
+		if err != nil && err != io.EOF {
+			b.exchange.Error = err.Error()
+		}
+		b.transport.write(b.exchange)
+	})
+}
+
+func (t *recordingTransport) write(exchange RecordedExchange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.Marshal(exchange)
+	if err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write request recording: %v\n", err)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	_, _ = f.Write(append(data, '\n'))
+}
+
+func maskHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		for _, masked := range maskedHeaders {
+			if strings.EqualFold(name, masked) {
+				value = "****"
+				break
+			}
+		}
+		result[name] = value
+	}
+	return result
+}
+
+/**
+ * ReadRecording parses a recording file written by RecordTo.
+ *
+ * @param path - The recording file to read
+ * @returns The recorded exchanges in the order they were written
+ * @returns An error if the file cannot be read or parsed
+ */
+func ReadRecording(path string) ([]RecordedExchange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var exchanges []RecordedExchange
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var exchange RecordedExchange
+		if err := json.Unmarshal([]byte(line), &exchange); err != nil {
+			return nil, fmt.Errorf("failed to parse recording: %w", err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchanges, nil
+}
@@ -0,0 +1,121 @@
+package opencode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordToWritesExchanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"session-123","title":"Test"}`))
+	}))
+	defer server.Close()
+
+	recordPath := filepath.Join(t.TempDir(), "record.jsonl")
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+	client.RecordTo(recordPath)
+
+	if _, err := client.CreateSession("Test"); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	exchanges, err := ReadRecording(recordPath)
+	if err != nil {
+		t.Fatalf("ReadRecording failed: %v", err)
+	}
+
+	if len(exchanges) != 1 {
+		t.Fatalf("Expected 1 exchange, got %d", len(exchanges))
+	}
+
+	exchange := exchanges[0]
+	if exchange.Method != "POST" {
+		t.Errorf("Method mismatch: got %q", exchange.Method)
+	}
+	if exchange.RequestBody != `{"title":"Test"}` {
+		t.Errorf("Request body mismatch: got %q", exchange.RequestBody)
+	}
+	if exchange.Status != http.StatusOK {
+		t.Errorf("Status mismatch: got %d", exchange.Status)
+	}
+	if exchange.ResponseBody != `{"id":"session-123","title":"Test"}` {
+		t.Errorf("Response body mismatch: got %q", exchange.ResponseBody)
+	}
+
+	t.Log("✓ Exchange recorded with request and response bodies")
+}
+
+func TestMaskHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret")
+	header.Set("X-Api-Key", "secret")
+	header.Set("Content-Type", "application/json")
+
+	masked := maskHeaders(header)
+
+	if masked["Authorization"] != "****" {
+		t.Errorf("Authorization not masked: %q", masked["Authorization"])
+	}
+	if masked["X-Api-Key"] != "****" {
+		t.Errorf("X-Api-Key not masked: %q", masked["X-Api-Key"])
+	}
+	if masked["Content-Type"] != "application/json" {
+		t.Errorf("Content-Type should not be masked: %q", masked["Content-Type"])
+	}
+
+	t.Log("✓ Auth headers masked in recordings")
+}
+
+func TestRecordToStreamsEventStream(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: feat: record\n\nevent: done\ndata: {}\n\n"))
+		w.(http.Flusher).Flush()
+		// Keep the connection open after "done", as some servers do
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	recordPath := filepath.Join(t.TempDir(), "record.jsonl")
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+	client.RecordTo(recordPath)
+
+	start := time.Now()
+	response, err := client.SendMessageStream("session-123", "Test message", nil, nil)
+	if err != nil {
+		t.Fatalf("SendMessageStream failed: %v", err)
+	}
+	if response != "feat: record" {
+		t.Errorf("Response mismatch: got %q", response)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the stream to return after done, took %v", elapsed)
+	}
+
+	exchanges, err := ReadRecording(recordPath)
+	if err != nil {
+		t.Fatalf("ReadRecording failed: %v", err)
+	}
+	if len(exchanges) != 1 {
+		t.Fatalf("Expected 1 exchange, got %d", len(exchanges))
+	}
+	if !strings.Contains(exchanges[0].ResponseBody, "event: done") {
+		t.Errorf("Expected the streamed events recorded, got %q", exchanges[0].ResponseBody)
+	}
+
+	t.Log("✓ Event stream recorded without buffering the reply")
+}
@@ -3,17 +3,45 @@ package opencode
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
+// DefaultBinary is the opencode executable looked up in PATH when none is configured.
+const DefaultBinary = "opencode"
+
+// quietFlags suppress opencode's own log output so stdout only carries the model response.
+var quietFlags = []string{"--log-level", "ERROR"}
+
+// maxArgPrompt is the longest prompt passed as a command-line argument; longer
+// prompts are piped through stdin to stay clear of the OS argument-length limit.
+const maxArgPrompt = 16 * 1024
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// noisePattern matches opencode log lines that leak onto stdout.
+var noisePattern = regexp.MustCompile(`^(DEBUG|INFO|WARN|ERROR)\s+\d{4}-\d{2}-\d{2}`)
+
 /**
  * Runner executes opencode CLI commands directly via subprocess.
  */
 type Runner struct {
+	binary  string
 	timeout time.Duration
+
+	quietOnce sync.Once
+	quietArgs []string
+	helpText  func() string
+
+	filtered int
 }
 
 /**
@@ -23,21 +51,55 @@ type Runner struct {
  * @returns A new Runner instance
  */
 func NewRunner(timeout int) *Runner {
+	return NewRunnerWithBinary(DefaultBinary, timeout)
+}
+
+/**
+ * NewRunnerWithBinary creates a new Runner that invokes the given opencode
+ * executable, which may be a name looked up in PATH or a path starting with ~.
+ *
+ * @param binary - The opencode executable name or path (empty uses DefaultBinary)
+ * @param timeout - The timeout in seconds for subprocess execution
+ * @returns A new Runner instance
+ */
+func NewRunnerWithBinary(binary string, timeout int) *Runner {
 	return &Runner{
+		binary:  ResolveBinary(binary),
 		timeout: time.Duration(timeout) * time.Second,
 	}
 }
 
+/**
+ * ResolveBinary expands a leading ~ in the configured opencode binary and
+ * falls back to DefaultBinary when it is empty.
+ *
+ * @param binary - The configured binary name or path
+ * @returns The executable to run
+ */
+func ResolveBinary(binary string) string {
+	binary = strings.TrimSpace(binary)
+	if binary == "" {
+		return DefaultBinary
+	}
+
+	if binary == "~" || strings.HasPrefix(binary, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(binary, "~"))
+		}
+	}
+	return binary
+}
+
 /**
  * CheckAvailable verifies that the opencode binary is available in PATH.
+ * Successful results are cached until PATH changes.
  *
  * @returns true if opencode is available, false otherwise
  * @returns An error if the binary is not found
  */
 func (r *Runner) CheckAvailable() (bool, error) {
-	_, err := exec.LookPath("opencode")
-	if err != nil {
-		return false, fmt.Errorf("opencode binary not found in PATH: %w", err)
+	if err := cachedLookPath(r.binary); err != nil {
+		return false, fmt.Errorf("%s binary not found in PATH: %w", r.binary, err)
 	}
 	return true, nil
 }
@@ -54,16 +116,12 @@ func (r *Runner) Generate(prompt string, model *Model) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
 	defer cancel()
 
-	args := []string{"run"}
-
-	if model != nil && model.ProviderID != "" && model.ModelID != "" {
-		args = append(args, "--model", fmt.Sprintf("%s/%s", model.ProviderID, model.ModelID))
+	cmd := exec.CommandContext(ctx, r.binary, r.buildArgs(prompt, model)...)
+	cmd.Env = append(os.Environ(), "NO_COLOR=1")
+	if useStdin(prompt) {
+		cmd.Stdin = strings.NewReader(prompt)
 	}
 
-	args = append(args, prompt)
-
-	cmd := exec.CommandContext(ctx, "opencode", args...)
-
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -72,8 +130,106 @@ func (r *Runner) Generate(prompt string, model *Model) (string, error) {
 		if ctx.Err() == context.DeadlineExceeded {
 			return "", fmt.Errorf("opencode run timed out after %v", r.timeout)
 		}
+		if errors.Is(err, syscall.E2BIG) {
+			return "", fmt.Errorf("opencode run failed: the prompt (%d bytes) exceeds the OS argument-length limit; lower git.max_diff_size: %w", len(prompt), err)
+		}
 		return "", fmt.Errorf("opencode run failed: %w - %s", err, stderr.String())
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	output, filtered := filterOutput(stdout.String())
+	r.filtered = filtered
+	return output, nil
+}
+
+/**
+ * FilteredLines returns how many lines of the last Generate output were
+ * dropped as log noise.
+ *
+ * @returns The number of filtered lines
+ */
+func (r *Runner) FilteredLines() int {
+	return r.filtered
+}
+
+/**
+ * useStdin reports whether the prompt is too long to pass as an argument and
+ * should be piped through stdin instead.
+ */
+func useStdin(prompt string) bool {
+	return len(prompt) > maxArgPrompt
+}
+
+/**
+ * buildArgs assembles the opencode run arguments, including quiet flags
+ * when the installed opencode supports them. Long prompts are left out and
+ * sent through stdin by Generate.
+ *
+ * @param prompt - The prompt text
+ * @param model - The model configuration, or nil for the opencode default
+ * @returns The argument list for the opencode binary
+ */
+func (r *Runner) buildArgs(prompt string, model *Model) []string {
+	args := []string{"run"}
+	args = append(args, r.detectQuietArgs()...)
+
+	if model != nil && model.ProviderID != "" && model.ModelID != "" {
+		args = append(args, "--model", fmt.Sprintf("%s/%s", model.ProviderID, model.ModelID))
+	}
+
+	if useStdin(prompt) {
+		return args
+	}
+	return append(args, prompt)
+}
+
+/**
+ * detectQuietArgs checks once whether the installed opencode advertises the
+ * quiet flags in its run help output. Older versions without them get none.
+ *
+ * @returns The quiet flags to pass, or nil if unsupported
+ */
+func (r *Runner) detectQuietArgs() []string {
+	r.quietOnce.Do(func() {
+		helpText := r.helpText
+		if helpText == nil {
+			helpText = r.runHelp
+		}
+		if strings.Contains(helpText(), quietFlags[0]) {
+			r.quietArgs = quietFlags
+		}
+	})
+	return r.quietArgs
+}
+
+func (r *Runner) runHelp() string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	output, _ := exec.CommandContext(ctx, r.binary, "run", "--help").CombinedOutput()
+	return string(output)
+}
+
+/**
+ * filterOutput strips terminal color codes, opencode log lines, and surrounding
+ * whitespace from opencode output. It is a backstop for versions that ignore
+ * the quiet flags.
+ *
+ * @param output - The raw stdout from opencode
+ * @returns The cleaned output
+ * @returns The number of lines dropped as noise
+ */
+func filterOutput(output string) (string, int) {
+	output = ansiPattern.ReplaceAllString(output, "")
+
+	var kept []string
+	filtered := 0
+	for _, line := range strings.Split(output, "\n") {
+		if noisePattern.MatchString(line) {
+			filtered++
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	return strings.TrimSpace(strings.Join(kept, "\n")), filtered
 }
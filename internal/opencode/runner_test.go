@@ -1,6 +1,10 @@
 package opencode
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -133,3 +137,249 @@ func TestMultipleRunnerInstances(t *testing.T) {
 
 	t.Log("✓ Multiple runner instances created with different timeouts")
 }
+
+/**
+ * TestBuildArgsIncludesQuietFlags verifies quiet flags are passed when opencode supports them.
+ */
+func TestBuildArgsIncludesQuietFlags(t *testing.T) {
+	runner := NewRunner(10)
+	runner.helpText = func() string {
+		return "Options:\n  --log-level  log level [choices: DEBUG, INFO, WARN, ERROR]\n"
+	}
+
+	args := runner.buildArgs("prompt", &Model{ProviderID: "opencode", ModelID: "gpt-5-nano"})
+	joined := strings.Join(args, " ")
+
+	if !strings.Contains(joined, "--log-level ERROR") {
+		t.Errorf("Quiet flag missing from args: %v", args)
+	}
+
+	if args[0] != "run" || args[len(args)-1] != "prompt" {
+		t.Errorf("Unexpected argument layout: %v", args)
+	}
+
+	t.Logf("✓ Quiet flags included: %v", args)
+}
+
+/**
+ * TestBuildArgsOmitsUnsupportedQuietFlags verifies older opencode versions get no quiet flags.
+ */
+func TestBuildArgsOmitsUnsupportedQuietFlags(t *testing.T) {
+	runner := NewRunner(10)
+	runner.helpText = func() string { return "Options:\n  --model  model to use\n" }
+
+	args := runner.buildArgs("prompt", nil)
+
+	if strings.Contains(strings.Join(args, " "), "--log-level") {
+		t.Errorf("Quiet flag should be omitted when unsupported: %v", args)
+	}
+
+	t.Log("✓ Quiet flags omitted when unsupported")
+}
+
+/**
+ * TestFilterOutputStripsColor verifies the output backstop removes ANSI color codes.
+ */
+func TestFilterOutputStripsColor(t *testing.T) {
+	output := "\x1b[1m\x1b[32mfeat: add feature\x1b[0m\n"
+
+	if result, _ := filterOutput(output); result != "feat: add feature" {
+		t.Errorf("Color codes not stripped: got %q", result)
+	}
+
+	t.Log("✓ ANSI color codes stripped from output")
+}
+
+/**
+ * TestRunnerUsesConfiguredBinary verifies a custom opencode binary is invoked.
+ */
+func TestRunnerUsesConfiguredBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Stub script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	stub := filepath.Join(dir, "my-opencode")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\necho 'feat: from stub'\n"
+	if err := os.WriteFile(stub, []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+
+	runner := NewRunnerWithBinary(stub, 10)
+	runner.helpText = func() string { return "" }
+
+	available, err := runner.CheckAvailable()
+	if err != nil || !available {
+		t.Fatalf("Stub binary should be available: %v", err)
+	}
+
+	output, err := runner.Generate("hello", nil)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if output != "feat: from stub" {
+		t.Errorf("Output mismatch: got %q", output)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Stub was not invoked: %v", err)
+	}
+	if strings.TrimSpace(string(args)) != "run hello" {
+		t.Errorf("Unexpected stub args: %q", args)
+	}
+
+	t.Log("✓ Configured opencode binary invoked")
+}
+
+/**
+ * TestResolveBinary verifies defaulting and ~ expansion of the binary path.
+ */
+func TestResolveBinary(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("No home directory")
+	}
+
+	tests := map[string]string{
+		"":                 DefaultBinary,
+		"oc-wrapper":       "oc-wrapper",
+		"~/bin/opencode":   filepath.Join(home, "bin", "opencode"),
+		"/opt/oc/opencode": "/opt/oc/opencode",
+	}
+
+	for input, expected := range tests {
+		if got := ResolveBinary(input); got != expected {
+			t.Errorf("ResolveBinary(%q): got %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+/**
+ * TestFilterOutputCountsNoiseLines verifies dropped log lines are counted.
+ */
+func TestFilterOutputCountsNoiseLines(t *testing.T) {
+	output := "INFO  2025-01-02T10:00:00 +12ms service=default version=0.1.0 opencode\n" +
+		"feat: add feature\n" +
+		"WARN  2025-01-02T10:00:01 +3ms service=provider slow response\n"
+
+	result, filtered := filterOutput(output)
+
+	if result != "feat: add feature" {
+		t.Errorf("Noise not removed: got %q", result)
+	}
+	if filtered != 2 {
+		t.Errorf("Expected 2 filtered lines, got %d", filtered)
+	}
+
+	t.Logf("✓ Filtered %d noise lines", filtered)
+}
+
+/**
+ * TestCheckAvailableUsesCache verifies a second check skips the PATH lookup
+ * and that changing PATH invalidates the cached result.
+ */
+func TestCheckAvailableUsesCache(t *testing.T) {
+	resetAvailabilityCache()
+	defer resetAvailabilityCache()
+	SetAvailabilityCacheDir("")
+
+	lookups := 0
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) {
+		lookups++
+		return "/usr/bin/" + file, nil
+	}
+	defer func() { lookPath = originalLookPath }()
+
+	runner := NewRunnerWithBinary("opencode-cache-test", 10)
+	for i := 0; i < 2; i++ {
+		if available, err := runner.CheckAvailable(); !available || err != nil {
+			t.Fatalf("CheckAvailable failed: %v, %v", available, err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("Expected second check to hit the cache, got %d lookups", lookups)
+	}
+
+	t.Setenv("PATH", os.Getenv("PATH")+string(os.PathListSeparator)+t.TempDir())
+	if _, err := runner.CheckAvailable(); err != nil {
+		t.Fatalf("CheckAvailable failed: %v", err)
+	}
+	if lookups != 2 {
+		t.Errorf("Expected PATH change to invalidate the cache, got %d lookups", lookups)
+	}
+
+	t.Log("✓ Availability check cached until PATH changes")
+}
+
+/**
+ * TestCheckAvailableUsesDiskCache verifies a successful check is reused
+ * across processes through the cache directory.
+ */
+func TestCheckAvailableUsesDiskCache(t *testing.T) {
+	resetAvailabilityCache()
+	defer resetAvailabilityCache()
+	SetAvailabilityCacheDir(t.TempDir())
+	defer SetAvailabilityCacheDir("")
+
+	lookups := 0
+	originalLookPath := lookPath
+	lookPath = func(file string) (string, error) {
+		lookups++
+		return "/usr/bin/" + file, nil
+	}
+	defer func() { lookPath = originalLookPath }()
+
+	runner := NewRunnerWithBinary("opencode-disk-test", 10)
+	_, _ = runner.CheckAvailable()
+	resetAvailabilityCache()
+	_, _ = runner.CheckAvailable()
+
+	if lookups != 1 {
+		t.Errorf("Expected on-disk cache hit after reset, got %d lookups", lookups)
+	}
+}
+
+/**
+ * TestRunnerPipesLongPromptThroughStdin verifies a prompt too large for the
+ * command line reaches opencode through stdin without an exec error.
+ */
+func TestRunnerPipesLongPromptThroughStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Stub script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	stdinFile := filepath.Join(dir, "stdin")
+	stub := filepath.Join(dir, "opencode")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat > " + stdinFile + "\necho 'feat: big change'\n"
+	if err := os.WriteFile(stub, []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+
+	runner := NewRunnerWithBinary(stub, 10)
+	runner.helpText = func() string { return "" }
+
+	prompt := strings.Repeat("x", 200*1024)
+	output, err := runner.Generate(prompt, nil)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if output != "feat: big change" {
+		t.Errorf("Output mismatch: got %q", output)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	if strings.TrimSpace(string(args)) != "run" {
+		t.Errorf("Prompt should not be passed as an argument: %q", args)
+	}
+	stdin, _ := os.ReadFile(stdinFile)
+	if string(stdin) != prompt {
+		t.Errorf("Stdin mismatch: got %d bytes, expected %d", len(stdin), len(prompt))
+	}
+
+	t.Log("✓ Long prompt piped through stdin")
+}
@@ -0,0 +1,214 @@
+package opencode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// healthPollInterval is how often an auto-started server is checked for readiness.
+const healthPollInterval = 250 * time.Millisecond
+
+// Files kept in the server state directory (the cache directory).
+const (
+	serverLogFile = "opencode-server.log"
+	serverPIDFile = "opencode-server.pid"
+)
+
+// ErrNoServerRecorded is returned by StopServer when no started server is recorded.
+var ErrNoServerRecorded = errors.New("no auto-started opencode server is recorded")
+
+/**
+ * ServerProcess is an `opencode serve` process started in the background.
+ * Its stdout and stderr go to LogPath.
+ */
+type ServerProcess struct {
+	LogPath string
+	exited  chan error
+}
+
+/**
+ * StartServer launches `opencode serve` detached from the current process
+ * group. Its output is written to opencode-server.log and its PID to
+ * opencode-server.pid in dir, so later invocations can find it.
+ *
+ * @param binary - The opencode executable name or path
+ * @param port - The port to serve on
+ * @param dir - The state directory; it is created if missing
+ * @returns The started process
+ */
+func StartServer(binary string, port int, dir string) (*ServerProcess, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create server state directory: %w", err)
+	}
+	logPath := filepath.Join(dir, serverLogFile)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open server log: %w", err)
+	}
+
+	cmd := exec.Command(ResolveBinary(binary), "serve", "--port", strconv.Itoa(port))
+	setSysProcAttr(cmd)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+
+	if err := cmd.Start(); err != nil {
+		_ = logFile.Close()
+		return nil, err
+	}
+	pidPath := filepath.Join(dir, serverPIDFile)
+	pid := cmd.Process.Pid
+	if err := WritePIDFile(pidPath, pid); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record opencode server PID: %v\n", err)
+	}
+
+	process := &ServerProcess{LogPath: logPath, exited: make(chan error, 1)}
+	go func() {
+		err := cmd.Wait()
+		_ = logFile.Close()
+		if recorded, readErr := ReadPIDFile(pidPath); readErr == nil && recorded == pid {
+			_ = os.Remove(pidPath)
+		}
+		process.exited <- err
+	}()
+	return process, nil
+}
+
+/**
+ * RunningServer returns the recorded server started by an earlier
+ * invocation if its process is still alive and has the recorded start time.
+ * A PID file left by a process that has exited, or whose PID now belongs to
+ * another process, is removed.
+ *
+ * @param dir - The state directory passed to StartServer
+ * @returns The running server, its PID, and true; or false if none is running
+ */
+func RunningServer(dir string) (*ServerProcess, int, bool) {
+	pidPath := filepath.Join(dir, serverPIDFile)
+	pid, started, err := readPIDRecord(pidPath)
+	if err != nil {
+		return nil, 0, false
+	}
+	if !processAlive(pid) || processStartTime(pid) != started {
+		_ = os.Remove(pidPath)
+		return nil, 0, false
+	}
+	return &ServerProcess{LogPath: filepath.Join(dir, serverLogFile)}, pid, true
+}
+
+/**
+ * StopServer terminates the recorded server's process group and removes
+ * its PID file.
+ *
+ * @param dir - The state directory passed to StartServer
+ * @returns The PID that was stopped, or ErrNoServerRecorded if no live server is recorded
+ */
+func StopServer(dir string) (int, error) {
+	_, pid, ok := RunningServer(dir)
+	if !ok {
+		return 0, ErrNoServerRecorded
+	}
+	if err := killProcessGroup(pid); err != nil {
+		return pid, fmt.Errorf("failed to stop opencode server (pid %d): %w", pid, err)
+	}
+	_ = os.Remove(filepath.Join(dir, serverPIDFile))
+	return pid, nil
+}
+
+/**
+ * WritePIDFile records pid in path, followed by the process start time on
+ * its own line so RunningServer can detect a reused PID.
+ *
+ * @param path - The PID file
+ * @param pid - The process ID
+ * @returns An error if the file cannot be written
+ */
+func WritePIDFile(path string, pid int) error {
+	return os.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"+processStartTime(pid)+"\n"), 0o644)
+}
+
+/**
+ * ReadPIDFile reads a PID written by WritePIDFile.
+ *
+ * @param path - The PID file
+ * @returns The PID, or an error if the file is missing or malformed
+ */
+func ReadPIDFile(path string) (int, error) {
+	pid, _, err := readPIDRecord(path)
+	return pid, err
+}
+
+// readPIDRecord reads the PID and the recorded start time from a PID file.
+// Files written before the start time was recorded have an empty one.
+func readPIDRecord(path string) (int, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", err
+	}
+	first, rest, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+	pid, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil || pid <= 0 {
+		return 0, "", fmt.Errorf("invalid PID file %s", path)
+	}
+	return pid, strings.TrimSpace(rest), nil
+}
+
+/**
+ * WaitHealthy polls check until the server reports healthy, the process
+ * exits, or timeout elapses.
+ *
+ * @param check - Reports whether the server is healthy
+ * @param timeout - How long to wait in total
+ * @returns An error naming the log file if the server never became healthy
+ */
+func (p *ServerProcess) WaitHealthy(check func() (bool, error), timeout time.Duration) error {
+	if err := WaitForHealth(check, timeout, healthPollInterval, p.exited); err != nil {
+		return fmt.Errorf("%w (server log: %s)", err, p.LogPath)
+	}
+	return nil
+}
+
+/**
+ * WaitForHealth calls check every interval until it reports healthy. It
+ * gives up when timeout elapses or a value arrives on exited.
+ *
+ * @param check - Reports whether the server is healthy
+ * @param timeout - How long to wait in total
+ * @param interval - The delay between checks
+ * @param exited - Receives the process exit status; nil if not watched
+ * @returns nil once healthy, otherwise an error with the last check failure
+ */
+func WaitForHealth(check func() (bool, error), timeout, interval time.Duration, exited <-chan error) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		healthy, err := check()
+		if err == nil && healthy {
+			return nil
+		}
+		lastErr = err
+
+		select {
+		case err := <-exited:
+			if err == nil {
+				return fmt.Errorf("opencode server exited before becoming healthy")
+			}
+			return fmt.Errorf("opencode server exited before becoming healthy: %w", err)
+		case <-deadline.C:
+			if lastErr != nil {
+				return fmt.Errorf("opencode server did not become healthy within %v: %w", timeout, lastErr)
+			}
+			return fmt.Errorf("opencode server did not become healthy within %v", timeout)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package opencode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForHealthBecomesHealthy(t *testing.T) {
+	polls := 0
+	check := func() (bool, error) {
+		polls++
+		switch {
+		case polls < 3:
+			return false, errors.New("connection refused")
+		case polls < 4:
+			return false, nil
+		}
+		return true, nil
+	}
+
+	if err := WaitForHealth(check, time.Second, time.Millisecond, nil); err != nil {
+		t.Fatalf("Expected the server to become healthy, got %v", err)
+	}
+	if polls != 4 {
+		t.Errorf("Expected 4 polls, got %d", polls)
+	}
+}
+
+func TestWaitForHealthTimesOut(t *testing.T) {
+	check := func() (bool, error) { return false, errors.New("connection refused") }
+
+	err := WaitForHealth(check, 20*time.Millisecond, time.Millisecond, nil)
+	if err == nil || !strings.Contains(err.Error(), "did not become healthy") || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("Expected a timeout with the last error, got %v", err)
+	}
+}
+
+func TestWaitForHealthStopsWhenProcessExits(t *testing.T) {
+	exited := make(chan error, 1)
+	exited <- errors.New("exit status 1")
+	check := func() (bool, error) { return false, nil }
+
+	start := time.Now()
+	err := WaitForHealth(check, time.Minute, time.Hour, exited)
+	if err == nil || !strings.Contains(err.Error(), "exited before becoming healthy") {
+		t.Fatalf("Expected an early exit error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected WaitForHealth to return as soon as the process exited")
+	}
+}
+
+func TestStartServerLogsOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the opencode binary")
+	}
+
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "opencode")
+	script := "#!/bin/sh\necho \"cannot bind port $3\" >&2\nexit 1\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+
+	stateDir := filepath.Join(dir, "state")
+	logPath := filepath.Join(stateDir, serverLogFile)
+	process, err := StartServer(binary, 4999, stateDir)
+	if err != nil {
+		t.Fatalf("StartServer failed: %v", err)
+	}
+
+	err = process.WaitHealthy(func() (bool, error) { return false, nil }, 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "exited before becoming healthy") || !strings.Contains(err.Error(), logPath) {
+		t.Fatalf("Expected an exit error naming the log, got %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil || !strings.Contains(string(data), "cannot bind port 4999") {
+		t.Errorf("Expected the server's stderr in the log, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, serverPIDFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected the PID file to be removed when the server exits, got %v", err)
+	}
+}
+
+func TestPIDFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), serverPIDFile)
+	if err := WritePIDFile(path, 4242); err != nil {
+		t.Fatalf("WritePIDFile failed: %v", err)
+	}
+	if pid, err := ReadPIDFile(path); err != nil || pid != 4242 {
+		t.Errorf("Expected pid 4242, got %d (%v)", pid, err)
+	}
+
+	for _, content := range []string{"", "abc", "-5"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write PID file: %v", err)
+		}
+		if _, err := ReadPIDFile(path); err == nil {
+			t.Errorf("Expected an error for PID file content %q", content)
+		}
+	}
+
+	if _, err := ReadPIDFile(filepath.Join(t.TempDir(), "missing.pid")); err == nil {
+		t.Error("Expected an error for a missing PID file")
+	}
+}
+
+func TestRunningServerRemovesStalePID(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on Unix process semantics")
+	}
+
+	// A finished child's PID is free again and, in practice, unused.
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run child: %v", err)
+	}
+
+	dir := t.TempDir()
+	pidPath := filepath.Join(dir, serverPIDFile)
+	if err := WritePIDFile(pidPath, cmd.Process.Pid); err != nil {
+		t.Fatalf("WritePIDFile failed: %v", err)
+	}
+
+	if _, _, running := RunningServer(dir); running {
+		t.Fatal("Expected a dead process not to count as running")
+	}
+	if _, err := os.Stat(pidPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale PID file to be removed, got %v", err)
+	}
+	if _, err := StopServer(dir); !errors.Is(err, ErrNoServerRecorded) {
+		t.Errorf("Expected ErrNoServerRecorded, got %v", err)
+	}
+}
+
+func TestRunningServerRejectsReusedPID(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process start times are not available on Windows")
+	}
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start child: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	dir := t.TempDir()
+	pidPath := filepath.Join(dir, serverPIDFile)
+	if err := WritePIDFile(pidPath, cmd.Process.Pid); err != nil {
+		t.Fatalf("WritePIDFile failed: %v", err)
+	}
+	if _, _, running := RunningServer(dir); !running {
+		t.Fatal("Expected the recorded process to count as running")
+	}
+
+	// The same PID with another start time belongs to an unrelated process
+	reused := fmt.Sprintf("%d\n%s\n", cmd.Process.Pid, "1")
+	if err := os.WriteFile(pidPath, []byte(reused), 0o644); err != nil {
+		t.Fatalf("Failed to write PID file: %v", err)
+	}
+	if _, _, running := RunningServer(dir); running {
+		t.Fatal("Expected a reused PID not to count as running")
+	}
+	if _, err := os.Stat(pidPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale PID file to be removed, got %v", err)
+	}
+	if _, err := StopServer(dir); !errors.Is(err, ErrNoServerRecorded) {
+		t.Errorf("Expected ErrNoServerRecorded, got %v", err)
+	}
+	if !processAlive(cmd.Process.Pid) {
+		t.Error("Expected the unrelated process to be left running")
+	}
+}
+
+func TestStopServerKillsRecordedProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the opencode binary")
+	}
+
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "opencode")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\nsleep 30\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write stub: %v", err)
+	}
+
+	process, err := StartServer(binary, 4999, dir)
+	if err != nil {
+		t.Fatalf("StartServer failed: %v", err)
+	}
+
+	_, pid, running := RunningServer(dir)
+	if !running {
+		t.Fatal("Expected the started server to be recorded as running")
+	}
+
+	stopped, err := StopServer(dir)
+	if err != nil || stopped != pid {
+		t.Fatalf("StopServer returned pid %d, err %v; expected pid %d", stopped, err, pid)
+	}
+
+	select {
+	case <-process.exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the server process to exit")
+	}
+	if _, err := os.Stat(filepath.Join(dir, serverPIDFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected the PID file to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package opencode
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+/**
+ * StreamEvent is a single server-sent event.
+ */
+type StreamEvent struct {
+	Event string
+	Data  string
+}
+
+/**
+ * sseReader parses a text/event-stream body into events. Reads are buffered,
+ * so an event split across several network reads is still returned whole.
+ */
+type sseReader struct {
+	r *bufio.Reader
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{r: bufio.NewReader(r)}
+}
+
+/**
+ * Next returns the next event with a data field, skipping comments and
+ * events without data.
+ *
+ * @returns The event, or io.EOF when the stream ends; a stream cut off
+ * mid-event yields io.ErrUnexpectedEOF
+ */
+func (s *sseReader) Next() (*StreamEvent, error) {
+	var event StreamEvent
+	var data []string
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF && (data != nil || event.Event != "") {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if data != nil {
+				event.Data = strings.Join(data, "\n")
+				return &event, nil
+			}
+			event = StreamEvent{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		}
+
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+}
+
+/**
+ * chunkText extracts the text carried by a stream event. Data that is a JSON
+ * message part contributes its text; anything else is taken verbatim.
+ */
+func chunkText(data string) string {
+	var part MessagePart
+	if strings.HasPrefix(data, "{") && json.Unmarshal([]byte(data), &part) == nil {
+		if part.Type == "text" || part.Type == "" {
+			return part.Text
+		}
+		return ""
+	}
+	return data
+}
+
+/**
+ * isEventStream reports whether the response is a text/event-stream.
+ */
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+/**
+ * SendMessageStream sends a message and streams the reply, calling onChunk
+ * with each piece of text as it arrives. If the server answers with a plain
+ * JSON message instead of an event stream, the whole reply is passed to
+ * onChunk once, as SendMessage would return it.
+ *
+ * @param sessionID - The session to send to
+ * @param message - The message text
+ * @param model - The model to use, or nil for the server default
+ * @param onChunk - Called with each chunk of text; may be nil
+ * @returns The accumulated response text
+ */
+func (c *Client) SendMessageStream(sessionID string, message string, model *Model, onChunk func(string)) (string, error) {
+	return c.SendMessageStreamCtx(context.Background(), sessionID, message, model, onChunk)
+}
+
+/**
+ * SendMessageStreamCtx is SendMessageStream with a context for cancellation.
+ */
+func (c *Client) SendMessageStreamCtx(ctx context.Context, sessionID string, message string, model *Model, onChunk func(string)) (string, error) {
+	req := PromptRequest{
+		Model: model,
+		Parts: []MessagePart{
+			{
+				Type: "text",
+				Text: message,
+			},
+		},
+	}
+
+	bodyBytes, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	if onChunk == nil {
+		onChunk = func(string) {}
+	}
+
+	c.usage = nil
+	var text string
+	err = c.withRetry(ctx, func() error {
+		resp, err := c.post(ctx, fmt.Sprintf("/session/%s/message", sessionID), bodyBytes, "text/event-stream, application/json")
+		if err != nil {
+			return requestError(ctx, "send message", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		if resp.StatusCode != http.StatusOK {
+			return newStatusError("failed to send message", resp)
+		}
+
+		if !isEventStream(resp) {
+			var msg Message
+			if err := decodeResponse(resp.Body, &msg); err != nil {
+				return fmt.Errorf("failed to parse message response: %w", err)
+			}
+			text, err = messageText(&msg)
+			if err != nil {
+				return err
+			}
+			c.usage = msg.TokenUsage()
+			onChunk(text)
+			return nil
+		}
+
+		text, err = readStream(resp.Body, onChunk)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if text == "" {
+		return "", fmt.Errorf("no text response received")
+	}
+	return text, nil
+}
+
+/**
+ * readStream consumes an event stream until it ends or sends a "done"
+ * event, passing each chunk to onChunk. An "error" event aborts the stream.
+ *
+ * @returns The accumulated text
+ */
+func readStream(body io.Reader, onChunk func(string)) (string, error) {
+	var sb strings.Builder
+	events := newSSEReader(body)
+	for {
+		event, err := events.Next()
+		if errors.Is(err, io.EOF) {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read message stream: %w", err)
+		}
+
+		switch event.Event {
+		case "error":
+			return "", fmt.Errorf("message stream failed: %s", event.Data)
+		case "done":
+			return sb.String(), nil
+		}
+
+		if chunk := chunkText(event.Data); chunk != "" {
+			sb.WriteString(chunk)
+			onChunk(chunk)
+		}
+	}
+}
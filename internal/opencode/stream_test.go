@@ -0,0 +1,131 @@
+package opencode
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestSSEReaderSplitAcrossReads(t *testing.T) {
+	stream := ": keep-alive\n\ndata: feat: add\n\nevent: message\r\ndata: {\"type\":\"text\",\"text\":\" streaming\"}\r\n\r\ndata: line one\ndata: line two\n\n"
+	events := newSSEReader(iotest.OneByteReader(strings.NewReader(stream)))
+
+	var got []StreamEvent
+	for {
+		event, err := events.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		got = append(got, *event)
+	}
+
+	expected := []StreamEvent{
+		{Data: "feat: add"},
+		{Event: "message", Data: `{"type":"text","text":" streaming"}`},
+		{Data: "line one\nline two"},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Event %d: got %+v, expected %+v", i, got[i], expected[i])
+		}
+	}
+}
+
+func TestSSEReaderTruncatedEvent(t *testing.T) {
+	events := newSSEReader(strings.NewReader("data: complete\n\ndata: cut"))
+
+	if _, err := events.Next(); err != nil {
+		t.Fatalf("First event failed: %v", err)
+	}
+	if _, err := events.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestSendMessageStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); !strings.Contains(accept, "text/event-stream") {
+			t.Errorf("Accept header missing event stream: %q", accept)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, piece := range []string{"data: {\"type\":\"text\",\"text\":\"feat: \"}\n", "\ndata: stream", " tokens\n\n", "event: done\ndata: {}\n\n"} {
+			_, _ = w.Write([]byte(piece))
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+
+	var chunks []string
+	response, err := client.SendMessageStream("session-123", "Test message", nil, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("SendMessageStream failed: %v", err)
+	}
+
+	if response != "feat: stream tokens" {
+		t.Errorf("Response mismatch: got %q", response)
+	}
+	if len(chunks) != 2 || chunks[0] != "feat: " || chunks[1] != "stream tokens" {
+		t.Errorf("Unexpected chunks: %q", chunks)
+	}
+}
+
+func TestSendMessageStreamFallsBackToJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Message{
+			Parts: []MessagePart{{Type: "text", Text: "fix: no stream"}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+
+	var chunks []string
+	response, err := client.SendMessageStream("session-123", "Test message", nil, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("SendMessageStream failed: %v", err)
+	}
+
+	if response != "fix: no stream" {
+		t.Errorf("Response mismatch: got %q", response)
+	}
+	if len(chunks) != 1 || chunks[0] != "fix: no stream" {
+		t.Errorf("Unexpected chunks: %q", chunks)
+	}
+}
+
+func TestSendMessageStreamErrorEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		_, _ = w.Write([]byte("data: partial\n\nevent: error\ndata: model unavailable\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient("localhost", 9999, 5)
+	client.baseURL = server.URL
+
+	_, err := client.SendMessageStream("session-123", "Test message", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "model unavailable") {
+		t.Errorf("Expected stream error, got %v", err)
+	}
+}